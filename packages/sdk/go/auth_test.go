@@ -0,0 +1,198 @@
+package sgptcoder_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+)
+
+// authServer accepts requests with the Authorization header value of
+// *accepted and records every request it gets.
+type authServer struct {
+	mu       sync.Mutex
+	accepted string
+	seen     []string
+}
+
+func (s *authServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	s.seen = append(s.seen, r.Header.Get("Authorization")+" "+string(body))
+	ok := r.Header.Get("Authorization") == s.accepted
+	s.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Path == "/event" {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"type\":\"server.connected\",\"properties\":{}}\n\n")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, `{"id":"ses_1","title":"authorized"}`)
+}
+
+func TestBearerTokenRefresh(t *testing.T) {
+	handler := &authServer{accepted: "Bearer second"}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	var calls []bool
+	client := sgptcoder.NewClient(
+		option.WithBaseURL(server.URL),
+		option.WithMaxRetries(0),
+		option.WithBearerTokenFunc(func(ctx context.Context, refresh bool) (string, error) {
+			calls = append(calls, refresh)
+			if refresh {
+				return "second", nil
+			}
+			return "first", nil
+		}),
+	)
+	ctx := context.Background()
+	session, err := client.Session.New(ctx, sgptcoder.SessionNewParams{Title: sgptcoder.F("refreshed")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if session.Title != "authorized" {
+		t.Errorf("unexpected session %+v", session)
+	}
+	if len(handler.seen) != 2 || handler.seen[1] != `Bearer second {"title":"refreshed"}` {
+		t.Errorf("expected the request to be sent again with the new token, got %q", handler.seen)
+	}
+
+	stream := client.Event.ListStreaming(ctx, sgptcoder.EventListParams{})
+	defer stream.Close()
+	if !stream.Next() || stream.Current().Type != sgptcoder.EventListResponseTypeServerConnected {
+		t.Fatalf("expected an authorized event stream, got %v", stream.Err())
+	}
+	if len(calls) != 2 || calls[0] || !calls[1] {
+		t.Errorf("expected one token and one refresh, got %v", calls)
+	}
+
+	// A token the server keeps rejecting is not refreshed forever
+	handler.accepted = "Bearer never"
+	if _, err := client.Session.Get(ctx, "ses_1", sgptcoder.SessionGetParams{}); err == nil {
+		t.Error("expected an error for a rejected token")
+	}
+	if len(calls) != 3 {
+		t.Errorf("expected a single refresh, got %v", calls)
+	}
+}
+
+func TestAuthFromEnvironment(t *testing.T) {
+	handler := &authServer{accepted: "Bearer from-env"}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	t.Setenv("SGPTCODER_BASE_URL", server.URL)
+	t.Setenv("SGPTCODER_AUTH_TOKEN", "from-env")
+
+	client := sgptcoder.NewClient(option.WithMaxRetries(0))
+	if _, err := client.Session.Get(context.Background(), "ses_1", sgptcoder.SessionGetParams{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Setenv("SGPTCODER_AUTH_TOKEN", "")
+	t.Setenv("SGPTCODER_AUTH_USERNAME", "agent")
+	t.Setenv("SGPTCODER_AUTH_PASSWORD", "secret")
+	handler.accepted = "Basic YWdlbnQ6c2VjcmV0"
+	client = sgptcoder.NewClient(option.WithMaxRetries(0))
+	if _, err := client.Session.Get(context.Background(), "ses_1", sgptcoder.SessionGetParams{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// writeClientCertificate writes a self-signed client certificate and its key
+// as PEM files and returns their paths and the certificate.
+func writeClientCertificate(t *testing.T) (string, string, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sgptcoder client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile, cert
+}
+
+func TestClientCertificate(t *testing.T) {
+	certFile, keyFile, cert := writeClientCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+
+	server := httptest.NewUnstartedServer(&authServer{})
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600)
+	ctx := context.Background()
+
+	// Without a client certificate the handshake fails
+	client := sgptcoder.NewClient(
+		option.WithBaseURL(server.URL),
+		option.WithMaxRetries(0),
+		option.WithClientCertificate("", "", caFile),
+	)
+	if _, err := client.Session.Get(ctx, "ses_1", sgptcoder.SessionGetParams{}); err == nil {
+		t.Error("expected the server to require a client certificate")
+	}
+
+	client = sgptcoder.NewClient(
+		option.WithBaseURL(server.URL),
+		option.WithMaxRetries(0),
+		option.WithClientCertificate(certFile, keyFile, caFile),
+	)
+	session, err := client.Session.Get(ctx, "ses_1", sgptcoder.SessionGetParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if session.Title != "authorized" {
+		t.Errorf("unexpected session %+v", session)
+	}
+
+	client = sgptcoder.NewClient(option.WithClientCertificate(certFile, "", ""))
+	if _, err := client.Session.Get(ctx, "ses_1", sgptcoder.SessionGetParams{}); err == nil {
+		t.Error("expected an error for a certificate without a key")
+	}
+}
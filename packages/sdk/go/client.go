@@ -29,18 +29,29 @@ type Client struct {
 	Tui     *TuiService
 }
 
-// DefaultClientOptions read from the environment (SGPTCODER_BASE_URL). This should
-// be used to initialize new clients.
+// DefaultClientOptions read from the environment (SGPTCODER_BASE_URL, and the
+// credentials in SGPTCODER_AUTH_TOKEN, SGPTCODER_AUTH_USERNAME,
+// SGPTCODER_AUTH_PASSWORD, SGPTCODER_TLS_CERT, SGPTCODER_TLS_KEY and
+// SGPTCODER_TLS_CA). This should be used to initialize new clients.
 func DefaultClientOptions() []option.RequestOption {
 	defaults := []option.RequestOption{option.WithEnvironmentProduction()}
 	if o, ok := os.LookupEnv("SGPTCODER_BASE_URL"); ok {
 		defaults = append(defaults, option.WithBaseURL(o))
 	}
+	if o, ok := os.LookupEnv("SGPTCODER_AUTH_TOKEN"); ok && o != "" {
+		defaults = append(defaults, option.WithBearerToken(o))
+	} else if o, ok := os.LookupEnv("SGPTCODER_AUTH_USERNAME"); ok && o != "" {
+		defaults = append(defaults, option.WithBasicAuth(o, os.Getenv("SGPTCODER_AUTH_PASSWORD")))
+	}
+	cert, key, ca := os.Getenv("SGPTCODER_TLS_CERT"), os.Getenv("SGPTCODER_TLS_KEY"), os.Getenv("SGPTCODER_TLS_CA")
+	if cert != "" || key != "" || ca != "" {
+		defaults = append(defaults, option.WithClientCertificate(cert, key, ca))
+	}
 	return defaults
 }
 
 // NewClient generates a new client with the default option read from the
-// environment (SGPTCODER_BASE_URL). The option passed in as arguments are applied
+// environment (see [DefaultClientOptions]). The option passed in as arguments are applied
 // after these default arguments, and all option will be passed down to the
 // services and requests that this client makes.
 func NewClient(opts ...option.RequestOption) (r *Client) {
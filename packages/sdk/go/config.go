@@ -1808,13 +1808,13 @@ type KeybindsConfig struct {
 	AppHelp string `json:"app_help"`
 	// Open external editor
 	EditorOpen string `json:"editor_open"`
-	// @deprecated Close file
+	// Close file viewer
 	FileClose string `json:"file_close"`
-	// @deprecated Split/unified diff
+	// Toggle file content/unified/split diff
 	FileDiffToggle string `json:"file_diff_toggle"`
-	// @deprecated Currently not available. List files
+	// Browse project files
 	FileList string `json:"file_list"`
-	// @deprecated Search file
+	// Search project files
 	FileSearch string `json:"file_search"`
 	// Clear input field
 	InputClear string `json:"input_clear"`
@@ -1838,26 +1838,34 @@ type KeybindsConfig struct {
 	MessagesLast string `json:"messages_last"`
 	// @deprecated Toggle layout
 	MessagesLayoutToggle string `json:"messages_layout_toggle"`
-	// @deprecated Navigate to next message
+	// Move the message cursor down
 	MessagesNext string `json:"messages_next"`
 	// Scroll messages down by one page
 	MessagesPageDown string `json:"messages_page_down"`
 	// Scroll messages up by one page
 	MessagesPageUp string `json:"messages_page_up"`
-	// @deprecated Navigate to previous message
+	// Move the message cursor up
 	MessagesPrevious string `json:"messages_previous"`
 	// Redo message
 	MessagesRedo string `json:"messages_redo"`
 	// @deprecated use messages_undo. Revert message
 	MessagesRevert string `json:"messages_revert"`
+	// Find in messages
+	MessagesSearch string `json:"messages_search"`
 	// Undo message
 	MessagesUndo string `json:"messages_undo"`
+	// Yank a code block of the latest reply
+	MessagesYank string `json:"messages_yank"`
 	// Next recent model
 	ModelCycleRecent string `json:"model_cycle_recent"`
 	// Previous recent model
 	ModelCycleRecentReverse string `json:"model_cycle_recent_reverse"`
 	// List available models
 	ModelList string `json:"model_list"`
+	// Show permission decisions and rules
+	PermissionLog string `json:"permission_log"`
+	// Review pending permissions
+	PermissionReview string `json:"permission_review"`
 	// Create/update AGENTS.md
 	ProjectInit string `json:"project_init"`
 	// Cycle to next child session
@@ -1868,6 +1876,8 @@ type KeybindsConfig struct {
 	SessionCompact string `json:"session_compact"`
 	// Export session to editor
 	SessionExport string `json:"session_export"`
+	// Import session from an exported JSON transcript
+	SessionImport string `json:"session_import"`
 	// Interrupt current session
 	SessionInterrupt string `json:"session_interrupt"`
 	// List all sessions
@@ -1926,15 +1936,20 @@ type keybindsConfigJSON struct {
 	MessagesPrevious         apijson.Field
 	MessagesRedo             apijson.Field
 	MessagesRevert           apijson.Field
+	MessagesSearch           apijson.Field
 	MessagesUndo             apijson.Field
+	MessagesYank             apijson.Field
 	ModelCycleRecent         apijson.Field
 	ModelCycleRecentReverse  apijson.Field
 	ModelList                apijson.Field
+	PermissionLog            apijson.Field
+	PermissionReview         apijson.Field
 	ProjectInit              apijson.Field
 	SessionChildCycle        apijson.Field
 	SessionChildCycleReverse apijson.Field
 	SessionCompact           apijson.Field
 	SessionExport            apijson.Field
+	SessionImport            apijson.Field
 	SessionInterrupt         apijson.Field
 	SessionList              apijson.Field
 	SessionNew               apijson.Field
@@ -0,0 +1,149 @@
+package sgptcoder_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+)
+
+// unixSocketServer serves a session list, an event stream and a long poll on
+// a Unix domain socket and returns the socket path.
+func unixSocketServer(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not supported")
+	}
+	// Socket paths are limited to about 100 bytes, so avoid t.TempDir
+	dir, err := os.MkdirTemp("", "sgptcoder")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "server.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/session", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[{"id":"ses_1","title":"over a socket"}]`)
+	})
+	mux.HandleFunc("/event", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"type\":\"server.connected\",\"properties\":{}}\n\n")
+		w.(http.Flusher).Flush()
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(w, "data: {\"type\":\"session.idle\",\"properties\":{\"sessionID\":\"ses_1\"}}\n\n")
+	})
+	mux.HandleFunc("/tui/control/next", func(w http.ResponseWriter, r *http.Request) {
+		// A long poll only answers once there is something to send
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"path":"/tui/open-help","body":{}}`)
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return path
+}
+
+func TestUnixSocket(t *testing.T) {
+	path := unixSocketServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for name, opt := range map[string]option.RequestOption{
+		"option":   option.WithUnixSocket(path),
+		"base URL": option.WithBaseURL("unix://" + path),
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := sgptcoder.NewClient(opt, option.WithMaxRetries(0))
+
+			sessions, err := client.Session.List(ctx, sgptcoder.SessionListParams{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(*sessions) != 1 || (*sessions)[0].Title != "over a socket" {
+				t.Errorf("unexpected sessions %+v", *sessions)
+			}
+
+			stream := client.Event.ListStreaming(ctx, sgptcoder.EventListParams{})
+			defer stream.Close()
+			var events []string
+			for stream.Next() {
+				events = append(events, string(stream.Current().Type))
+			}
+			if err := stream.Err(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(events) != 2 || events[1] != "session.idle" {
+				t.Errorf("unexpected events %v", events)
+			}
+
+			var request struct {
+				Path string `json:"path"`
+			}
+			if err := client.Get(ctx, "/tui/control/next", nil, &request); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if request.Path != "/tui/open-help" {
+				t.Errorf("unexpected request %+v", request)
+			}
+		})
+	}
+}
+
+func TestWithDialer(t *testing.T) {
+	path := unixSocketServer(t)
+	dialed := 0
+	client := sgptcoder.NewClient(
+		option.WithBaseURL("http://sgptcoder.internal"),
+		option.WithDialer(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed++
+			if addr != "sgptcoder.internal:80" {
+				return nil, fmt.Errorf("unexpected address %s", addr)
+			}
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", path)
+		}),
+		option.WithMaxRetries(0),
+	)
+	for i := 0; i < 2; i++ {
+		if _, err := client.Session.List(context.Background(), sgptcoder.SessionListParams{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if dialed != 1 {
+		t.Errorf("expected the connection to be reused, dialed %d times", dialed)
+	}
+}
+
+func TestUnixSocketWithTLSConfig(t *testing.T) {
+	path := unixSocketServer(t)
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	for name, opts := range map[string][]option.RequestOption{
+		"socket first": {option.WithBaseURL("unix://" + path), option.WithTLSConfig(config)},
+		"TLS first":    {option.WithTLSConfig(config), option.WithUnixSocket(path)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := sgptcoder.NewClient(append(opts, option.WithMaxRetries(0))...)
+			for i := 0; i < 2; i++ {
+				if _, err := client.Session.List(context.Background(), sgptcoder.SessionListParams{}); err != nil {
+					t.Fatalf("expected the socket to be kept, got %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package sgptcoder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skorpland/sgptcoder-sdk-go/internal"
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+)
+
+// ServerVersionHeader is the response header in which servers report their
+// version.
+const ServerVersionHeader = "X-Sgptcoder-Version"
+
+var (
+	// ErrIncompatibleServer matches a [*VersionError].
+	ErrIncompatibleServer = errors.New("sgptcoder: incompatible server version")
+	// ErrServerNotFound is returned by [Connect] when no running server was
+	// found.
+	ErrServerNotFound = errors.New("sgptcoder: no running server found")
+)
+
+// VersionError reports a server whose version the SDK does not support.
+type VersionError struct {
+	// Server is the version the server reported, or empty when it did not
+	// report one.
+	Server string
+	// Supported is the server version the SDK was generated for.
+	Supported string
+	// SDK is the version of the SDK.
+	SDK string
+}
+
+func (e *VersionError) Error() string {
+	if e.Server == "" {
+		return fmt.Sprintf("sgptcoder: server does not report its version; SDK %s supports server %s", e.SDK, e.Supported)
+	}
+	return fmt.Sprintf("sgptcoder: server version %s is not compatible with SDK %s, which supports server %s", e.Server, e.SDK, e.Supported)
+}
+
+func (e *VersionError) Is(target error) bool {
+	return target == ErrIncompatibleServer
+}
+
+// CheckServerVersion returns a [*VersionError] unless a server of the given
+// version is compatible with the SDK: it must have the same major version as
+// the server the SDK was generated for, and for 0.x versions the same minor
+// version. Development builds, which report "dev", are accepted.
+func CheckServerVersion(version string) error {
+	if version == "dev" {
+		return nil
+	}
+	err := &VersionError{Server: version, Supported: internal.ServerVersion, SDK: internal.PackageVersion}
+	server, ok := parseVersion(version)
+	supported, _ := parseVersion(internal.ServerVersion)
+	if !ok || server[0] != supported[0] || (supported[0] == 0 && server[1] != supported[1]) {
+		return err
+	}
+	return nil
+}
+
+// parseVersion returns the major and minor numbers of a semantic version.
+func parseVersion(version string) ([2]int, bool) {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i > -1 {
+		version = version[:i]
+	}
+	fields := strings.Split(version, ".")
+	if len(fields) < 2 {
+		return [2]int{}, false
+	}
+	major, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return [2]int{}, false
+	}
+	minor, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return [2]int{}, false
+	}
+	return [2]int{major, minor}, true
+}
+
+// Handshake checks that the server is reachable and that its version is
+// compatible with the SDK, see [CheckServerVersion]. It returns the version
+// the server reported, and a [*VersionError] when it is not compatible.
+func (r *Client) Handshake(ctx context.Context, opts ...option.RequestOption) (string, error) {
+	var res *http.Response
+	opts = append(opts, option.WithResponseInto(&res))
+	if _, err := r.Path.Get(ctx, PathGetParams{}, opts...); err != nil {
+		return "", err
+	}
+	version := res.Header.Get(ServerVersionHeader)
+	return version, CheckServerVersion(version)
+}
+
+// ServerInfo describes a running server.
+type ServerInfo struct {
+	URL     string `json:"url"`
+	PID     int    `json:"pid"`
+	Version string `json:"version"`
+	// Directory is the directory the server was started in.
+	Directory string `json:"directory"`
+	// Time is when the server started, in milliseconds since the epoch.
+	Time int64 `json:"time"`
+}
+
+// DiscoverOptions configures [Discover] and [Connect].
+type DiscoverOptions struct {
+	// Directory keeps servers started in this directory or one of its
+	// parents. All servers are kept when it is empty.
+	Directory string
+	// StateDir is where servers announce themselves. It defaults to
+	// [DefaultStateDir].
+	StateDir string
+	// Timeout limits how long each server is probed. It defaults to two
+	// seconds.
+	Timeout time.Duration
+	// Options are applied to the requests probing servers and to the client
+	// returned by [Connect], for example to authenticate.
+	Options []option.RequestOption
+}
+
+// DefaultStateDir returns the directory servers announce themselves in:
+// $XDG_STATE_HOME/sgptcoder, or ~/.local/state/sgptcoder.
+func DefaultStateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "sgptcoder"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "sgptcoder"), nil
+}
+
+// Discover finds running servers and returns the ones that answer, most
+// recently started first. Servers are read from the server directory of the
+// state directory, where each one announces itself, and from the
+// SGPTCODER_SERVER environment variable that the TUI sets for the processes it
+// starts, which comes first. The versions are the ones the servers reported
+// when probed.
+func Discover(ctx context.Context, opts DiscoverOptions) ([]ServerInfo, error) {
+	candidates, err := announcedServers(opts)
+	if err != nil {
+		return nil, err
+	}
+	if url := os.Getenv("SGPTCODER_SERVER"); url != "" {
+		candidates = append([]ServerInfo{{URL: url}}, candidates...)
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	alive := make([]bool, len(candidates))
+	var wg sync.WaitGroup
+	for i := range candidates {
+		wg.Add(1)
+		go func(info *ServerInfo, alive *bool) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			version, err := serverClient(*info, opts, option.WithMaxRetries(0)).Handshake(ctx)
+			if err != nil && !errors.Is(err, ErrIncompatibleServer) {
+				return
+			}
+			*alive = true
+			if version != "" {
+				info.Version = version
+			}
+		}(&candidates[i], &alive[i])
+	}
+	wg.Wait()
+
+	var servers []ServerInfo
+	seen := map[string]bool{}
+	for i, info := range candidates {
+		if alive[i] && !seen[info.URL] {
+			seen[info.URL] = true
+			servers = append(servers, info)
+		}
+	}
+	return servers, ctx.Err()
+}
+
+// announcedServers reads the servers announced in the state directory that
+// match the directory of opts, most recently started first.
+func announcedServers(opts DiscoverOptions) ([]ServerInfo, error) {
+	stateDir := opts.StateDir
+	if stateDir == "" {
+		dir, err := DefaultStateDir()
+		if err != nil {
+			return nil, err
+		}
+		stateDir = dir
+	}
+	files, err := filepath.Glob(filepath.Join(stateDir, "server", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var servers []ServerInfo
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var info ServerInfo
+		if json.Unmarshal(data, &info) != nil || info.URL == "" {
+			continue
+		}
+		if opts.Directory != "" && !withinDirectory(opts.Directory, info.Directory) {
+			continue
+		}
+		servers = append(servers, info)
+	}
+	sort.SliceStable(servers, func(i, j int) bool {
+		return servers[i].Time > servers[j].Time
+	})
+	return servers, nil
+}
+
+// withinDirectory reports whether dir is parent or one of its subdirectories.
+func withinDirectory(dir, parent string) bool {
+	if parent == "" {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Clean(parent), filepath.Clean(dir))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func serverClient(info ServerInfo, opts DiscoverOptions, extra ...option.RequestOption) *Client {
+	clientOpts := append([]option.RequestOption{}, opts.Options...)
+	clientOpts = append(clientOpts, option.WithBaseURL(info.URL))
+	return NewClient(append(clientOpts, extra...)...)
+}
+
+// Connect returns a client for the most recently started compatible server
+// that [Discover] finds. When servers were found but none is compatible, the
+// error is the [*VersionError] of the newest one; when none was found, it is
+// [ErrServerNotFound].
+func Connect(ctx context.Context, opts DiscoverOptions) (*Client, ServerInfo, error) {
+	servers, err := Discover(ctx, opts)
+	if err != nil {
+		return nil, ServerInfo{}, err
+	}
+	if len(servers) == 0 {
+		return nil, ServerInfo{}, ErrServerNotFound
+	}
+	for _, info := range servers {
+		if CheckServerVersion(info.Version) == nil {
+			return serverClient(info, opts), info, nil
+		}
+	}
+	return nil, servers[0], CheckServerVersion(servers[0].Version)
+}
@@ -0,0 +1,139 @@
+package sgptcoder_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+	"github.com/skorpland/sgptcoder-sdk-go/sdktest"
+)
+
+func announce(t *testing.T, stateDir string, name string, info sgptcoder.ServerInfo) {
+	t.Helper()
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(stateDir, "server"), 0o755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(stateDir, "server", name+".json"), data, 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// versionServer answers every request as a server of the given version.
+func versionServer(t *testing.T, version string) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(sgptcoder.ServerVersionHeader, version)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"config":"","directory":"","state":"","worktree":""}`))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestCheckServerVersion(t *testing.T) {
+	for version, compatible := range map[string]bool{
+		"0.9.7":          true,
+		"0.9.0":          true,
+		"v0.9.12-beta.1": true,
+		"dev":            true,
+		"0.10.0":         false,
+		"1.9.7":          false,
+		"":               false,
+		"latest":         false,
+	} {
+		err := sgptcoder.CheckServerVersion(version)
+		if (err == nil) != compatible {
+			t.Errorf("version %q: expected compatible %v, got %v", version, compatible, err)
+		}
+		var versionErr *sgptcoder.VersionError
+		if err != nil && (!errors.As(err, &versionErr) || versionErr.Server != version || !errors.Is(err, sgptcoder.ErrIncompatibleServer)) {
+			t.Errorf("version %q: expected a version error, got %#v", version, err)
+		}
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	server := sdktest.NewServer()
+	defer server.Close()
+	version, err := server.Client().Handshake(context.Background())
+	if err != nil || version == "" {
+		t.Errorf("expected a compatible server, got %q, %v", version, err)
+	}
+
+	client := sgptcoder.NewClient(option.WithBaseURL(versionServer(t, "2.0.0")))
+	version, err = client.Handshake(context.Background())
+	if version != "2.0.0" || !errors.Is(err, sgptcoder.ErrIncompatibleServer) {
+		t.Errorf("expected an incompatible server, got %q, %v", version, err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	t.Setenv("SGPTCODER_SERVER", "")
+	stateDir := t.TempDir()
+	project := filepath.Join(t.TempDir(), "project")
+
+	live := sdktest.NewServer()
+	defer live.Close()
+	dead := sdktest.NewServer()
+	dead.Close()
+	upgraded := versionServer(t, "0.10.0")
+
+	announce(t, stateDir, "1-live", sgptcoder.ServerInfo{URL: live.URL, PID: 1, Version: "0.9.0", Directory: filepath.Dir(project), Time: 100})
+	announce(t, stateDir, "2-dead", sgptcoder.ServerInfo{URL: dead.URL, PID: 2, Version: "0.9.7", Directory: project, Time: 200})
+	announce(t, stateDir, "3-upgraded", sgptcoder.ServerInfo{URL: upgraded, PID: 3, Version: "0.10.0", Directory: project, Time: 300})
+	announce(t, stateDir, "4-other", sgptcoder.ServerInfo{URL: live.URL, PID: 4, Directory: "/elsewhere", Time: 400})
+	os.WriteFile(filepath.Join(stateDir, "server", "5-broken.json"), []byte("{"), 0o644)
+
+	opts := sgptcoder.DiscoverOptions{Directory: filepath.Join(project, "src"), StateDir: stateDir}
+	servers, err := sgptcoder.Discover(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(servers) != 2 || servers[0].URL != upgraded || servers[1].URL != live.URL {
+		t.Fatalf("expected the live servers of the project, newest first, got %+v", servers)
+	}
+	if servers[1].PID != 1 || servers[1].Version == "0.9.0" {
+		t.Errorf("expected the probed version, got %+v", servers[1])
+	}
+
+	client, info, err := sgptcoder.Connect(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.URL != live.URL {
+		t.Errorf("expected the compatible server, got %+v", info)
+	}
+	if _, err := client.Session.List(context.Background(), sgptcoder.SessionListParams{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// The upgraded server is the only one left
+	live.Close()
+	_, info, err = sgptcoder.Connect(context.Background(), opts)
+	var versionErr *sgptcoder.VersionError
+	if !errors.As(err, &versionErr) || versionErr.Server != "0.10.0" || info.URL != upgraded {
+		t.Errorf("expected a version error, got %v", err)
+	}
+
+	_, _, err = sgptcoder.Connect(context.Background(), sgptcoder.DiscoverOptions{StateDir: t.TempDir()})
+	if !errors.Is(err, sgptcoder.ErrServerNotFound) {
+		t.Errorf("expected no server, got %v", err)
+	}
+
+	t.Setenv("SGPTCODER_SERVER", upgraded)
+	servers, _ = sgptcoder.Discover(context.Background(), sgptcoder.DiscoverOptions{StateDir: t.TempDir()})
+	if len(servers) != 1 || servers[0].URL != upgraded {
+		t.Errorf("expected the server from the environment, got %+v", servers)
+	}
+}
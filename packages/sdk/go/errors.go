@@ -0,0 +1,104 @@
+package sgptcoder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/skorpland/sgptcoder-sdk-go/internal/apierror"
+	"github.com/skorpland/sgptcoder-sdk-go/internal/requestconfig"
+)
+
+var (
+	// ErrNotFound matches API errors with a 404 Not Found status.
+	ErrNotFound = apierror.ErrNotFound
+	// ErrSessionNotFound matches API errors for a session that does not exist.
+	// A missing message of a session that exists only matches [ErrNotFound].
+	ErrSessionNotFound = apierror.ErrSessionNotFound
+	// ErrSessionBusy matches API errors for a session that is still working
+	// on an earlier request.
+	ErrSessionBusy = apierror.ErrSessionBusy
+)
+
+// NamedError is an error the server reported by name that has no dedicated
+// type. errors.As finds it in an [*Error] whose body names such an error.
+type NamedError = apierror.NamedError
+
+// MessageOutputLengthError is the error of a reply that was cut off at the
+// model's output limit.
+type MessageOutputLengthError = AssistantMessageErrorMessageOutputLengthError
+
+func (r AssistantMessageErrorMessageOutputLengthError) Error() string {
+	return "message output length exceeded"
+}
+
+// Err returns the error of an assistant message as one of
+// [ProviderAuthError], [UnknownError], [MessageOutputLengthError] or
+// [MessageAbortedError], or nil when the message did not fail.
+func (r AssistantMessageError) Err() error {
+	if err, ok := r.AsUnion().(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Err returns the error of a session.error event as one of
+// [ProviderAuthError], [UnknownError], [MessageOutputLengthError] or
+// [MessageAbortedError], or nil when the event has no error.
+func (r EventListResponseEventSessionErrorPropertiesError) Err() error {
+	switch err := r.AsUnion().(type) {
+	case EventListResponseEventSessionErrorPropertiesErrorMessageOutputLengthError:
+		return MessageOutputLengthError{
+			Name: AssistantMessageErrorMessageOutputLengthErrorNameMessageOutputLengthError,
+			Data: err.Data,
+		}
+	case error:
+		return err
+	}
+	return nil
+}
+
+// ProviderID returns the ID of the provider an error is about, such as the
+// provider that rejected its credentials with a [ProviderAuthError].
+func ProviderID(err error) (string, bool) {
+	var auth ProviderAuthError
+	if errors.As(err, &auth) {
+		return auth.Data.ProviderID, auth.Data.ProviderID != ""
+	}
+	var named *NamedError
+	if errors.As(err, &named) {
+		var data struct {
+			ProviderID string `json:"providerID"`
+		}
+		if json.Unmarshal(named.Data, &data) == nil && data.ProviderID != "" {
+			return data.ProviderID, true
+		}
+	}
+	return "", false
+}
+
+// IsRetryable reports whether sending the same request again may succeed:
+// connection failures, busy sessions, and the API errors the client retries
+// by default. Canceled requests, rejected credentials and message errors are
+// not retryable.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrSessionBusy) {
+		return true
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		res := apiErr.Response
+		if res == nil {
+			res = &http.Response{StatusCode: apiErr.StatusCode, Header: http.Header{}}
+		}
+		return requestconfig.DefaultRetryable(res, nil)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
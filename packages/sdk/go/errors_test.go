@@ -0,0 +1,139 @@
+package sgptcoder_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+	"github.com/skorpland/sgptcoder-sdk-go/sdktest"
+)
+
+// errorServer answers every request with the given status and body.
+func errorServer(t *testing.T, status int, body string) *sgptcoder.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return sgptcoder.NewClient(option.WithBaseURL(server.URL), option.WithMaxRetries(0))
+}
+
+func TestErrorSentinels(t *testing.T) {
+	server := sdktest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+
+	_, err := server.Client().Session.Get(ctx, "ses_missing", sgptcoder.SessionGetParams{})
+	if !errors.Is(err, sgptcoder.ErrSessionNotFound) || !errors.Is(err, sgptcoder.ErrNotFound) {
+		t.Errorf("expected a missing session, got %v", err)
+	}
+	if errors.Is(err, sgptcoder.ErrSessionBusy) || sgptcoder.IsRetryable(err) {
+		t.Errorf("expected a missing session not to be busy or retryable, got %v", err)
+	}
+
+	// Only the status tells a missing session apart, not the message
+	client := errorServer(t, 400, `{"name":"UnknownError","data":{"message":"Error: ENOENT: no such file or directory"}}`)
+	_, err = client.Session.Get(ctx, "ses_missing", sgptcoder.SessionGetParams{})
+	if errors.Is(err, sgptcoder.ErrSessionNotFound) || errors.Is(err, sgptcoder.ErrNotFound) {
+		t.Errorf("expected an unknown error not to be a missing session, got %v", err)
+	}
+	var unknown sgptcoder.UnknownError
+	if !errors.As(err, &unknown) || unknown.Data.Message != "Error: ENOENT: no such file or directory" {
+		t.Errorf("expected an unknown error, got %#v", err)
+	}
+
+	// A missing message of a session that exists is not a missing session
+	sessionID := server.AddSession("test", "")
+	_, err = server.Client().Session.Message(ctx, sessionID, "msg_missing", sgptcoder.SessionMessageParams{})
+	if errors.Is(err, sgptcoder.ErrSessionNotFound) || !errors.Is(err, sgptcoder.ErrNotFound) {
+		t.Errorf("expected a missing message, got %v", err)
+	}
+	_, err = server.Client().Session.Fork(ctx, sessionID, sgptcoder.SessionForkParams{MessageID: sgptcoder.F("msg_missing")})
+	if errors.Is(err, sgptcoder.ErrSessionNotFound) || !errors.Is(err, sgptcoder.ErrNotFound) {
+		t.Errorf("expected forking at a missing message not to be a missing session, got %v", err)
+	}
+	// Below /session/{id}, the server names the session it didn't find
+	_, err = server.Client().Session.Messages(ctx, "ses_missing", sgptcoder.SessionMessagesParams{})
+	if !errors.Is(err, sgptcoder.ErrSessionNotFound) {
+		t.Errorf("expected the messages of a missing session to be a missing session, got %v", err)
+	}
+	client = errorServer(t, 404, `{"name":"NotFoundError","data":{"message":"Resource not found: message/ses_1/msg_1"}}`)
+	_, err = client.Session.Message(ctx, "ses_1", "msg_1", sgptcoder.SessionMessageParams{})
+	if errors.Is(err, sgptcoder.ErrSessionNotFound) || !errors.Is(err, sgptcoder.ErrNotFound) {
+		t.Errorf("expected a missing message, got %v", err)
+	}
+
+	client = errorServer(t, 409, `{"name":"BusyError","data":{"message":"Session ses_1 is busy"}}`)
+	_, err = client.Session.Prompt(ctx, "ses_1", sgptcoder.SessionPromptParams{})
+	if !errors.Is(err, sgptcoder.ErrSessionBusy) || !sgptcoder.IsRetryable(err) {
+		t.Errorf("expected a busy session, got %v", err)
+	}
+	var named *sgptcoder.NamedError
+	if !errors.As(err, &named) || named.Name != "BusyError" || named.Error() != "BusyError: Session ses_1 is busy" {
+		t.Errorf("expected a named error, got %#v", err)
+	}
+}
+
+func TestErrorProviderID(t *testing.T) {
+	ctx := context.Background()
+
+	client := errorServer(t, 400, `{"name":"ProviderAuthError","data":{"providerID":"anthropic","message":"invalid x-api-key"}}`)
+	_, err := client.Session.Prompt(ctx, "ses_1", sgptcoder.SessionPromptParams{})
+	var auth sgptcoder.ProviderAuthError
+	if !errors.As(err, &auth) || auth.Data.Message != "invalid x-api-key" {
+		t.Fatalf("expected a provider auth error, got %#v", err)
+	}
+	if id, ok := sgptcoder.ProviderID(err); !ok || id != "anthropic" {
+		t.Errorf("expected the provider ID, got %q", id)
+	}
+	if sgptcoder.IsRetryable(err) {
+		t.Error("expected rejected credentials not to be retryable")
+	}
+
+	client = errorServer(t, 400, `{"name":"ProviderModelNotFoundError","data":{"providerID":"openai","modelID":"gpt-0"}}`)
+	_, err = client.Session.Prompt(ctx, "ses_1", sgptcoder.SessionPromptParams{})
+	if id, ok := sgptcoder.ProviderID(err); !ok || id != "openai" {
+		t.Errorf("expected the provider ID of a named error, got %q", id)
+	}
+
+	client = errorServer(t, 503, `{}`)
+	_, err = client.Session.Prompt(ctx, "ses_1", sgptcoder.SessionPromptParams{})
+	if _, ok := sgptcoder.ProviderID(err); ok {
+		t.Error("expected no provider ID")
+	}
+	if !sgptcoder.IsRetryable(err) {
+		t.Error("expected a server error to be retryable")
+	}
+}
+
+func TestMessageErrors(t *testing.T) {
+	var message sgptcoder.AssistantMessage
+	if err := json.Unmarshal([]byte(`{"id":"msg_1","role":"assistant","error":{"name":"ProviderAuthError","data":{"providerID":"anthropic","message":"bad key"}}}`), &message); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := message.Error.Err()
+	if id, ok := sgptcoder.ProviderID(err); !ok || id != "anthropic" {
+		t.Errorf("expected a provider auth error, got %#v", err)
+	}
+
+	var event sgptcoder.EventListResponse
+	if err := json.Unmarshal([]byte(`{"type":"session.error","properties":{"sessionID":"ses_1","error":{"name":"MessageOutputLengthError","data":{}}}}`), &event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sessionError := event.AsUnion().(sgptcoder.EventListResponseEventSessionError)
+	var outputLength sgptcoder.MessageOutputLengthError
+	if !errors.As(sessionError.Properties.Error.Err(), &outputLength) {
+		t.Errorf("expected an output length error, got %#v", sessionError.Properties.Error.Err())
+	}
+
+	if err := (sgptcoder.AssistantMessageError{}).Err(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
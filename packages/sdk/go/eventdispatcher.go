@@ -0,0 +1,270 @@
+package sgptcoder
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+)
+
+// EventStream is the iterator shared by the streams returned from
+// [EventService.ListStreaming] and [EventService.ListStreamingReconnecting].
+type EventStream interface {
+	Next() bool
+	Current() EventListResponse
+	Err() error
+	Close() error
+}
+
+// EventDispatcher reads a single [EventStream] and fans every event out to the
+// subscribers registered with [Subscribe]. Each subscriber has its own bounded
+// buffer and goroutine, so a slow handler never blocks the stream or other
+// subscribers; events that do not fit in a full buffer are dropped and counted
+// by [Subscription.Dropped].
+type EventDispatcher struct {
+	stream EventStream
+
+	mu      sync.Mutex
+	subs    map[*Subscription]struct{}
+	started bool
+	closed  bool
+	done    chan struct{}
+	err     error
+}
+
+// NewEventDispatcher returns a dispatcher for the given stream. The stream is
+// not consumed until [EventDispatcher.Start] is called, so subscribers
+// registered before then see every event.
+func NewEventDispatcher(stream EventStream) *EventDispatcher {
+	return &EventDispatcher{
+		stream: stream,
+		subs:   map[*Subscription]struct{}{},
+		done:   make(chan struct{}),
+	}
+}
+
+// Dispatcher opens an event stream with [EventService.ListStreaming] and
+// returns an [EventDispatcher] reading from it.
+func (r *EventService) Dispatcher(ctx context.Context, query EventListParams, opts ...option.RequestOption) *EventDispatcher {
+	return NewEventDispatcher(r.ListStreaming(ctx, query, opts...))
+}
+
+// Start begins reading the stream in a new goroutine. Calling Start more than
+// once has no effect.
+func (d *EventDispatcher) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.started {
+		return
+	}
+	d.started = true
+	go d.run()
+}
+
+func (d *EventDispatcher) run() {
+	for d.stream.Next() {
+		d.dispatch(d.stream.Current().AsUnion())
+	}
+
+	d.mu.Lock()
+	d.err = d.stream.Err()
+	d.finish()
+	d.mu.Unlock()
+}
+
+// finish ends every subscription. It must be called with d.mu held.
+func (d *EventDispatcher) finish() {
+	d.closed = true
+	for sub := range d.subs {
+		delete(d.subs, sub)
+		close(sub.events)
+	}
+	close(d.done)
+}
+
+func (d *EventDispatcher) dispatch(event EventListResponseUnion) {
+	if event == nil {
+		return
+	}
+	sessionID, hasSession := EventSessionID(event)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for sub := range d.subs {
+		if !sub.match(event) {
+			continue
+		}
+		if len(sub.sessions) > 0 {
+			if _, ok := sub.sessions[sessionID]; !hasSession || !ok {
+				continue
+			}
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Done is closed once the underlying stream has ended and all subscriptions
+// have been closed.
+func (d *EventDispatcher) Done() <-chan struct{} {
+	return d.done
+}
+
+// Err returns the error that ended the stream, if any. It is only meaningful
+// after Done is closed.
+func (d *EventDispatcher) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+// Close closes the underlying stream, which ends all subscriptions.
+func (d *EventDispatcher) Close() error {
+	err := d.stream.Close()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.started {
+		// Nothing is reading the stream, so end the subscriptions here.
+		d.started = true
+		d.finish()
+	}
+	return err
+}
+
+// Subscription is a handler registered on an [EventDispatcher].
+type Subscription struct {
+	dispatcher *EventDispatcher
+	events     chan EventListResponseUnion
+	match      func(EventListResponseUnion) bool
+	sessions   map[string]struct{}
+	bufferSize int
+	dropped    atomic.Uint64
+	done       chan struct{}
+}
+
+// SubscriptionOption configures a [Subscription].
+type SubscriptionOption func(*Subscription)
+
+// WithSessionFilter only delivers events that belong to one of the given
+// sessions. Events that are not tied to a session, such as
+// installation.updated, are not delivered to filtered subscriptions.
+func WithSessionFilter(sessionIDs ...string) SubscriptionOption {
+	return func(s *Subscription) {
+		for _, id := range sessionIDs {
+			s.sessions[id] = struct{}{}
+		}
+	}
+}
+
+// WithBufferSize sets how many events may be queued for a subscriber before
+// further events are dropped. The default is 64.
+func WithBufferSize(size int) SubscriptionOption {
+	return func(s *Subscription) {
+		if size > 0 {
+			s.bufferSize = size
+		}
+	}
+}
+
+// Subscribe registers handler for events of type T, which is one of the
+// variants of [EventListResponseUnion] such as
+// [EventListResponseEventSessionIdle]. Use [EventListResponseUnion] itself to
+// receive every event.
+//
+//	sgptcoder.Subscribe(d, func(e sgptcoder.EventListResponseEventSessionIdle) {
+//		fmt.Println("idle", e.Properties.SessionID)
+//	}, sgptcoder.WithSessionFilter(session.ID))
+//
+// Handlers of one subscription are called sequentially, in stream order, on a
+// goroutine owned by the subscription. Subscribing to a dispatcher whose
+// stream has already ended returns a subscription that is already done.
+func Subscribe[T EventListResponseUnion](d *EventDispatcher, handler func(T), opts ...SubscriptionOption) *Subscription {
+	sub := &Subscription{
+		dispatcher: d,
+		match: func(e EventListResponseUnion) bool {
+			_, ok := e.(T)
+			return ok
+		},
+		sessions:   map[string]struct{}{},
+		bufferSize: 64,
+		done:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(sub)
+	}
+	sub.events = make(chan EventListResponseUnion, sub.bufferSize)
+
+	d.mu.Lock()
+	if d.closed {
+		close(sub.events)
+	} else {
+		d.subs[sub] = struct{}{}
+	}
+	d.mu.Unlock()
+
+	go func() {
+		defer close(sub.done)
+		for event := range sub.events {
+			handler(event.(T))
+		}
+	}()
+	return sub
+}
+
+// Unsubscribe removes the subscription from its dispatcher. Events that were
+// already buffered are still delivered before [Subscription.Done] is closed.
+// It is safe to call Unsubscribe from within the handler.
+func (s *Subscription) Unsubscribe() {
+	d := s.dispatcher
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.subs[s]; ok {
+		delete(d.subs, s)
+		close(s.events)
+	}
+}
+
+// Done is closed once the subscription has ended and its handler has returned
+// for the last time.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Dropped returns the number of events dropped because the buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// EventSessionID returns the ID of the session the event belongs to. It
+// reports false for events that are not tied to a session.
+func EventSessionID(event EventListResponseUnion) (string, bool) {
+	switch e := event.(type) {
+	case EventListResponseEventMessageUpdated:
+		return e.Properties.Info.SessionID, true
+	case EventListResponseEventMessageRemoved:
+		return e.Properties.SessionID, true
+	case EventListResponseEventMessagePartUpdated:
+		return e.Properties.Part.SessionID, true
+	case EventListResponseEventMessagePartRemoved:
+		return e.Properties.SessionID, true
+	case EventListResponseEventSessionCompacted:
+		return e.Properties.SessionID, true
+	case EventListResponseEventPermissionUpdated:
+		return e.Properties.SessionID, true
+	case EventListResponseEventPermissionReplied:
+		return e.Properties.SessionID, true
+	case EventListResponseEventSessionIdle:
+		return e.Properties.SessionID, true
+	case EventListResponseEventSessionUpdated:
+		return e.Properties.Info.ID, true
+	case EventListResponseEventSessionDeleted:
+		return e.Properties.Info.ID, true
+	case EventListResponseEventSessionError:
+		return e.Properties.SessionID, e.Properties.SessionID != ""
+	}
+	return "", false
+}
@@ -0,0 +1,103 @@
+package sgptcoder_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+)
+
+type sliceEventStream struct {
+	events []sgptcoder.EventListResponse
+	cur    sgptcoder.EventListResponse
+}
+
+func newSliceEventStream(t *testing.T, raw ...string) *sliceEventStream {
+	t.Helper()
+	s := &sliceEventStream{}
+	for _, r := range raw {
+		var event sgptcoder.EventListResponse
+		if err := json.Unmarshal([]byte(r), &event); err != nil {
+			t.Fatalf("failed to decode event %s: %v", r, err)
+		}
+		s.events = append(s.events, event)
+	}
+	return s
+}
+
+func (s *sliceEventStream) Next() bool {
+	if len(s.events) == 0 {
+		return false
+	}
+	s.cur, s.events = s.events[0], s.events[1:]
+	return true
+}
+
+func (s *sliceEventStream) Current() sgptcoder.EventListResponse { return s.cur }
+func (s *sliceEventStream) Err() error                           { return nil }
+func (s *sliceEventStream) Close() error                         { return nil }
+
+func TestEventDispatcherFiltersByTypeAndSession(t *testing.T) {
+	stream := newSliceEventStream(t,
+		`{"type":"server.connected","properties":{}}`,
+		`{"type":"session.idle","properties":{"sessionID":"ses_a"}}`,
+		`{"type":"session.idle","properties":{"sessionID":"ses_b"}}`,
+		`{"type":"permission.replied","properties":{"sessionID":"ses_a","permissionID":"per_1","response":"once"}}`,
+	)
+	d := sgptcoder.NewEventDispatcher(stream)
+
+	var idle []string
+	idleSub := sgptcoder.Subscribe(d, func(e sgptcoder.EventListResponseEventSessionIdle) {
+		idle = append(idle, e.Properties.SessionID)
+	}, sgptcoder.WithSessionFilter("ses_a"))
+
+	var all []sgptcoder.EventListResponseUnion
+	allSub := sgptcoder.Subscribe(d, func(e sgptcoder.EventListResponseUnion) {
+		all = append(all, e)
+	})
+
+	d.Start()
+	for _, sub := range []*sgptcoder.Subscription{idleSub, allSub} {
+		select {
+		case <-sub.Done():
+		case <-time.After(time.Second):
+			t.Fatal("subscription did not finish after the stream ended")
+		}
+	}
+
+	if len(idle) != 1 || idle[0] != "ses_a" {
+		t.Errorf("expected only the idle event for ses_a, got %v", idle)
+	}
+	if len(all) != 4 {
+		t.Errorf("expected all 4 events, got %d", len(all))
+	}
+	if d.Err() != nil {
+		t.Errorf("unexpected error: %v", d.Err())
+	}
+}
+
+func TestEventDispatcherDropsWhenBufferIsFull(t *testing.T) {
+	stream := newSliceEventStream(t,
+		`{"type":"session.idle","properties":{"sessionID":"ses_a"}}`,
+		`{"type":"session.idle","properties":{"sessionID":"ses_a"}}`,
+		`{"type":"session.idle","properties":{"sessionID":"ses_a"}}`,
+	)
+	d := sgptcoder.NewEventDispatcher(stream)
+
+	release := make(chan struct{})
+	sub := sgptcoder.Subscribe(d, func(e sgptcoder.EventListResponseEventSessionIdle) {
+		<-release
+	}, sgptcoder.WithBufferSize(1))
+
+	d.Start()
+	<-d.Done()
+	close(release)
+	<-sub.Done()
+
+	// The handler may or may not have picked up the first event before the
+	// rest arrived, so at least one of the three must have been dropped.
+	if sub.Dropped() == 0 {
+		t.Error("expected events to be dropped by a blocked subscriber")
+	}
+}
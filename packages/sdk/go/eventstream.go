@@ -0,0 +1,35 @@
+package sgptcoder
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/skorpland/sgptcoder-sdk-go/internal/requestconfig"
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+	"github.com/skorpland/sgptcoder-sdk-go/packages/ssestream"
+)
+
+// ListStreamingReconnecting is like [EventService.ListStreaming], but the
+// returned stream reconnects whenever the connection drops instead of ending.
+// Each reconnection resends the last received event ID in the Last-Event-ID
+// header and waits according to the server's "retry:" hint with exponential
+// backoff. Use [ssestream.ReconnectOptions.OnReconnect] to be told about
+// reconnects and possible gaps in the event sequence.
+//
+// The stream ends when ctx is done, when it is closed, or when
+// reconnect.MaxAttempts consecutive attempts have failed.
+func (r *EventService) ListStreamingReconnecting(ctx context.Context, query EventListParams, reconnect ssestream.ReconnectOptions, opts ...option.RequestOption) (stream *ssestream.ReconnectingStream[EventListResponse]) {
+	opts = append(r.Options[:], opts...)
+	opts = append([]option.RequestOption{option.WithHeader("Accept", "text/event-stream")}, opts...)
+	path := "event"
+	connect := func(ctx context.Context, lastEventID string) (*http.Response, error) {
+		var raw *http.Response
+		reqOpts := opts
+		if lastEventID != "" {
+			reqOpts = append(reqOpts[:len(reqOpts):len(reqOpts)], option.WithHeader("Last-Event-ID", lastEventID))
+		}
+		err := requestconfig.ExecuteNewRequest(ctx, http.MethodGet, path, query, &raw, reqOpts...)
+		return raw, err
+	}
+	return ssestream.NewReconnectingStream[EventListResponse](ctx, connect, reconnect)
+}
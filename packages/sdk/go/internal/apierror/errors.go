@@ -0,0 +1,124 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/skorpland/sgptcoder-sdk-go/shared"
+)
+
+var (
+	// ErrNotFound matches API errors with a 404 Not Found status.
+	ErrNotFound = errors.New("sgptcoder: not found")
+	// ErrSessionNotFound matches API errors for a session that does not exist.
+	ErrSessionNotFound = errors.New("sgptcoder: session not found")
+	// ErrSessionBusy matches API errors for a session that is still working
+	// on an earlier request.
+	ErrSessionBusy = errors.New("sgptcoder: session is busy")
+)
+
+// NamedError is an error the server reported by name that has no dedicated
+// type, such as ProviderModelNotFoundError or ConfigJsonError.
+type NamedError struct {
+	Name string
+	// Data is the raw JSON data of the error.
+	Data json.RawMessage
+}
+
+func (e *NamedError) Error() string {
+	var data struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(e.Data, &data) == nil && data.Message != "" {
+		return e.Name + ": " + data.Message
+	}
+	return e.Name
+}
+
+// named is the body of the server's error responses.
+type named struct {
+	Name string          `json:"name"`
+	Data json.RawMessage `json:"data"`
+}
+
+func (r *Error) named() (named, bool) {
+	var body named
+	if err := json.Unmarshal([]byte(r.JSON.RawJSON()), &body); err != nil || body.Name == "" {
+		return named{}, false
+	}
+	return body, true
+}
+
+// Unwrap returns the error named in the response body, so that errors.As
+// finds a [shared.ProviderAuthError], [shared.UnknownError],
+// [shared.MessageAbortedError] or [NamedError] in API errors.
+func (r *Error) Unwrap() error {
+	body, ok := r.named()
+	if !ok {
+		return nil
+	}
+	raw, _ := json.Marshal(body)
+	switch body.Name {
+	case string(shared.ProviderAuthErrorNameProviderAuthError):
+		var err shared.ProviderAuthError
+		if json.Unmarshal(raw, &err) == nil {
+			return err
+		}
+	case string(shared.UnknownErrorNameUnknownError):
+		var err shared.UnknownError
+		if json.Unmarshal(raw, &err) == nil {
+			return err
+		}
+	case string(shared.MessageAbortedErrorNameMessageAbortedError):
+		var err shared.MessageAbortedError
+		if json.Unmarshal(raw, &err) == nil {
+			return err
+		}
+	}
+	return &NamedError{Name: body.Name, Data: body.Data}
+}
+
+// Is reports whether the error matches [ErrNotFound], [ErrSessionNotFound]
+// or [ErrSessionBusy].
+func (r *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return r.StatusCode == http.StatusNotFound
+	case ErrSessionNotFound:
+		return r.isSessionNotFound()
+	case ErrSessionBusy:
+		if r.StatusCode == http.StatusConflict {
+			return true
+		}
+		body, _ := r.named()
+		return body.Name == "BusyError"
+	}
+	return false
+}
+
+// isSessionNotFound reports whether a 404 is for the session the request is
+// about: a request to /session/{id} itself, or one the server answered with a
+// NotFoundError naming that session. A missing message of a session that
+// exists is only [ErrNotFound].
+func (r *Error) isSessionNotFound() bool {
+	if r.StatusCode != http.StatusNotFound || r.Request == nil {
+		return false
+	}
+	segments := strings.Split(strings.Trim(r.Request.URL.Path, "/"), "/")
+	i := slices.Index(segments, "session")
+	if i < 0 || i+1 >= len(segments) {
+		return false
+	}
+	if i+2 == len(segments) {
+		return true
+	}
+	body, _ := r.named()
+	var data struct {
+		SessionID string `json:"sessionID"`
+	}
+	json.Unmarshal(body.Data, &data)
+	return body.Name == "NotFoundError" && data.SessionID == segments[i+1]
+}
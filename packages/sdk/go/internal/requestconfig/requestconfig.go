@@ -206,6 +206,7 @@ type HTTPDoer interface {
 // composing the RequestOption instead if possible.
 type RequestConfig struct {
 	MaxRetries     int
+	RetryPolicy    *RetryPolicy
 	RequestTimeout time.Duration
 	Context        context.Context
 	Request        *http.Request
@@ -240,12 +241,36 @@ func applyMiddleware(middleware middleware, next middlewareNext) middlewareNext
 	}
 }
 
-func shouldRetry(req *http.Request, res *http.Response) bool {
-	// If there is no way to recover the Body, then we shouldn't retry.
-	if req.Body != nil && req.GetBody == nil {
-		return false
-	}
+// RetryPolicy customizes which failed requests are retried and how long to
+// wait between attempts. Zero fields fall back to the defaults.
+type RetryPolicy struct {
+	// Retryable reports whether an attempt should be retried. res is nil when
+	// the attempt failed with a transport error err. Defaults to
+	// [DefaultRetryable].
+	Retryable func(res *http.Response, err error) bool
+	// InitialDelay is the delay before the first retry. Defaults to 0.5s.
+	InitialDelay time.Duration
+	// MaxDelay caps the delay between two attempts. Defaults to 8s.
+	MaxDelay time.Duration
+	// Multiplier grows the delay after every retry. Defaults to 2.
+	Multiplier float64
+	// Jitter is the fraction of each delay, between 0 and 1, that is randomly
+	// subtracted from it. Defaults to 0.25; use a negative value to disable
+	// jitter entirely.
+	Jitter float64
+	// IgnoreRetryAfter disables honoring the Retry-After and Retry-After-Ms
+	// response headers.
+	IgnoreRetryAfter bool
+	// Budget caps the total time spent waiting between retries of a single
+	// call. A retry whose delay would exceed the remaining budget is not
+	// attempted. Zero means no limit.
+	Budget time.Duration
+}
 
+// DefaultRetryable retries connection errors, 408 Request Timeout, 409
+// Conflict, 429 Rate Limit and >=500 Internal errors, unless the server
+// overrides the decision with an x-should-retry header.
+func DefaultRetryable(res *http.Response, err error) bool {
 	// If there is no response, that indicates that there is a connection error
 	// so we retry the request.
 	if res == nil {
@@ -267,6 +292,24 @@ func shouldRetry(req *http.Request, res *http.Response) bool {
 		res.StatusCode >= http.StatusInternalServerError
 }
 
+func shouldRetry(req *http.Request, res *http.Response, err error, policy *RetryPolicy) bool {
+	// If there is no way to recover the Body, then we shouldn't retry.
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+
+	if policy != nil && policy.Retryable != nil {
+		return policy.Retryable(res, err)
+	}
+	return DefaultRetryable(res, err)
+}
+
+// RetryAfter returns how long a response asks the client to wait before
+// sending more requests, from its Retry-After-Ms or Retry-After header.
+func RetryAfter(resp *http.Response) (time.Duration, bool) {
+	return parseRetryAfterHeader(resp)
+}
+
 func parseRetryAfterHeader(resp *http.Response) (time.Duration, bool) {
 	if resp == nil {
 		return 0, false
@@ -357,25 +400,62 @@ func (b *bodyWithTimeout) Close() error {
 	return err
 }
 
-func retryDelay(res *http.Response, retryCount int) time.Duration {
+func retryDelay(res *http.Response, retryCount int, policy *RetryPolicy) time.Duration {
+	if policy == nil {
+		policy = &RetryPolicy{}
+	}
+
 	// If the API asks us to wait a certain amount of time (and it's a reasonable amount),
 	// just do what it says.
-
-	if retryAfterDelay, ok := parseRetryAfterHeader(res); ok && 0 <= retryAfterDelay && retryAfterDelay < time.Minute {
-		return retryAfterDelay
+	if !policy.IgnoreRetryAfter {
+		if retryAfterDelay, ok := parseRetryAfterHeader(res); ok && 0 <= retryAfterDelay && retryAfterDelay < time.Minute {
+			return retryAfterDelay
+		}
 	}
 
+	initialDelay := 500 * time.Millisecond
+	if policy.InitialDelay > 0 {
+		initialDelay = policy.InitialDelay
+	}
 	maxDelay := 8 * time.Second
-	delay := time.Duration(0.5 * float64(time.Second) * math.Pow(2, float64(retryCount)))
-	if delay > maxDelay {
+	if policy.MaxDelay > 0 {
+		maxDelay = policy.MaxDelay
+	}
+	multiplier := 2.0
+	if policy.Multiplier > 0 {
+		multiplier = policy.Multiplier
+	}
+	jitterFraction := 0.25
+	if policy.Jitter != 0 {
+		jitterFraction = min(policy.Jitter, 1)
+	}
+
+	delay := time.Duration(float64(initialDelay) * math.Pow(multiplier, float64(retryCount)))
+	if delay > maxDelay || delay < 0 {
 		delay = maxDelay
 	}
 
-	jitter := rand.Int63n(int64(delay / 4))
-	delay -= time.Duration(jitter)
+	if jitterFraction > 0 {
+		if maxJitter := int64(float64(delay) * jitterFraction); maxJitter > 0 {
+			delay -= time.Duration(rand.Int63n(maxJitter))
+		}
+	}
 	return delay
 }
 
+// sleep waits for the given duration, returning early with the context's error
+// if it is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 func (cfg *RequestConfig) Execute() (err error) {
 	if cfg.BaseURL == nil {
 		if cfg.DefaultBaseURL != nil {
@@ -426,6 +506,7 @@ func (cfg *RequestConfig) Execute() (err error) {
 
 	var res *http.Response
 	var cancel context.CancelFunc
+	var waited time.Duration
 	for retryCount := 0; retryCount <= cfg.MaxRetries; retryCount += 1 {
 		ctx := cfg.Request.Context()
 		if cfg.RequestTimeout != time.Duration(0) && isBeforeContextDeadline(time.Now().Add(cfg.RequestTimeout), ctx) {
@@ -447,9 +528,15 @@ func (cfg *RequestConfig) Execute() (err error) {
 		if ctx != nil && ctx.Err() != nil {
 			return ctx.Err()
 		}
-		if !shouldRetry(cfg.Request, res) || retryCount >= cfg.MaxRetries {
+		if !shouldRetry(cfg.Request, res, err, cfg.RetryPolicy) || retryCount >= cfg.MaxRetries {
+			break
+		}
+
+		delay := retryDelay(res, retryCount, cfg.RetryPolicy)
+		if cfg.RetryPolicy != nil && cfg.RetryPolicy.Budget > 0 && waited+delay > cfg.RetryPolicy.Budget {
 			break
 		}
+		waited += delay
 
 		// Prepare next request and wait for the retry delay
 		if cfg.Request.GetBody != nil {
@@ -469,7 +556,9 @@ func (cfg *RequestConfig) Execute() (err error) {
 			res.Body.Close()
 		}
 
-		time.Sleep(retryDelay(res, retryCount))
+		if err := sleep(cfg.Request.Context(), delay); err != nil {
+			return err
+		}
 	}
 
 	// Save *http.Response if it is requested to, even if there was an error making the request. This is
@@ -581,6 +670,7 @@ func (cfg *RequestConfig) Clone(ctx context.Context) *RequestConfig {
 	}
 	new := &RequestConfig{
 		MaxRetries:     cfg.MaxRetries,
+		RetryPolicy:    cfg.RetryPolicy,
 		RequestTimeout: cfg.RequestTimeout,
 		Context:        ctx,
 		Request:        req,
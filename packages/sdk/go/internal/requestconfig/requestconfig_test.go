@@ -0,0 +1,110 @@
+package requestconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestConfig(t *testing.T, ctx context.Context, serverURL string, opts ...RequestOption) *RequestConfig {
+	t.Helper()
+	base, err := url.Parse(serverURL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts = append([]RequestOption{RequestOptionFunc(func(r *RequestConfig) error {
+		r.BaseURL = base
+		return nil
+	})}, opts...)
+	cfg, err := NewRequestConfig(ctx, http.MethodGet, "session", nil, nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestRetrySleepIsCancelledWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg := newTestConfig(t, ctx, server.URL, RequestOptionFunc(func(r *RequestConfig) error {
+		r.RetryPolicy = &RetryPolicy{InitialDelay: time.Hour, Jitter: -1}
+		return nil
+	}))
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	err := cfg.Execute()
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("retry sleep was not interrupted, took %s", elapsed)
+	}
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, context.Background(), server.URL, RequestOptionFunc(func(r *RequestConfig) error {
+		r.RetryPolicy = &RetryPolicy{
+			Retryable: func(res *http.Response, err error) bool {
+				return res != nil && res.StatusCode != http.StatusBadGateway
+			},
+		}
+		return nil
+	}))
+	if err := cfg.Execute(); err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("expected a single attempt, got %d", got)
+	}
+}
+
+func TestRetryPolicyBudget(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, context.Background(), server.URL, RequestOptionFunc(func(r *RequestConfig) error {
+		r.MaxRetries = 10
+		r.RetryPolicy = &RetryPolicy{
+			InitialDelay: 10 * time.Millisecond,
+			Multiplier:   1,
+			Jitter:       -1,
+			Budget:       25 * time.Millisecond,
+		}
+		return nil
+	}))
+	if err := cfg.Execute(); err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("expected 3 attempts within the budget, got %d", got)
+	}
+}
+
+func TestRetryDelayDefaults(t *testing.T) {
+	for retryCount, want := range []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second} {
+		got := retryDelay(nil, retryCount, nil)
+		if got > want || got < want*3/4 {
+			t.Errorf("retry %d: expected delay in [%s, %s], got %s", retryCount, want*3/4, want, got)
+		}
+	}
+}
@@ -3,3 +3,7 @@
 package internal
 
 const PackageVersion = "0.15.0" // x-release-please-version
+
+// ServerVersion is the version of the server whose API the package was
+// generated from.
+const ServerVersion = "0.9.7"
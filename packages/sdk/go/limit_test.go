@@ -0,0 +1,187 @@
+package sgptcoder_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+)
+
+// sessionListServer answers session lists after an optional handler runs.
+func sessionListServer(t *testing.T, handle func(w http.ResponseWriter, r *http.Request) bool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handle != nil && !handle(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestMaxInFlight(t *testing.T) {
+	var inFlight, peak int32
+	server := sessionListServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return true
+	})
+	client := sgptcoder.NewClient(option.WithBaseURL(server.URL), option.WithMaxInFlight(2))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Session.List(context.Background(), sgptcoder.SessionListParams{}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if peak := atomic.LoadInt32(&peak); peak != 2 {
+		t.Errorf("expected at most 2 requests in flight, got %d", peak)
+	}
+}
+
+func TestMaxInFlightSkipsLongPolls(t *testing.T) {
+	poll := make(chan struct{})
+	server := sessionListServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/tui/control/next" {
+			<-poll
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"path":"/tui/open-help","body":{}}`))
+			return false
+		}
+		return true
+	})
+	defer close(poll)
+	client := sgptcoder.NewClient(option.WithBaseURL(server.URL), option.WithMaxInFlight(1))
+
+	go client.Get(context.Background(), "/tui/control/next", nil, nil)
+	time.Sleep(20 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := client.Session.List(ctx, sgptcoder.SessionListParams{}); err != nil {
+		t.Fatalf("expected the long poll not to hold the only slot, got %v", err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	server := sessionListServer(t, nil)
+	client := sgptcoder.NewClient(option.WithBaseURL(server.URL), option.WithRateLimit(50, 1))
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := client.Session.List(ctx, sgptcoder.SessionListParams{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("expected requests to be paced, took %v", elapsed)
+	}
+
+	// Waiting stops with the context
+	limiter := option.NewLimiter(option.LimiterConfig{Rate: 0.1, Burst: 1})
+	client = sgptcoder.NewClient(option.WithBaseURL(server.URL), option.WithLimiter(limiter))
+	if _, err := client.Session.List(ctx, sgptcoder.SessionListParams{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err := client.Session.List(ctx, sgptcoder.SessionListParams{}, option.WithMaxRetries(0))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the wait to end with the context, got %v", err)
+	}
+}
+
+func TestLimiterRetryAfter(t *testing.T) {
+	var calls int32
+	server := sessionListServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After-Ms", "100")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return false
+		}
+		return true
+	})
+	limiter := option.NewLimiter(option.LimiterConfig{})
+	client := sgptcoder.NewClient(option.WithBaseURL(server.URL), option.WithLimiter(limiter), option.WithMaxRetries(0))
+	ctx := context.Background()
+
+	if _, err := client.Session.List(ctx, sgptcoder.SessionListParams{}); err == nil {
+		t.Fatal("expected the first request to be rejected")
+	}
+	start := time.Now()
+	if _, err := client.Session.List(ctx, sgptcoder.SessionListParams{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("expected the limiter to honor Retry-After, took %v", elapsed)
+	}
+}
+
+func TestLimiterPaths(t *testing.T) {
+	server := sessionListServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+		if strings.HasSuffix(r.URL.Path, "/find/file") {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[]`))
+			return false
+		}
+		return true
+	})
+	limiter := option.NewLimiter(option.LimiterConfig{Rate: 0.1, Burst: 1})
+	client := sgptcoder.NewClient(option.WithBaseURL(server.URL), option.WithLimiter(limiter, "find"), option.WithMaxRetries(0))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Session requests are not limited
+	for i := 0; i < 3; i++ {
+		if _, err := client.Session.List(ctx, sgptcoder.SessionListParams{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := client.Find.Files(ctx, sgptcoder.FindFilesParams{Query: sgptcoder.F("a")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	short, cancelShort := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelShort()
+	if _, err := client.Find.Files(short, sgptcoder.FindFilesParams{Query: sgptcoder.F("a")}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected find requests to be limited, got %v", err)
+	}
+
+	// Paths match from the start of the API route, after the base URL path
+	limiter = option.NewLimiter(option.LimiterConfig{Rate: 0.1, Burst: 1})
+	client = sgptcoder.NewClient(option.WithBaseURL(server.URL+"/api/"), option.WithLimiter(limiter, "file"), option.WithMaxRetries(0))
+	for i := 0; i < 3; i++ {
+		if _, err := client.Find.Files(ctx, sgptcoder.FindFilesParams{Query: sgptcoder.F("a")}); err != nil {
+			t.Fatalf("expected find/file not to be limited as file, got %v", err)
+		}
+	}
+	if _, err := client.File.Status(ctx, sgptcoder.FileStatusParams{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	short, cancelShort = context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelShort()
+	if _, err := client.File.Status(short, sgptcoder.FileStatusParams{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected file requests to be limited, got %v", err)
+	}
+}
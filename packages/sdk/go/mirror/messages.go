@@ -0,0 +1,124 @@
+package mirror
+
+import (
+	"slices"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+)
+
+// Message is a message with its parts, in the order the server sent them.
+type Message struct {
+	Info  sgptcoder.MessageUnion
+	Parts []sgptcoder.PartUnion
+}
+
+// MessageID returns the ID of a user or assistant message.
+func MessageID(info sgptcoder.MessageUnion) string {
+	switch casted := info.(type) {
+	case sgptcoder.UserMessage:
+		return casted.ID
+	case sgptcoder.AssistantMessage:
+		return casted.ID
+	}
+	return ""
+}
+
+// PartID returns the ID of a message part.
+func PartID(part sgptcoder.PartUnion) string {
+	switch casted := part.(type) {
+	case sgptcoder.TextPart:
+		return casted.ID
+	case sgptcoder.ReasoningPart:
+		return casted.ID
+	case sgptcoder.FilePart:
+		return casted.ID
+	case sgptcoder.ToolPart:
+		return casted.ID
+	case sgptcoder.StepStartPart:
+		return casted.ID
+	case sgptcoder.StepFinishPart:
+		return casted.ID
+	}
+	return ""
+}
+
+// Index returns the index of the message with the given ID, or -1.
+func Index(messages []Message, id string) int {
+	return slices.IndexFunc(messages, func(m Message) bool {
+		return MessageID(m.Info) == id
+	})
+}
+
+// UpdateMessage applies a message.updated event to a list of messages,
+// replacing the message if it is known and otherwise inserting it in ID order.
+func UpdateMessage(messages []Message, info sgptcoder.Message) []Message {
+	if index := Index(messages, info.ID); index > -1 {
+		messages[index] = Message{
+			Info:  info.AsUnion(),
+			Parts: messages[index].Parts,
+		}
+		return messages
+	}
+
+	// Find the correct insertion index by scanning backwards
+	// Most messages are added to the end, so start from the end
+	insertIndex := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if MessageID(messages[i].Info) < info.ID {
+			insertIndex = i + 1
+			break
+		}
+	}
+
+	message := Message{
+		Info:  info.AsUnion(),
+		Parts: []sgptcoder.PartUnion{},
+	}
+	return slices.Insert(messages, insertIndex, message)
+}
+
+// RemoveMessage applies a message.removed event to a list of messages.
+func RemoveMessage(messages []Message, id string) []Message {
+	if index := Index(messages, id); index > -1 {
+		return slices.Delete(messages, index, index+1)
+	}
+	return messages
+}
+
+// UpdatePart applies a message.part.updated event to a list of messages,
+// replacing the part if it is known and otherwise appending it to its
+// message. Parts of unknown messages are ignored.
+func UpdatePart(messages []Message, part sgptcoder.Part) []Message {
+	messageIndex := Index(messages, part.MessageID)
+	if messageIndex == -1 {
+		return messages
+	}
+	message := messages[messageIndex]
+	partIndex := slices.IndexFunc(message.Parts, func(p sgptcoder.PartUnion) bool {
+		return PartID(p) == part.ID
+	})
+	if partIndex > -1 {
+		message.Parts[partIndex] = part.AsUnion()
+	} else {
+		message.Parts = append(message.Parts, part.AsUnion())
+	}
+	messages[messageIndex] = message
+	return messages
+}
+
+// RemovePart applies a message.part.removed event to a list of messages.
+func RemovePart(messages []Message, messageID, id string) []Message {
+	messageIndex := Index(messages, messageID)
+	if messageIndex == -1 {
+		return messages
+	}
+	message := messages[messageIndex]
+	partIndex := slices.IndexFunc(message.Parts, func(p sgptcoder.PartUnion) bool {
+		return PartID(p) == id
+	})
+	if partIndex > -1 {
+		message.Parts = slices.Delete(message.Parts, partIndex, partIndex+1)
+		messages[messageIndex] = message
+	}
+	return messages
+}
@@ -0,0 +1,418 @@
+// Package mirror keeps a local, event-sourced copy of the server's sessions
+// and messages.
+//
+// A [Store] bootstraps from [sgptcoder.SessionService.List] and
+// [sgptcoder.SessionService.Messages], then applies the events of an
+// [sgptcoder.EventStream] to stay in sync. It resyncs from the API whenever the
+// stream reconnects, so events missed while disconnected are not lost.
+package mirror
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+	"github.com/skorpland/sgptcoder-sdk-go/packages/ssestream"
+)
+
+// ChangeKind is the kind of a [Change].
+type ChangeKind string
+
+const (
+	ChangeSessionUpdated ChangeKind = "session.updated"
+	ChangeSessionDeleted ChangeKind = "session.deleted"
+	ChangeMessageUpdated ChangeKind = "message.updated"
+	ChangeMessageRemoved ChangeKind = "message.removed"
+	ChangePartUpdated    ChangeKind = "message.part.updated"
+	ChangePartRemoved    ChangeKind = "message.part.removed"
+	// ChangeResynced is sent after the store reloaded state from the API, in
+	// place of the changes it may have missed. An empty SessionID means every
+	// session was reloaded.
+	ChangeResynced ChangeKind = "resynced"
+)
+
+// Change describes an update to the store. Fields that do not apply to the
+// kind of change are empty.
+type Change struct {
+	Kind      ChangeKind
+	SessionID string
+	MessageID string
+	PartID    string
+}
+
+// Store is a thread-safe mirror of sessions and their messages.
+//
+// Sessions are always mirrored. Messages are only mirrored for tracked
+// sessions, see [Store.Track] and [WithAllMessages], so a store for a single
+// conversation does not hold the history of every session.
+type Store struct {
+	client *sgptcoder.Client
+	opts   []option.RequestOption
+	query  sgptcoder.SessionListParams
+	all    bool
+	onErr  func(error)
+
+	mu       sync.RWMutex
+	sessions map[string]sgptcoder.Session
+	messages map[string][]Message
+
+	watchMu  sync.Mutex
+	watchers map[*watcher]struct{}
+}
+
+type watcher struct {
+	fn func(Change)
+}
+
+// Option configures a [Store].
+type Option func(*Store)
+
+// WithDirectory mirrors the sessions of the given project directory instead
+// of the server's current one.
+func WithDirectory(directory string) Option {
+	return func(s *Store) {
+		s.query.Directory = sgptcoder.F(directory)
+	}
+}
+
+// WithAllMessages tracks the messages of every session.
+func WithAllMessages() Option {
+	return func(s *Store) {
+		s.all = true
+	}
+}
+
+// WithRequestOptions adds request options to every request the store sends.
+func WithRequestOptions(opts ...option.RequestOption) Option {
+	return func(s *Store) {
+		s.opts = append(s.opts, opts...)
+	}
+}
+
+// WithErrorHandler calls fn with the errors of the reloads [Store.Sync] does
+// on reconnects and compactions. Sync keeps going after such an error, so the
+// store may be stale until the next reload succeeds.
+func WithErrorHandler(fn func(error)) Option {
+	return func(s *Store) {
+		s.onErr = fn
+	}
+}
+
+// New returns an empty store that loads state with the given client.
+func New(client *sgptcoder.Client, opts ...Option) *Store {
+	s := &Store{
+		client:   client,
+		sessions: map[string]sgptcoder.Session{},
+		messages: map[string][]Message{},
+		watchers: map[*watcher]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Load replaces the mirrored sessions, and the messages of tracked sessions,
+// with the server's.
+func (s *Store) Load(ctx context.Context) error {
+	sessions, err := s.client.Session.List(ctx, s.query, s.opts...)
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	tracked := make([]string, 0, len(s.messages))
+	for id := range s.messages {
+		tracked = append(tracked, id)
+	}
+	s.mu.RUnlock()
+	if s.all {
+		tracked = tracked[:0]
+		for _, session := range *sessions {
+			tracked = append(tracked, session.ID)
+		}
+	}
+
+	messages := map[string][]Message{}
+	for _, id := range tracked {
+		list, err := s.fetchMessages(ctx, id)
+		if err != nil {
+			if errors.Is(err, sgptcoder.ErrSessionNotFound) {
+				continue
+			}
+			return err
+		}
+		messages[id] = list
+	}
+
+	s.mu.Lock()
+	s.sessions = map[string]sgptcoder.Session{}
+	for _, session := range *sessions {
+		s.sessions[session.ID] = session
+	}
+	s.messages = messages
+	s.mu.Unlock()
+
+	s.notify(Change{Kind: ChangeResynced})
+	return nil
+}
+
+// Track loads the messages of a session and keeps them in sync from then on.
+// Tracking an already tracked session reloads its messages.
+func (s *Store) Track(ctx context.Context, sessionID string) error {
+	list, err := s.fetchMessages(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.messages[sessionID] = list
+	s.mu.Unlock()
+
+	s.notify(Change{Kind: ChangeResynced, SessionID: sessionID})
+	return nil
+}
+
+// Untrack stops mirroring the messages of a session and drops them.
+func (s *Store) Untrack(sessionID string) {
+	s.mu.Lock()
+	delete(s.messages, sessionID)
+	s.mu.Unlock()
+}
+
+func (s *Store) fetchMessages(ctx context.Context, sessionID string) ([]Message, error) {
+	params := sgptcoder.SessionMessagesParams{Directory: s.query.Directory}
+	response, err := s.client.Session.Messages(ctx, sessionID, params, s.opts...)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]Message, 0, len(*response))
+	for _, message := range *response {
+		parts := make([]sgptcoder.PartUnion, 0, len(message.Parts))
+		for _, part := range message.Parts {
+			parts = append(parts, part.AsUnion())
+		}
+		messages = append(messages, Message{Info: message.Info.AsUnion(), Parts: parts})
+	}
+	return messages, nil
+}
+
+// Apply updates the store with an event and reports whether it changed
+// anything. Events that do not concern sessions or messages are ignored.
+func (s *Store) Apply(event sgptcoder.EventListResponseUnion) bool {
+	change, ok := s.apply(event)
+	if ok {
+		s.notify(change)
+	}
+	return ok
+}
+
+func (s *Store) apply(event sgptcoder.EventListResponseUnion) (Change, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch e := event.(type) {
+	case sgptcoder.EventListResponseEventSessionUpdated:
+		info := e.Properties.Info
+		s.sessions[info.ID] = info
+		if _, tracked := s.messages[info.ID]; s.all && !tracked {
+			s.messages[info.ID] = []Message{}
+		}
+		return Change{Kind: ChangeSessionUpdated, SessionID: info.ID}, true
+
+	case sgptcoder.EventListResponseEventSessionDeleted:
+		id := e.Properties.Info.ID
+		_, known := s.sessions[id]
+		delete(s.sessions, id)
+		delete(s.messages, id)
+		return Change{Kind: ChangeSessionDeleted, SessionID: id}, known
+
+	case sgptcoder.EventListResponseEventMessageUpdated:
+		info := e.Properties.Info
+		messages, tracked := s.messages[info.SessionID]
+		if !tracked {
+			return Change{}, false
+		}
+		s.messages[info.SessionID] = UpdateMessage(messages, info)
+		return Change{Kind: ChangeMessageUpdated, SessionID: info.SessionID, MessageID: info.ID}, true
+
+	case sgptcoder.EventListResponseEventMessageRemoved:
+		p := e.Properties
+		messages, tracked := s.messages[p.SessionID]
+		if !tracked || Index(messages, p.MessageID) == -1 {
+			return Change{}, false
+		}
+		s.messages[p.SessionID] = RemoveMessage(messages, p.MessageID)
+		return Change{Kind: ChangeMessageRemoved, SessionID: p.SessionID, MessageID: p.MessageID}, true
+
+	case sgptcoder.EventListResponseEventMessagePartUpdated:
+		part := e.Properties.Part
+		messages, tracked := s.messages[part.SessionID]
+		if !tracked || Index(messages, part.MessageID) == -1 {
+			return Change{}, false
+		}
+		s.messages[part.SessionID] = UpdatePart(messages, part)
+		return Change{Kind: ChangePartUpdated, SessionID: part.SessionID, MessageID: part.MessageID, PartID: part.ID}, true
+
+	case sgptcoder.EventListResponseEventMessagePartRemoved:
+		p := e.Properties
+		messages, tracked := s.messages[p.SessionID]
+		if !tracked || Index(messages, p.MessageID) == -1 {
+			return Change{}, false
+		}
+		s.messages[p.SessionID] = RemovePart(messages, p.MessageID, p.PartID)
+		return Change{Kind: ChangePartRemoved, SessionID: p.SessionID, MessageID: p.MessageID, PartID: p.PartID}, true
+	}
+	return Change{}, false
+}
+
+// Sync applies the events of a stream until it ends or ctx is done. The
+// store is loaded again on every server.connected event, which the server
+// sends first on each connection, so a stream from
+// [sgptcoder.EventService.ListStreamingReconnecting] resyncs after every
+// reconnect. A session.compacted event reloads the messages of that session.
+// A failed reload does not end Sync, see [WithErrorHandler].
+func (s *Store) Sync(ctx context.Context, stream sgptcoder.EventStream) error {
+	for stream.Next() {
+		switch e := stream.Current().AsUnion().(type) {
+		case sgptcoder.EventListResponseEventServerConnected:
+			if err := s.Load(ctx); err != nil {
+				s.reportError(err)
+			}
+		case sgptcoder.EventListResponseEventSessionCompacted:
+			if s.tracked(e.Properties.SessionID) {
+				if err := s.Track(ctx, e.Properties.SessionID); err != nil {
+					s.reportError(err)
+				}
+			}
+		case nil:
+		default:
+			s.Apply(e)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func (s *Store) reportError(err error) {
+	if s.onErr != nil && !errors.Is(err, context.Canceled) {
+		s.onErr(err)
+	}
+}
+
+// Run opens a reconnecting event stream and syncs the store from it until
+// ctx is done or the stream gives up.
+func (s *Store) Run(ctx context.Context, reconnect ssestream.ReconnectOptions) error {
+	query := sgptcoder.EventListParams{Directory: s.query.Directory}
+	stream := s.client.Event.ListStreamingReconnecting(ctx, query, reconnect, s.opts...)
+	defer stream.Close()
+	return s.Sync(ctx, stream)
+}
+
+func (s *Store) tracked(sessionID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.messages[sessionID]
+	return ok
+}
+
+// Watch calls fn after every change to the store until the returned function
+// is called. fn runs on the goroutine applying the change, so it must not
+// block; it may query the store.
+func (s *Store) Watch(fn func(Change)) (stop func()) {
+	w := &watcher{fn: fn}
+	s.watchMu.Lock()
+	s.watchers[w] = struct{}{}
+	s.watchMu.Unlock()
+	return func() {
+		s.watchMu.Lock()
+		delete(s.watchers, w)
+		s.watchMu.Unlock()
+	}
+}
+
+func (s *Store) notify(change Change) {
+	s.watchMu.Lock()
+	watchers := make([]*watcher, 0, len(s.watchers))
+	for w := range s.watchers {
+		watchers = append(watchers, w)
+	}
+	s.watchMu.Unlock()
+	for _, w := range watchers {
+		w.fn(change)
+	}
+}
+
+// Sessions returns the mirrored sessions, most recently updated first.
+func (s *Store) Sessions() []sgptcoder.Session {
+	s.mu.RLock()
+	sessions := make([]sgptcoder.Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	s.mu.RUnlock()
+	sort.SliceStable(sessions, func(i, j int) bool {
+		if sessions[i].Time.Updated != sessions[j].Time.Updated {
+			return sessions[i].Time.Updated > sessions[j].Time.Updated
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions
+}
+
+// Session returns a mirrored session.
+func (s *Store) Session(id string) (sgptcoder.Session, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	session, ok := s.sessions[id]
+	return session, ok
+}
+
+// Children returns the mirrored sessions whose parent is the given session,
+// most recently updated first.
+func (s *Store) Children(parentID string) []sgptcoder.Session {
+	var children []sgptcoder.Session
+	for _, session := range s.Sessions() {
+		if session.ParentID == parentID {
+			children = append(children, session)
+		}
+	}
+	return children
+}
+
+// Messages returns a copy of the messages of a session in ID order, and
+// whether the session is tracked.
+func (s *Store) Messages(sessionID string) ([]Message, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	messages, ok := s.messages[sessionID]
+	if !ok {
+		return nil, false
+	}
+	copied := make([]Message, len(messages))
+	for i, message := range messages {
+		copied[i] = Message{Info: message.Info, Parts: slices.Clone(message.Parts)}
+	}
+	return copied, true
+}
+
+// Message returns a copy of a message of a tracked session.
+func (s *Store) Message(sessionID, messageID string) (Message, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	messages := s.messages[sessionID]
+	index := Index(messages, messageID)
+	if index == -1 {
+		return Message{}, false
+	}
+	message := messages[index]
+	return Message{Info: message.Info, Parts: slices.Clone(message.Parts)}, true
+}
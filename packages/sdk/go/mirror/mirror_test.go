@@ -0,0 +1,254 @@
+package mirror_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/mirror"
+	"github.com/skorpland/sgptcoder-sdk-go/sdktest"
+)
+
+func textPrompt(text string) sgptcoder.SessionPromptParams {
+	return sgptcoder.SessionPromptParams{
+		Parts: sgptcoder.F([]sgptcoder.SessionPromptParamsPartUnion{
+			sgptcoder.TextPartInputParam{Type: sgptcoder.F(sgptcoder.TextPartInputTypeText), Text: sgptcoder.F(text)},
+		}),
+	}
+}
+
+func event(t *testing.T, data string) sgptcoder.EventListResponse {
+	t.Helper()
+	var e sgptcoder.EventListResponse
+	if err := e.UnmarshalJSON([]byte(data)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return e
+}
+
+// fakeStream replays a fixed list of events, running a hook before each one.
+type fakeStream struct {
+	events []sgptcoder.EventListResponse
+	before []func()
+	index  int
+}
+
+func (s *fakeStream) Next() bool {
+	if s.index >= len(s.events) {
+		return false
+	}
+	if hook := s.before[s.index]; hook != nil {
+		hook()
+	}
+	s.index++
+	return true
+}
+
+func (s *fakeStream) Current() sgptcoder.EventListResponse { return s.events[s.index-1] }
+func (s *fakeStream) Err() error                           { return nil }
+func (s *fakeStream) Close() error                         { return nil }
+
+// waitFor polls the store until cond holds or the test times out.
+func waitFor(t *testing.T, store *mirror.Store, cond func() bool) {
+	t.Helper()
+	changed := make(chan struct{}, 1)
+	stop := store.Watch(func(mirror.Change) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	defer stop()
+	timeout := time.After(5 * time.Second)
+	for !cond() {
+		select {
+		case <-changed:
+		case <-timeout:
+			t.Fatal("timed out waiting for the store")
+		}
+	}
+}
+
+func TestStoreFollowsPrompt(t *testing.T) {
+	server := sdktest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sessionID := server.AddSession("first", "")
+	server.Enqueue(sdktest.Reply(sdktest.Text("Hello")))
+	if _, err := client.Session.Prompt(ctx, sessionID, textPrompt("hi")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	store := mirror.New(client)
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Track(ctx, sessionID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	messages, ok := store.Messages(sessionID)
+	if !ok || len(messages) != 2 {
+		t.Fatalf("expected the bootstrapped messages, got %d", len(messages))
+	}
+
+	var changes []mirror.Change
+	connected := make(chan struct{})
+	stop := store.Watch(func(change mirror.Change) {
+		if change.Kind == mirror.ChangeResynced && len(changes) == 0 {
+			// The resync that follows server.connected
+			close(connected)
+		}
+		changes = append(changes, change)
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- store.Sync(ctx, client.Event.ListStreaming(ctx, sgptcoder.EventListParams{}))
+	}()
+	<-connected
+
+	otherID := server.AddSession("second", "")
+	server.Enqueue(sdktest.Reply(sdktest.Text("Bye ", "now")))
+	if _, err := client.Session.Prompt(ctx, sessionID, textPrompt("bye")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server.Enqueue(sdktest.Reply(sdktest.Text("untracked")))
+	if _, err := client.Session.Prompt(ctx, otherID, textPrompt("hi")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.Session.Delete(ctx, otherID, sgptcoder.SessionDeleteParams{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	waitFor(t, store, func() bool {
+		_, ok := store.Session(otherID)
+		messages, _ := store.Messages(sessionID)
+		return !ok && len(messages) == 4
+	})
+	stop()
+	cancel()
+	<-done
+
+	messages, _ = store.Messages(sessionID)
+	reply := messages[3]
+	if _, ok := reply.Info.(sgptcoder.AssistantMessage); !ok {
+		t.Fatalf("expected an assistant reply, got %T", reply.Info)
+	}
+	if len(reply.Parts) != 3 || reply.Parts[1].(sgptcoder.TextPart).Text != "Bye now" {
+		t.Errorf("expected the streamed parts, got %+v", reply.Parts)
+	}
+	if _, ok := store.Messages(otherID); ok {
+		t.Error("expected the deleted session not to be tracked")
+	}
+
+	var sawPart, sawDelete bool
+	for _, change := range changes {
+		if change.SessionID == otherID && change.Kind != mirror.ChangeSessionUpdated && change.Kind != mirror.ChangeSessionDeleted {
+			t.Errorf("unexpected change for an untracked session: %+v", change)
+		}
+		sawPart = sawPart || change.Kind == mirror.ChangePartUpdated
+		sawDelete = sawDelete || change.Kind == mirror.ChangeSessionDeleted
+	}
+	if !sawPart || !sawDelete {
+		t.Errorf("expected part and delete notifications, got %+v", changes)
+	}
+}
+
+func TestStoreResyncsOnReconnect(t *testing.T) {
+	server := sdktest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+	store := mirror.New(server.Client(), mirror.WithAllMessages())
+
+	var missedID string
+	stream := &fakeStream{
+		events: []sgptcoder.EventListResponse{
+			event(t, `{"type":"server.connected","properties":{}}`),
+			event(t, `{"type":"server.connected","properties":{}}`),
+		},
+		before: []func(){
+			nil,
+			// The session is created while disconnected, so its event is missed
+			func() { missedID = server.AddSession("missed", "") },
+		},
+	}
+	if err := store.Sync(ctx, stream); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if session, ok := store.Session(missedID); !ok || session.Title != "missed" {
+		t.Errorf("expected the resync to find the missed session, got %+v", store.Sessions())
+	}
+	if messages, ok := store.Messages(missedID); !ok || len(messages) != 0 {
+		t.Errorf("expected the missed session to be tracked, got %v", messages)
+	}
+}
+
+func TestStoreSyncSurvivesFailedReload(t *testing.T) {
+	server := sdktest.NewServer()
+	client := server.Client()
+	server.Close()
+
+	var errs []error
+	store := mirror.New(client, mirror.WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	stream := &fakeStream{
+		events: []sgptcoder.EventListResponse{
+			event(t, `{"type":"server.connected","properties":{}}`),
+			event(t, `{"type":"session.updated","properties":{"info":{"id":"ses_1","title":"one","time":{"created":1,"updated":1}}}}`),
+		},
+		before: []func(){nil, nil},
+	}
+	if err := store.Sync(context.Background(), stream); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected the failed reload to be reported once, got %v", errs)
+	}
+	if _, ok := store.Session("ses_1"); !ok {
+		t.Error("expected events after the failed reload to be applied")
+	}
+}
+
+func TestStoreApply(t *testing.T) {
+	store := mirror.New(nil, mirror.WithAllMessages())
+	apply := func(data string) bool {
+		return store.Apply(event(t, data).AsUnion())
+	}
+
+	apply(`{"type":"session.updated","properties":{"info":{"id":"ses_1","title":"one","time":{"created":1,"updated":1}}}}`)
+	apply(`{"type":"message.updated","properties":{"info":{"id":"msg_2","sessionID":"ses_1","role":"user","time":{"created":2}}}}`)
+	apply(`{"type":"message.updated","properties":{"info":{"id":"msg_1","sessionID":"ses_1","role":"user","time":{"created":1}}}}`)
+	apply(`{"type":"message.part.updated","properties":{"part":{"id":"prt_1","messageID":"msg_2","sessionID":"ses_1","type":"text","text":"a"}}}`)
+	apply(`{"type":"message.part.updated","properties":{"part":{"id":"prt_2","messageID":"msg_2","sessionID":"ses_1","type":"text","text":"b"}}}`)
+	apply(`{"type":"message.part.updated","properties":{"part":{"id":"prt_1","messageID":"msg_2","sessionID":"ses_1","type":"text","text":"c"}}}`)
+	if apply(`{"type":"message.part.updated","properties":{"part":{"id":"prt_3","messageID":"msg_9","sessionID":"ses_1","type":"text","text":"d"}}}`) {
+		t.Error("expected a part of an unknown message to be ignored")
+	}
+
+	messages, _ := store.Messages("ses_1")
+	if len(messages) != 2 || mirror.MessageID(messages[0].Info) != "msg_1" {
+		t.Fatalf("expected messages in ID order, got %+v", messages)
+	}
+	parts := messages[1].Parts
+	if len(parts) != 2 || parts[0].(sgptcoder.TextPart).Text != "c" || parts[1].(sgptcoder.TextPart).Text != "b" {
+		t.Errorf("expected the replaced part in place, got %+v", parts)
+	}
+
+	// Copies do not alias the store
+	messages[1].Parts[0] = nil
+	if message, _ := store.Message("ses_1", "msg_2"); message.Parts[0] == nil {
+		t.Error("expected the store to be unaffected by changes to a copy")
+	}
+
+	apply(`{"type":"message.part.removed","properties":{"sessionID":"ses_1","messageID":"msg_2","partID":"prt_1"}}`)
+	apply(`{"type":"message.removed","properties":{"sessionID":"ses_1","messageID":"msg_1"}}`)
+	messages, _ = store.Messages("ses_1")
+	if len(messages) != 1 || len(messages[0].Parts) != 1 || mirror.PartID(messages[0].Parts[0]) != "prt_2" {
+		t.Errorf("expected the removals to apply, got %+v", messages)
+	}
+	if apply(`{"type":"session.idle","properties":{"sessionID":"ses_1"}}`) {
+		t.Error("expected unrelated events to be ignored")
+	}
+}
@@ -0,0 +1,157 @@
+package option
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/skorpland/sgptcoder-sdk-go/internal/requestconfig"
+)
+
+// WithBearerToken returns a RequestOption that authenticates requests with
+// an Authorization: Bearer header.
+func WithBearerToken(token string) RequestOption {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// TokenFunc returns the bearer token for requests. It is called for the first
+// request, and again with refresh set after the server rejected the current
+// token with 401 Unauthorized.
+type TokenFunc func(ctx context.Context, refresh bool) (string, error)
+
+// WithBearerTokenFunc returns a RequestOption that authenticates requests
+// with bearer tokens from fn. The token is cached between requests. When the
+// server answers 401 Unauthorized, the token is refreshed and the request is
+// sent once more with the new token; event streams and long polls are
+// authenticated the same way.
+func WithBearerTokenFunc(fn TokenFunc) RequestOption {
+	source := &tokenSource{fn: fn}
+	return WithMiddleware(source.middleware)
+}
+
+type tokenSource struct {
+	fn TokenFunc
+
+	mu    sync.Mutex
+	token string
+}
+
+// get returns the cached token, or a new one when there is none or the
+// rejected token is still the cached one.
+func (s *tokenSource) get(ctx context.Context, rejected string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refresh := rejected != ""
+	if s.token != "" && (!refresh || s.token != rejected) {
+		// Another request refreshed the token in the meantime
+		return s.token, nil
+	}
+	token, err := s.fn(ctx, refresh)
+	if err != nil {
+		return "", fmt.Errorf("requestoption: getting bearer token: %w", err)
+	}
+	s.token = token
+	return token, nil
+}
+
+func (s *tokenSource) middleware(req *http.Request, next MiddlewareNext) (*http.Response, error) {
+	token, err := s.get(req.Context(), "")
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err := next(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// The body cannot be sent again
+		return res, nil
+	}
+
+	token, err = s.get(req.Context(), token)
+	if err != nil {
+		return res, nil
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return res, nil
+		}
+	}
+	res.Body.Close()
+	retry.Header.Set("Authorization", "Bearer "+token)
+	return next(retry)
+}
+
+// WithBasicAuth returns a RequestOption that authenticates requests with
+// HTTP basic authentication.
+func WithBasicAuth(username, password string) RequestOption {
+	return requestconfig.RequestOptionFunc(func(r *requestconfig.RequestConfig) error {
+		r.Request.SetBasicAuth(username, password)
+		return nil
+	})
+}
+
+// WithTLSConfig returns a RequestOption that connects to the server with the
+// given TLS configuration, for example to present a client certificate or to
+// trust a private certificate authority. It replaces the HTTP client, so it
+// must not be combined with [WithHTTPClient]; combined with [WithDialer] or
+// [WithUnixSocket], both apply to the same transport.
+func WithTLSConfig(config *tls.Config) RequestOption {
+	if config == nil {
+		return requestconfig.RequestOptionFunc(func(r *requestconfig.RequestConfig) error {
+			return fmt.Errorf("requestoption: tls config cannot be nil")
+		})
+	}
+	return transportOption(func(transport *http.Transport) {
+		transport.TLSClientConfig = config
+	})
+}
+
+// WithClientCertificate returns a RequestOption that connects to the server
+// with mutual TLS. certFile and keyFile hold the PEM encoded client
+// certificate and key; either may be empty to only set caFile, a PEM bundle of
+// the certificate authorities trusted for the server instead of the system's.
+// Files are read once, when the option is created.
+func WithClientCertificate(certFile, keyFile, caFile string) RequestOption {
+	config, err := LoadTLSConfig(certFile, keyFile, caFile)
+	if err != nil {
+		return requestconfig.RequestOptionFunc(func(r *requestconfig.RequestConfig) error {
+			return err
+		})
+	}
+	return WithTLSConfig(config)
+}
+
+// LoadTLSConfig builds a TLS configuration from PEM files, see
+// [WithClientCertificate].
+func LoadTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("requestoption: a client certificate needs both a certificate and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("requestoption: loading client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("requestoption: reading CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("requestoption: no certificates found in CA bundle %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
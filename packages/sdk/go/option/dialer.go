@@ -0,0 +1,102 @@
+package option
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/skorpland/sgptcoder-sdk-go/internal/requestconfig"
+)
+
+// DialContextFunc opens a connection to the server, like
+// [net.Dialer.DialContext].
+type DialContextFunc = func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// WithDialer returns a RequestOption that opens connections to the server
+// with dial instead of a TCP dialer. It replaces the HTTP client, so it must
+// not be combined with [WithHTTPClient]; combined with [WithTLSConfig], both
+// apply to the same transport. Connections are reused across requests made
+// with the same option.
+func WithDialer(dial DialContextFunc) RequestOption {
+	if dial == nil {
+		return requestconfig.RequestOptionFunc(func(r *requestconfig.RequestConfig) error {
+			return fmt.Errorf("requestoption: dialer cannot be nil")
+		})
+	}
+	return transportOption(func(transport *http.Transport) {
+		transport.DialContext = dial
+	})
+}
+
+// WithUnixSocket returns a RequestOption that sends requests to a server
+// listening on the Unix domain socket at path, including event streams and
+// long polls. It replaces the base URL and the HTTP client, so it must not be
+// combined with [WithHTTPClient]. A base URL of the form unix:///path/to.sock
+// has the same effect.
+func WithUnixSocket(path string) RequestOption {
+	if path == "" {
+		return requestconfig.RequestOptionFunc(func(r *requestconfig.RequestConfig) error {
+			return fmt.Errorf("requestoption: unix socket path cannot be empty")
+		})
+	}
+	setTransport := transportOption(func(transport *http.Transport) {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", path)
+		}
+		// The connection never leaves the host
+		transport.Proxy = nil
+	})
+	return requestconfig.RequestOptionFunc(func(r *requestconfig.RequestConfig) error {
+		r.BaseURL = unixSocketBaseURL()
+		return setTransport.Apply(r)
+	})
+}
+
+// unixSocketBaseURL is the URL requests over a Unix socket are made to. The
+// host only fills the Host header; the connection always goes to the socket.
+func unixSocketBaseURL() *url.URL {
+	return &url.URL{Scheme: "http", Host: "localhost", Path: "/"}
+}
+
+// transportClients are the HTTP clients made by transportOption.
+var transportClients sync.Map
+
+// transportOption returns a RequestOption that replaces the HTTP client with
+// one whose transport is set up by configure. If an earlier option set the
+// client with transportOption, configure changes a copy of its transport, so
+// for example a Unix socket and a TLS configuration both take effect. The
+// clients are kept so connections are reused across requests.
+func transportOption(configure func(*http.Transport)) RequestOption {
+	var mu sync.Mutex
+	clients := map[*http.Client]*http.Client{}
+	return requestconfig.RequestOptionFunc(func(r *requestconfig.RequestConfig) error {
+		mu.Lock()
+		defer mu.Unlock()
+		client, ok := clients[r.HTTPClient]
+		if !ok {
+			transport := defaultTransport()
+			if _, made := transportClients.Load(r.HTTPClient); made {
+				transport = r.HTTPClient.Transport.(*http.Transport).Clone()
+			}
+			configure(transport)
+			client = &http.Client{Transport: transport}
+			transportClients.Store(client, struct{}{})
+			clients[r.HTTPClient] = client
+		}
+		r.HTTPClient = client
+		r.CustomHTTPDoer = nil
+		return nil
+	})
+}
+
+// defaultTransport returns a copy of [http.DefaultTransport] to customize.
+func defaultTransport() *http.Transport {
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		return transport.Clone()
+	}
+	return &http.Transport{}
+}
@@ -0,0 +1,215 @@
+package option
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skorpland/sgptcoder-sdk-go/internal/requestconfig"
+)
+
+// Limiter limits the rate and the concurrency of requests. A limiter can be
+// shared by several clients to put a common limit on all of them.
+//
+// When a response asks the client to slow down, with a 429 Too Many Requests
+// or 503 Service Unavailable status and a Retry-After header, requests
+// waiting on the limiter are held back until that time has passed.
+type Limiter struct {
+	rate  float64
+	burst float64
+	slots chan struct{}
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	paused time.Time
+}
+
+// LimiterConfig configures a [Limiter]. Zero values leave that limit off.
+type LimiterConfig struct {
+	// Rate is the number of requests per second.
+	Rate float64
+	// Burst is the number of requests that may be sent at once before Rate
+	// applies. It is at least one.
+	Burst int
+	// MaxInFlight is the number of requests that may wait for their response
+	// at the same time. A request is in flight until its response body is
+	// closed. Event streams and long polls do not count, since they stay open
+	// until there is something to send.
+	MaxInFlight int
+}
+
+// NewLimiter returns a limiter with the given limits.
+func NewLimiter(config LimiterConfig) *Limiter {
+	l := &Limiter{rate: config.Rate, burst: float64(config.Burst)}
+	if l.burst < 1 {
+		l.burst = 1
+	}
+	l.tokens = l.burst
+	if config.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, config.MaxInFlight)
+	}
+	return l
+}
+
+// WithLimiter returns a RequestOption that sends requests through the
+// limiter. Given paths, only requests to those API routes are limited, such
+// as "find" for the find endpoints or "session" for the session endpoints;
+// this way a client can have separate limits per service. Paths match from
+// the start of the API route, so "file" limits the file endpoints but not
+// "find/file". Limiters of several options all apply.
+func WithLimiter(limiter *Limiter, paths ...string) RequestOption {
+	return requestconfig.RequestOptionFunc(func(r *requestconfig.RequestConfig) error {
+		r.Middlewares = append(r.Middlewares, func(req *http.Request, next MiddlewareNext) (*http.Response, error) {
+			route := apiRoute(r.BaseURL, req.URL.Path)
+			if len(paths) > 0 && !matchesRoute(route, paths) {
+				return next(req)
+			}
+			return limiter.do(req, next, route)
+		})
+		return nil
+	})
+}
+
+// WithRateLimit returns a RequestOption that limits requests to rate per
+// second, with bursts of up to burst requests.
+func WithRateLimit(rate float64, burst int) RequestOption {
+	return WithLimiter(NewLimiter(LimiterConfig{Rate: rate, Burst: burst}))
+}
+
+// WithMaxInFlight returns a RequestOption that limits how many requests wait
+// for their response at the same time. Event streams and long polls are not
+// counted.
+func WithMaxInFlight(n int) RequestOption {
+	return WithLimiter(NewLimiter(LimiterConfig{MaxInFlight: n}))
+}
+
+// longPolls are the API routes that only answer once there is something to
+// send, so holding an in-flight slot for them would block other requests.
+var longPolls = []string{"tui/control/next"}
+
+// apiRoute returns the segments of a request path after the path of the base
+// URL.
+func apiRoute(base *url.URL, path string) []string {
+	if base != nil {
+		path = strings.TrimPrefix(path, strings.TrimSuffix(base.Path, "/"))
+	}
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// matchesRoute reports whether an API route is one of the routes, or below
+// one.
+func matchesRoute(route []string, routes []string) bool {
+	for _, r := range routes {
+		prefix := strings.Split(strings.Trim(r, "/"), "/")
+		if len(prefix) <= len(route) && slices.Equal(route[:len(prefix)], prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Limiter) do(req *http.Request, next MiddlewareNext, route []string) (*http.Response, error) {
+	ctx := req.Context()
+	stream := req.Header.Get("Accept") == "text/event-stream" || matchesRoute(route, longPolls)
+	if l.slots != nil && !stream {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if l.slots != nil && !stream {
+			<-l.slots
+		}
+	}
+
+	if err := l.wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	res, err := next(req)
+	if res != nil && (res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable) {
+		if delay, ok := requestconfig.RetryAfter(res); ok && delay > 0 {
+			l.pause(delay)
+		}
+	}
+	if err != nil || res == nil || res.Body == nil {
+		release()
+		return res, err
+	}
+	res.Body = &releasingBody{ReadCloser: res.Body, release: release}
+	return res, nil
+}
+
+// wait blocks until the rate limit allows a request, or ctx is done.
+func (l *Limiter) wait(ctx context.Context) error {
+	for {
+		delay := l.reserve()
+		if delay == 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token and returns zero, or returns how long to wait before
+// trying again.
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Before(l.paused) {
+		return l.paused.Sub(now)
+	}
+	if l.rate <= 0 {
+		return 0
+	}
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// pause holds back requests for delay.
+func (l *Limiter) pause(delay time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(delay); until.After(l.paused) {
+		l.paused = until
+	}
+}
+
+// releasingBody frees the in-flight slot of a request once its response body
+// is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -25,8 +25,14 @@ type RequestOption = requestconfig.RequestOption
 // WithBaseURL returns a RequestOption that sets the BaseURL for the client.
 //
 // For security reasons, ensure that the base URL is trusted.
+//
+// A unix:///path/to.sock URL connects to a server listening on that Unix
+// domain socket, like [WithUnixSocket].
 func WithBaseURL(base string) RequestOption {
 	u, err := url.Parse(base)
+	if err == nil && u.Scheme == "unix" {
+		return WithUnixSocket(u.Host + u.Path)
+	}
 	if err == nil && u.Path != "" && !strings.HasSuffix(u.Path, "/") {
 		u.Path += "/"
 	}
@@ -106,6 +112,30 @@ func WithMaxRetries(retries int) RequestOption {
 	})
 }
 
+// RetryPolicy customizes which failed requests are retried, the backoff between
+// attempts and the total time a call may spend waiting to retry. See
+// [WithRetryPolicy].
+type RetryPolicy = requestconfig.RetryPolicy
+
+// DefaultRetryable is the retry decision used when a [RetryPolicy] does not set
+// Retryable. It retries connection errors, 408 Request Timeout, 409 Conflict,
+// 429 Rate Limit and >=500 Internal errors. Custom policies can call it to
+// extend the default rules rather than replace them.
+func DefaultRetryable(res *http.Response, err error) bool {
+	return requestconfig.DefaultRetryable(res, err)
+}
+
+// WithRetryPolicy returns a RequestOption that customizes how failed requests
+// are retried. The number of retries is still controlled by [WithMaxRetries].
+// Waiting between retries is always aborted as soon as the request context is
+// done.
+func WithRetryPolicy(policy RetryPolicy) RequestOption {
+	return requestconfig.RequestOptionFunc(func(r *requestconfig.RequestConfig) error {
+		r.RetryPolicy = &policy
+		return nil
+	})
+}
+
 // WithHeader returns a RequestOption that sets the header value to the associated key. It overwrites
 // any value if there was one already present.
 func WithHeader(key, value string) RequestOption {
@@ -0,0 +1,256 @@
+package ssestream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Connector opens a new event stream. lastEventID is the ID of the last event
+// received on a previous connection, or empty if no event carried an ID yet.
+// Implementations should send it to the server in the Last-Event-ID header.
+type Connector func(ctx context.Context, lastEventID string) (*http.Response, error)
+
+// ReconnectOptions configures a [ReconnectingStream].
+type ReconnectOptions struct {
+	// InitialDelay is the delay before the first reconnection attempt when the
+	// server has not sent a "retry:" hint. Defaults to one second.
+	InitialDelay time.Duration
+	// MaxDelay caps the exponential backoff between consecutive failed
+	// attempts. A server "retry:" hint larger than MaxDelay is still honored.
+	// Defaults to 30 seconds.
+	MaxDelay time.Duration
+	// MaxAttempts is the number of consecutive failed attempts after which the
+	// stream gives up. Zero retries until the context is done.
+	MaxAttempts int
+	// OnReconnect, if set, is called before every reconnection attempt. It runs
+	// on the goroutine calling Next and must not block.
+	OnReconnect func(Reconnect)
+}
+
+// Reconnect describes a reconnection attempt of a [ReconnectingStream].
+type Reconnect struct {
+	// Attempt is the number of consecutive attempts, starting at 1.
+	Attempt int
+	// LastEventID is the ID that will be sent in the Last-Event-ID header.
+	LastEventID string
+	// Delay is how long the stream waits before connecting.
+	Delay time.Duration
+	// Err is the reason the previous connection ended, or nil if the server
+	// closed it cleanly.
+	Err error
+	// Gap reports that events may have been missed while disconnected, because
+	// the server never assigned event IDs and so cannot replay them.
+	Gap bool
+}
+
+// ReconnectingStream is like [Stream] but transparently reconnects when the
+// underlying connection drops, resuming from the last event ID it has seen.
+type ReconnectingStream[T any] struct {
+	ctx     context.Context
+	connect Connector
+	opts    ReconnectOptions
+
+	mu      sync.Mutex
+	decoder Decoder
+	closed  bool
+
+	dialed  bool
+	attempt int
+	cause   error
+	lastID  string
+	retry   time.Duration
+	cur     T
+	err     error
+}
+
+// NewReconnectingStream returns a stream that calls connect to open the first
+// connection and again after every disconnect, until ctx is done, the stream is
+// closed, or opts.MaxAttempts consecutive attempts have failed.
+func NewReconnectingStream[T any](ctx context.Context, connect Connector, opts ReconnectOptions) *ReconnectingStream[T] {
+	if opts.InitialDelay <= 0 {
+		opts.InitialDelay = time.Second
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 30 * time.Second
+	}
+	return &ReconnectingStream[T]{
+		ctx:     ctx,
+		connect: connect,
+		opts:    opts,
+	}
+}
+
+// Next returns false once the stream can no longer make progress. Unlike
+// [Stream.Next], a dropped connection does not end the stream; it is
+// reopened according to the [ReconnectOptions].
+func (s *ReconnectingStream[T]) Next() bool {
+	if s.err != nil {
+		return false
+	}
+
+	for {
+		decoder := s.currentDecoder()
+		if decoder == nil {
+			if !s.dial() {
+				return false
+			}
+			continue
+		}
+
+		if decoder.Next() {
+			evt := decoder.Event()
+			if evt.ID != "" {
+				s.lastID = evt.ID
+			}
+			if evt.Retry > 0 {
+				s.retry = evt.Retry
+			}
+			s.attempt = 0
+
+			var nxt T
+			s.err = json.Unmarshal(evt.Data, &nxt)
+			if s.err != nil {
+				return false
+			}
+			s.cur = nxt
+			return true
+		}
+
+		s.cause = decoder.Err()
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return false
+		}
+		decoder.Close()
+		s.decoder = nil
+		s.mu.Unlock()
+	}
+}
+
+func (s *ReconnectingStream[T]) currentDecoder() Decoder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.decoder
+}
+
+// dial opens a new connection, waiting out the backoff first unless this is
+// the very first connection.
+func (s *ReconnectingStream[T]) dial() bool {
+	for {
+		if s.dialed {
+			s.attempt++
+			if s.opts.MaxAttempts > 0 && s.attempt > s.opts.MaxAttempts {
+				s.err = fmt.Errorf("ssestream: giving up after %d reconnection attempts: %w", s.opts.MaxAttempts, s.cause)
+				return false
+			}
+
+			delay := s.backoff()
+			if s.opts.OnReconnect != nil {
+				s.opts.OnReconnect(Reconnect{
+					Attempt:     s.attempt,
+					LastEventID: s.lastID,
+					Delay:       delay,
+					Err:         s.cause,
+					Gap:         s.lastID == "",
+				})
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-s.ctx.Done():
+				timer.Stop()
+				s.err = s.ctx.Err()
+				return false
+			case <-timer.C:
+			}
+		}
+		s.dialed = true
+
+		if s.isClosed() {
+			return false
+		}
+
+		res, err := s.connect(s.ctx, s.lastID)
+		if s.ctx.Err() != nil {
+			if res != nil && res.Body != nil {
+				res.Body.Close()
+			}
+			s.err = s.ctx.Err()
+			return false
+		}
+		if err == nil && (res == nil || res.Body == nil) {
+			err = errors.New("ssestream: connector returned no response body")
+		}
+		if err != nil {
+			s.cause = err
+			continue
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			res.Body.Close()
+			return false
+		}
+		s.decoder = NewDecoder(res)
+		s.mu.Unlock()
+		return true
+	}
+}
+
+func (s *ReconnectingStream[T]) backoff() time.Duration {
+	base := s.opts.InitialDelay
+	if s.retry > 0 {
+		base = s.retry
+	}
+
+	delay := base
+	for i := 1; i < s.attempt && delay < s.opts.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.opts.MaxDelay {
+		delay = max(s.opts.MaxDelay, base)
+	}
+	return delay
+}
+
+func (s *ReconnectingStream[T]) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// LastEventID returns the ID of the last event received, which is sent to the
+// server on the next reconnection.
+func (s *ReconnectingStream[T]) LastEventID() string {
+	return s.lastID
+}
+
+func (s *ReconnectingStream[T]) Current() T {
+	return s.cur
+}
+
+func (s *ReconnectingStream[T]) Err() error {
+	return s.err
+}
+
+// Close stops the stream. It is safe to call from another goroutine while Next
+// is blocked; Next then returns false without reconnecting.
+func (s *ReconnectingStream[T]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.decoder == nil {
+		return nil
+	}
+	return s.decoder.Close()
+}
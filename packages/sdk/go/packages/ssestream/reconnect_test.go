@@ -0,0 +1,146 @@
+package ssestream_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/skorpland/sgptcoder-sdk-go/packages/ssestream"
+)
+
+type payload struct {
+	N int `json:"n"`
+}
+
+func TestDecoderParsesIDAndRetry(t *testing.T) {
+	body := "retry: 250\n\n: keepalive\n\nid: 1\ndata: {\"n\":1}\n\ndata: {\"n\":2}\n\n"
+	res := &http.Response{
+		Header: http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:   io.NopCloser(strings.NewReader(body)),
+	}
+	decoder := ssestream.NewDecoder(res)
+
+	var events []ssestream.Event
+	for decoder.Next() {
+		events = append(events, decoder.Event())
+	}
+	if err := decoder.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	for _, evt := range events {
+		if evt.ID != "1" {
+			t.Errorf("expected id to carry over as 1, got %q", evt.ID)
+		}
+		if evt.Retry != 250*time.Millisecond {
+			t.Errorf("expected retry of 250ms, got %s", evt.Retry)
+		}
+	}
+}
+
+func TestReconnectingStreamResumesFromLastEventID(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		last := r.Header.Get("Last-Event-ID")
+		seen = append(seen, last)
+		mu.Unlock()
+
+		start := 1
+		if last != "" {
+			fmt.Sscanf(last, "%d", &start)
+			start++
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		// Each connection delivers two events and then drops.
+		for n := start; n < start+2; n++ {
+			fmt.Fprintf(w, "retry: 1\nid: %d\ndata: {\"n\":%d}\n\n", n, n)
+		}
+	}))
+	defer server.Close()
+
+	connect := func(ctx context.Context, lastEventID string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		return http.DefaultClient.Do(req)
+	}
+
+	var reconnects []ssestream.Reconnect
+	stream := ssestream.NewReconnectingStream[payload](context.Background(), connect, ssestream.ReconnectOptions{
+		OnReconnect: func(r ssestream.Reconnect) { reconnects = append(reconnects, r) },
+	})
+	defer stream.Close()
+
+	for want := 1; want <= 5; want++ {
+		if !stream.Next() {
+			t.Fatalf("stream ended early: %v", stream.Err())
+		}
+		if got := stream.Current().N; got != want {
+			t.Fatalf("expected event %d, got %d", want, got)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"", "2", "4"}; strings.Join(seen, ",") != strings.Join(want, ",") {
+		t.Errorf("expected Last-Event-ID headers %q, got %q", want, seen)
+	}
+	if len(reconnects) != 2 {
+		t.Fatalf("expected 2 reconnect notifications, got %d", len(reconnects))
+	}
+	if reconnects[0].Gap || reconnects[0].LastEventID != "2" || reconnects[0].Delay != time.Millisecond {
+		t.Errorf("unexpected reconnect notification: %+v", reconnects[0])
+	}
+}
+
+func TestReconnectingStreamGivesUp(t *testing.T) {
+	attempts := 0
+	connect := func(ctx context.Context, lastEventID string) (*http.Response, error) {
+		attempts++
+		return nil, fmt.Errorf("connection refused")
+	}
+	stream := ssestream.NewReconnectingStream[payload](context.Background(), connect, ssestream.ReconnectOptions{
+		InitialDelay: time.Millisecond,
+		MaxAttempts:  2,
+	})
+	if stream.Next() {
+		t.Fatal("expected stream to end")
+	}
+	if stream.Err() == nil || !strings.Contains(stream.Err().Error(), "connection refused") {
+		t.Errorf("expected wrapped connection error, got %v", stream.Err())
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 connection attempts, got %d", attempts)
+	}
+}
+
+func TestReconnectingStreamStopsOnContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	connect := func(ctx context.Context, lastEventID string) (*http.Response, error) {
+		cancel()
+		return nil, fmt.Errorf("connection refused")
+	}
+	stream := ssestream.NewReconnectingStream[payload](ctx, connect, ssestream.ReconnectOptions{
+		InitialDelay: time.Hour,
+	})
+	if stream.Next() {
+		t.Fatal("expected stream to end")
+	}
+	if stream.Err() != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", stream.Err())
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Decoder interface {
@@ -44,14 +46,22 @@ func RegisterDecoder(contentType string, decoder func(io.ReadCloser) Decoder) {
 type Event struct {
 	Type string
 	Data []byte
+	// ID is the last event ID seen on the stream when this event was dispatched.
+	// Per the event-stream spec it carries over from earlier events that set it.
+	ID string
+	// Retry is the most recent reconnection time requested by the server with a
+	// "retry:" field, or zero if the server has not sent one.
+	Retry time.Duration
 }
 
 // A base implementation of a Decoder for text/event-stream.
 type eventStreamDecoder struct {
-	evt Event
-	rc  io.ReadCloser
-	scn *bufio.Scanner
-	err error
+	evt    Event
+	rc     io.ReadCloser
+	scn    *bufio.Scanner
+	err    error
+	lastID string
+	retry  time.Duration
 }
 
 func (s *eventStreamDecoder) Next() bool {
@@ -65,11 +75,17 @@ func (s *eventStreamDecoder) Next() bool {
 	for s.scn.Scan() {
 		txt := s.scn.Bytes()
 
-		// Dispatch event on an empty line
+		// Dispatch event on an empty line. Blocks that only carried comments,
+		// "id:" or "retry:" fields have nothing to dispatch.
 		if len(txt) == 0 {
+			if event == "" && data.Len() == 0 {
+				continue
+			}
 			s.evt = Event{
-				Type: event,
-				Data: data.Bytes(),
+				Type:  event,
+				Data:  data.Bytes(),
+				ID:    s.lastID,
+				Retry: s.retry,
 			}
 			return true
 		}
@@ -88,6 +104,15 @@ func (s *eventStreamDecoder) Next() bool {
 			continue
 		case "event":
 			event = string(value)
+		case "id":
+			// IDs containing NULL are ignored, as required by the spec.
+			if bytes.IndexByte(value, 0) == -1 {
+				s.lastID = string(value)
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(string(value), 10, 63); err == nil {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
 		case "data":
 			_, s.err = data.Write(value)
 			if s.err != nil {
@@ -0,0 +1,289 @@
+package sgptcoder
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PromptBuilder composes the parts of a prompt. Text is collected into a
+// single text part; files, symbols and agents are mentioned in that text, the
+// way the TUI shows them, and sent as parts of their own.
+//
+// Errors, such as a file that cannot be read, are collected and returned by
+// [PromptBuilder.Parts] and [PromptBuilder.Params], so calls can be chained:
+//
+//	params, err := sgptcoder.NewPromptBuilder().
+//		Text("Explain").
+//		Lines("internal/app/app.go", 10, 40).
+//		Text("and ask").
+//		Agent("general").
+//		Params()
+type PromptBuilder struct {
+	text   strings.Builder
+	length int
+	parts  []SessionPromptParamsPartUnion
+	errs   []error
+}
+
+// NewPromptBuilder returns an empty prompt builder.
+func NewPromptBuilder() *PromptBuilder {
+	return &PromptBuilder{}
+}
+
+// Text appends text to the prompt. Text and mentions are separated by a
+// space unless one is already there.
+func (b *PromptBuilder) Text(text string) *PromptBuilder {
+	b.appendText(text)
+	return b
+}
+
+// File attaches the contents of a local file, encoded as a data URL. The MIME
+// type is detected from the file name and contents; text files are sent as
+// text/plain, which the server adds to the conversation as text.
+func (b *PromptBuilder) File(path string) *PromptBuilder {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("sgptcoder: attach %s: %w", path, err))
+		return b
+	}
+	return b.Data(filepath.Base(path), DetectMIME(path, data), data)
+}
+
+// Data attaches in-memory contents under a file name, encoded as a data URL.
+// An empty MIME type is detected from the name and contents.
+func (b *PromptBuilder) Data(filename string, mimeType string, data []byte) *PromptBuilder {
+	if mimeType == "" {
+		mimeType = DetectMIME(filename, data)
+	}
+	start, end := b.mention("@" + filename)
+	b.parts = append(b.parts, FilePartInputParam{
+		Type:     F(FilePartInputTypeFile),
+		Mime:     F(mimeType),
+		URL:      F(DataURL(mimeType, data)),
+		Filename: F(filename),
+		Source: F[FilePartSourceUnionParam](FileSourceParam{
+			Type: F(FileSourceTypeFile),
+			Path: F(filename),
+			Text: F(sourceText("@"+filename, start, end)),
+		}),
+	})
+	return b
+}
+
+// Path refers to a file or directory that the server reads itself, so it
+// must be a path on the server's machine. Relative paths are made absolute
+// against the working directory of this process.
+func (b *PromptBuilder) Path(path string) *PromptBuilder {
+	return b.reference(path, "@"+path, "")
+}
+
+// Lines refers to lines start through end, counted from one, of a file that
+// the server reads itself, like [PromptBuilder.Path].
+func (b *PromptBuilder) Lines(path string, start, end int) *PromptBuilder {
+	if start < 1 || end < start {
+		b.errs = append(b.errs, fmt.Errorf("sgptcoder: invalid line range %d-%d of %s", start, end, path))
+		return b
+	}
+	query := url.Values{"start": {fmt.Sprint(start)}, "end": {fmt.Sprint(end)}}
+	return b.reference(path, fmt.Sprintf("@%s:%d-%d", path, start, end), query.Encode())
+}
+
+// Symbol refers to a symbol found with [FindService.Symbols].
+func (b *PromptBuilder) Symbol(symbol Symbol) *PromptBuilder {
+	names := strings.Split(symbol.Name, ".")
+	name := names[len(names)-1]
+	if name == "" || symbol.Location.Uri == "" {
+		b.errs = append(b.errs, fmt.Errorf("sgptcoder: incomplete symbol %q", symbol.Name))
+		return b
+	}
+	r := symbol.Location.Range
+	display := "@" + name
+	start, end := b.mention(display)
+	b.parts = append(b.parts, FilePartInputParam{
+		Type:     F(FilePartInputTypeFile),
+		Mime:     F("text/plain"),
+		URL:      F(fmt.Sprintf("%s?start=%d&end=%d", symbol.Location.Uri, int(r.Start.Line), int(r.End.Line))),
+		Filename: F(name),
+		Source: F[FilePartSourceUnionParam](SymbolSourceParam{
+			Type: F(SymbolSourceTypeSymbol),
+			Path: F(symbol.Location.Uri),
+			Name: F(symbol.Name),
+			Kind: F(int64(symbol.Kind)),
+			Range: F(SymbolSourceRangeParam{
+				Start: F(SymbolSourceRangeStartParam{
+					Line:      F(r.Start.Line),
+					Character: F(r.Start.Character),
+				}),
+				End: F(SymbolSourceRangeEndParam{
+					Line:      F(r.End.Line),
+					Character: F(r.End.Character),
+				}),
+			}),
+			Text: F(sourceText(display, start, end)),
+		}),
+	})
+	return b
+}
+
+// Agent mentions an agent, which the server asks to handle the prompt as a
+// subtask.
+func (b *PromptBuilder) Agent(name string) *PromptBuilder {
+	name = strings.TrimPrefix(name, "@")
+	if name == "" || strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		b.errs = append(b.errs, fmt.Errorf("sgptcoder: invalid agent name %q", name))
+		return b
+	}
+	display := "@" + name
+	start, end := b.mention(display)
+	b.parts = append(b.parts, AgentPartInputParam{
+		Type: F(AgentPartInputTypeAgent),
+		Name: F(name),
+		Source: F(AgentPartInputSourceParam{
+			Value: F(display),
+			Start: F(int64(start)),
+			End:   F(int64(end)),
+		}),
+	})
+	return b
+}
+
+// Parts returns the parts of the prompt, starting with its text, or the
+// errors collected while building it.
+func (b *PromptBuilder) Parts() ([]SessionPromptParamsPartUnion, error) {
+	if len(b.errs) > 0 {
+		return nil, errors.Join(b.errs...)
+	}
+	parts := make([]SessionPromptParamsPartUnion, 0, len(b.parts)+1)
+	if strings.TrimSpace(b.text.String()) != "" {
+		parts = append(parts, TextPartInputParam{
+			Type: F(TextPartInputTypeText),
+			Text: F(b.text.String()),
+		})
+	}
+	parts = append(parts, b.parts...)
+	if len(parts) == 0 {
+		return nil, errors.New("sgptcoder: empty prompt")
+	}
+	return parts, nil
+}
+
+// Params returns [SessionPromptParams] with the parts of the prompt. Other
+// fields, such as the model, can be set on the result.
+func (b *PromptBuilder) Params() (SessionPromptParams, error) {
+	parts, err := b.Parts()
+	if err != nil {
+		return SessionPromptParams{}, err
+	}
+	return SessionPromptParams{Parts: F(parts)}, nil
+}
+
+func (b *PromptBuilder) reference(path string, display string, query string) *PromptBuilder {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("sgptcoder: refer to %s: %w", path, err))
+		return b
+	}
+	mimeType := DetectMIME(path, nil)
+	if info, err := os.Stat(absolute); err == nil && info.IsDir() {
+		if query != "" {
+			b.errs = append(b.errs, fmt.Errorf("sgptcoder: line range of directory %s", path))
+			return b
+		}
+		mimeType = "application/x-directory"
+	}
+	fileURL := url.URL{Scheme: "file", Path: filepath.ToSlash(absolute), RawQuery: query}
+	start, end := b.mention(display)
+	b.parts = append(b.parts, FilePartInputParam{
+		Type:     F(FilePartInputTypeFile),
+		Mime:     F(mimeType),
+		URL:      F(fileURL.String()),
+		Filename: F(path),
+		Source: F[FilePartSourceUnionParam](FileSourceParam{
+			Type: F(FileSourceTypeFile),
+			Path: F(absolute),
+			Text: F(sourceText(display, start, end)),
+		}),
+	})
+	return b
+}
+
+// appendText adds text to the prompt, keeping count of its length in
+// characters, which is what part sources refer to.
+func (b *PromptBuilder) appendText(text string) {
+	if text == "" {
+		return
+	}
+	if b.length > 0 && !endsWithSpace(b.text.String()) && !startsWithSpace(text) {
+		b.text.WriteByte(' ')
+		b.length++
+	}
+	b.text.WriteString(text)
+	b.length += utf8.RuneCountInString(text)
+}
+
+// mention adds the text that stands for a part and returns where it starts
+// and ends.
+func (b *PromptBuilder) mention(display string) (start int, end int) {
+	b.appendText(display)
+	return b.length - utf8.RuneCountInString(display), b.length
+}
+
+func sourceText(display string, start int, end int) FilePartSourceTextParam {
+	return FilePartSourceTextParam{
+		Value: F(display),
+		Start: F(int64(start)),
+		End:   F(int64(end)),
+	}
+}
+
+func startsWithSpace(text string) bool {
+	r, _ := utf8.DecodeRuneInString(text)
+	return unicode.IsSpace(r) || strings.ContainsRune(",.;:!?)", r)
+}
+
+func endsWithSpace(text string) bool {
+	r, _ := utf8.DecodeLastRuneInString(text)
+	return unicode.IsSpace(r) || r == '('
+}
+
+// DetectMIME returns the MIME type of a file from its name and, if given, its
+// contents. Text of any kind is reported as text/plain, the type the server
+// reads as text.
+func DetectMIME(filename string, data []byte) string {
+	detected, _, _ := mime.ParseMediaType(mime.TypeByExtension(strings.ToLower(filepath.Ext(filename))))
+	if detected == "" && data != nil {
+		detected, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	switch {
+	case strings.HasPrefix(detected, "text/"), isTextMIME(detected):
+		return "text/plain"
+	case detected != "" && detected != "application/octet-stream":
+		return detected
+	case data == nil || (utf8.Valid(data) && !strings.ContainsRune(string(data), 0)):
+		// Source files without a registered type, such as main.go
+		return "text/plain"
+	}
+	return "application/octet-stream"
+}
+
+func isTextMIME(mimeType string) bool {
+	switch mimeType {
+	case "application/json", "application/javascript", "application/xml", "application/x-sh", "application/toml", "application/yaml", "application/x-yaml":
+		return true
+	}
+	return strings.HasSuffix(mimeType, "+json") || (strings.HasSuffix(mimeType, "+xml") && mimeType != "image/svg+xml")
+}
+
+// DataURL encodes data as a base64 data URL of the given MIME type.
+func DataURL(mimeType string, data []byte) string {
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
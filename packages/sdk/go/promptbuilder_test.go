@@ -0,0 +1,141 @@
+package sgptcoder_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+)
+
+func TestPromptBuilder(t *testing.T) {
+	dir := t.TempDir()
+	image := filepath.Join(dir, "shot.png")
+	if err := os.WriteFile(image, []byte("\x89PNG\r\n\x1a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var symbol sgptcoder.Symbol
+	if err := json.Unmarshal([]byte(`{"name":"app.Run","kind":12,"location":{"uri":"file:///src/app.go","range":{"start":{"line":3,"character":0},"end":{"line":9,"character":1}}}}`), &symbol); err != nil {
+		t.Fatal(err)
+	}
+	params, err := sgptcoder.NewPromptBuilder().
+		Text("Compare").
+		Symbol(symbol).
+		Text("with").
+		Lines(filepath.Join(dir, "main.go"), 10, 20).
+		File(image).
+		Path(dir).
+		Text(", then ask").
+		Agent("@general").
+		Params()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var body struct {
+		Parts []struct {
+			Type   string `json:"type"`
+			Text   string `json:"text"`
+			Mime   string `json:"mime"`
+			URL    string `json:"url"`
+			Name   string `json:"name"`
+			Source struct {
+				Type  string `json:"type"`
+				Value string `json:"value"`
+				Start int    `json:"start"`
+				End   int    `json:"end"`
+				Text  struct {
+					Value string `json:"value"`
+					Start int    `json:"start"`
+					End   int    `json:"end"`
+				} `json:"text"`
+			} `json:"source"`
+		} `json:"parts"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(body.Parts) != 6 {
+		t.Fatalf("expected 6 parts, got %s", data)
+	}
+
+	text := body.Parts[0].Text
+	lines := "@" + filepath.Join(dir, "main.go") + ":10-20"
+	expected := "Compare @Run with " + lines + " @shot.png @" + dir + ", then ask @general"
+	if body.Parts[0].Type != "text" || text != expected {
+		t.Fatalf("expected %q, got %q", expected, text)
+	}
+	// Every mention points at its place in the text
+	for _, part := range body.Parts[1:5] {
+		source := part.Source.Text
+		if got := text[source.Start:source.End]; got != source.Value {
+			t.Errorf("expected %q at %d-%d, got %q", source.Value, source.Start, source.End, got)
+		}
+	}
+	agent := body.Parts[5]
+	if agent.Type != "agent" || agent.Name != "general" || text[agent.Source.Start:agent.Source.End] != "@general" {
+		t.Errorf("expected an agent mention, got %+v", agent)
+	}
+
+	if part := body.Parts[1]; part.Source.Type != "symbol" || part.URL != "file:///src/app.go?start=3&end=9" {
+		t.Errorf("expected a symbol reference, got %+v", part)
+	}
+	if part := body.Parts[2]; part.Mime != "text/plain" || !strings.HasSuffix(part.URL, "/main.go?end=20&start=10") {
+		t.Errorf("expected a line range reference, got %+v", part)
+	}
+	if part := body.Parts[3]; part.Mime != "image/png" || part.URL != "data:image/png;base64,iVBORw0KGgo=" {
+		t.Errorf("expected an embedded image, got %+v", part)
+	}
+	if part := body.Parts[4]; part.Mime != "application/x-directory" {
+		t.Errorf("expected a directory reference, got %+v", part)
+	}
+}
+
+func TestPromptBuilderErrors(t *testing.T) {
+	_, err := sgptcoder.NewPromptBuilder().
+		Text("hi").
+		File(filepath.Join(t.TempDir(), "missing.txt")).
+		Lines("main.go", 5, 2).
+		Agent("two words").
+		Params()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, text := range []string{"missing.txt", "invalid line range 5-2", `invalid agent name "two words"`} {
+		if !strings.Contains(err.Error(), text) {
+			t.Errorf("expected %q in %v", text, err)
+		}
+	}
+
+	if _, err := sgptcoder.NewPromptBuilder().Text("  ").Parts(); err == nil {
+		t.Error("expected an empty prompt to be rejected")
+	}
+}
+
+func TestDetectMIME(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"main.go", []byte("package main\n"), "text/plain"},
+		{"notes.md", nil, "text/plain"},
+		{"config.json", nil, "text/plain"},
+		{"photo.JPG", nil, "image/jpeg"},
+		{"paper.pdf", nil, "application/pdf"},
+		{"unknown", []byte("\x89PNG\r\n\x1a\n"), "image/png"},
+		{"blob", []byte{0, 1, 2, 0xff}, "application/octet-stream"},
+	}
+	for _, c := range cases {
+		if got := sgptcoder.DetectMIME(c.name, c.data); got != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.name, c.expected, got)
+		}
+	}
+}
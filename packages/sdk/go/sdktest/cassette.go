@@ -0,0 +1,370 @@
+package sdktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// CassetteVersion is the version of the cassette file layout.
+const CassetteVersion = 1
+
+// Redacted replaces scrubbed secrets in cassettes.
+const Redacted = "[REDACTED]"
+
+// Cassette is a list of recorded HTTP interactions, stored as JSON.
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and the response it got.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request in a [Cassette].
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response in a [Cassette]. The body of an event
+// stream holds the events the client read before closing it.
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// LoadCassette reads a cassette file.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("sdktest: invalid cassette %s: %w", path, err)
+	}
+	if cassette.Version < 1 || cassette.Version > CassetteVersion {
+		return nil, fmt.Errorf("sdktest: unsupported cassette version %d in %s", cassette.Version, path)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette file, creating its directory if needed.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Match selects the parts of a request compared when replaying.
+type Match int
+
+const (
+	MatchMethod Match = 1 << iota
+	MatchPath
+	MatchQuery
+	// MatchBody compares JSON bodies by value, ignoring key order and
+	// whitespace, and other bodies byte for byte.
+	MatchBody
+
+	MatchAll = MatchMethod | MatchPath | MatchQuery | MatchBody
+)
+
+// CassetteOption configures a [Recorder] or [Replayer].
+type CassetteOption func(*cassetteOptions)
+
+type cassetteOptions struct {
+	match        Match
+	ignoreFields map[string]bool
+	headers      map[string]bool
+	fields       map[string]bool
+	patterns     []*regexp.Regexp
+	secrets      []string
+}
+
+// MatchOn sets the parts of a request that must equal the recorded one for
+// it to be replayed. The default is [MatchAll].
+func MatchOn(match Match) CassetteOption {
+	return func(o *cassetteOptions) {
+		o.match = match
+	}
+}
+
+// IgnoreBodyFields leaves JSON object fields with the given names out of body
+// matching, such as IDs generated by the client.
+func IgnoreBodyFields(names ...string) CassetteOption {
+	return func(o *cassetteOptions) {
+		for _, name := range names {
+			o.ignoreFields[name] = true
+		}
+	}
+}
+
+// ScrubHeaders redacts more request and response headers. Authorization,
+// Proxy-Authorization, Cookie, Set-Cookie and X-Api-Key are always redacted.
+func ScrubHeaders(names ...string) CassetteOption {
+	return func(o *cassetteOptions) {
+		for _, name := range names {
+			o.headers[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+}
+
+// ScrubFields redacts more JSON object fields, matched case-insensitively in
+// bodies and event stream data. Fields named apiKey, api_key, token,
+// accessToken, refreshToken, secret and password are always redacted.
+func ScrubFields(names ...string) CassetteOption {
+	return func(o *cassetteOptions) {
+		for _, name := range names {
+			o.fields[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// ScrubPatterns redacts text matching the patterns anywhere in recorded
+// bodies and header values.
+func ScrubPatterns(patterns ...*regexp.Regexp) CassetteOption {
+	return func(o *cassetteOptions) {
+		o.patterns = append(o.patterns, patterns...)
+	}
+}
+
+// ScrubSecrets redacts the literal values, such as keys read from the
+// environment, anywhere in recorded bodies and header values.
+func ScrubSecrets(secrets ...string) CassetteOption {
+	return func(o *cassetteOptions) {
+		for _, secret := range secrets {
+			if secret != "" {
+				o.secrets = append(o.secrets, secret)
+			}
+		}
+	}
+}
+
+// providerKeyPattern matches the usual shapes of provider API keys.
+var providerKeyPattern = regexp.MustCompile(`\b(?:sk-(?:ant-)?[A-Za-z0-9_\-]{16,}|AIza[0-9A-Za-z_\-]{35}|gsk_[A-Za-z0-9]{20,}|xai-[A-Za-z0-9]{20,})`)
+
+func newCassetteOptions(opts []CassetteOption) *cassetteOptions {
+	o := &cassetteOptions{
+		match:        MatchAll,
+		ignoreFields: map[string]bool{},
+		headers: map[string]bool{
+			"Authorization":       true,
+			"Proxy-Authorization": true,
+			"Cookie":              true,
+			"Set-Cookie":          true,
+			"X-Api-Key":           true,
+		},
+		fields: map[string]bool{
+			"apikey":       true,
+			"api_key":      true,
+			"token":        true,
+			"accesstoken":  true,
+			"refreshtoken": true,
+			"secret":       true,
+			"password":     true,
+		},
+		patterns: []*regexp.Regexp{providerKeyPattern},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// scrub redacts secrets from an interaction in place.
+func (o *cassetteOptions) scrub(interaction *Interaction) {
+	o.scrubRequest(&interaction.Request)
+	interaction.Response.Header = o.scrubHeader(interaction.Response.Header)
+	interaction.Response.Body = o.scrubBody(interaction.Response.Body)
+}
+
+func (o *cassetteOptions) scrubRequest(request *RecordedRequest) {
+	request.Header = o.scrubHeader(request.Header)
+	request.Query = o.scrubText(request.Query)
+	request.Body = o.scrubBody(request.Body)
+}
+
+func (o *cassetteOptions) scrubHeader(header http.Header) http.Header {
+	if header == nil {
+		return nil
+	}
+	scrubbed := http.Header{}
+	for name, values := range header {
+		for _, value := range values {
+			if o.headers[http.CanonicalHeaderKey(name)] {
+				value = Redacted
+			}
+			scrubbed.Add(name, o.scrubText(value))
+		}
+	}
+	return scrubbed
+}
+
+// scrubBody redacts fields of a JSON body, or of each data line of an event
+// stream, then secrets in the remaining text.
+func (o *cassetteOptions) scrubBody(body string) string {
+	if body == "" {
+		return body
+	}
+	if scrubbed, ok := o.scrubJSON(body); ok {
+		return o.scrubText(scrubbed)
+	}
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		if data, ok := strings.CutPrefix(line, "data:"); ok {
+			if scrubbed, ok := o.scrubJSON(strings.TrimSpace(data)); ok {
+				lines[i] = "data: " + scrubbed
+			}
+		}
+	}
+	return o.scrubText(strings.Join(lines, "\n"))
+}
+
+func (o *cassetteOptions) scrubJSON(text string) (string, bool) {
+	var value any
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return "", false
+	}
+	if !o.scrubValue(value) {
+		// Keep untouched bodies byte for byte
+		return text, true
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// scrubValue reports whether it redacted anything.
+func (o *cassetteOptions) scrubValue(value any) bool {
+	changed := false
+	switch value := value.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if _, isString := field.(string); isString && o.fields[strings.ToLower(key)] {
+				value[key] = Redacted
+				changed = true
+				continue
+			}
+			changed = o.scrubValue(field) || changed
+		}
+	case []any:
+		for _, item := range value {
+			changed = o.scrubValue(item) || changed
+		}
+	}
+	return changed
+}
+
+func (o *cassetteOptions) scrubText(text string) string {
+	for _, secret := range o.secrets {
+		text = strings.ReplaceAll(text, secret, Redacted)
+	}
+	for _, pattern := range o.patterns {
+		text = pattern.ReplaceAllString(text, Redacted)
+	}
+	return text
+}
+
+// matches reports whether a scrubbed request equals a recorded one.
+func (o *cassetteOptions) matches(request, recorded RecordedRequest) bool {
+	if o.match&MatchMethod != 0 && request.Method != recorded.Method {
+		return false
+	}
+	if o.match&MatchPath != 0 && request.Path != recorded.Path {
+		return false
+	}
+	if o.match&MatchQuery != 0 && canonicalQuery(request.Query) != canonicalQuery(recorded.Query) {
+		return false
+	}
+	if o.match&MatchBody != 0 && !o.sameBody(request.Body, recorded.Body) {
+		return false
+	}
+	return true
+}
+
+func canonicalQuery(query string) string {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return query
+	}
+	// Encode sorts by key
+	return values.Encode()
+}
+
+func (o *cassetteOptions) sameBody(a, b string) bool {
+	var valueA, valueB any
+	if json.Unmarshal([]byte(a), &valueA) != nil || json.Unmarshal([]byte(b), &valueB) != nil {
+		return a == b
+	}
+	o.dropIgnored(valueA)
+	o.dropIgnored(valueB)
+	return reflect.DeepEqual(valueA, valueB)
+}
+
+func (o *cassetteOptions) dropIgnored(value any) {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if o.ignoreFields[key] {
+				delete(value, key)
+				continue
+			}
+			o.dropIgnored(field)
+		}
+	case []any:
+		for _, item := range value {
+			o.dropIgnored(item)
+		}
+	}
+}
+
+// recordRequest reads the request body and puts it back so the request can
+// still be sent.
+func recordRequest(req *http.Request) (RecordedRequest, error) {
+	recorded := RecordedRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.RawQuery,
+		Header: req.Header.Clone(),
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return recorded, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return recorded, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	recorded.Body = string(body)
+	return recorded, nil
+}
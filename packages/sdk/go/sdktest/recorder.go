@@ -0,0 +1,213 @@
+package sdktest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+)
+
+// RecordEnv forces [UseCassette] to record, replacing existing cassettes,
+// when set to a true value such as "1".
+const RecordEnv = "SDKTEST_RECORD"
+
+// Recorder is a middleware that records every request and response that
+// passes through it. Secrets are scrubbed when the cassette is saved.
+type Recorder struct {
+	path string
+	opts *cassetteOptions
+
+	mu           sync.Mutex
+	interactions []*recording
+}
+
+type recording struct {
+	mu          sync.Mutex
+	interaction Interaction
+	body        bytes.Buffer
+}
+
+// NewRecorder returns a recorder that saves to the cassette file at path.
+func NewRecorder(path string, opts ...CassetteOption) *Recorder {
+	return &Recorder{path: path, opts: newCassetteOptions(opts)}
+}
+
+// Option returns the request option that installs the recorder.
+func (r *Recorder) Option() option.RequestOption {
+	return option.WithMiddleware(r.Middleware)
+}
+
+// Middleware records the request and wraps the response body so that what
+// the client reads of it, including event streams, is recorded too.
+func (r *Recorder) Middleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	request, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := next(req)
+	if err != nil || res == nil {
+		// Transport errors are not part of the recording
+		return res, err
+	}
+
+	rec := &recording{interaction: Interaction{
+		Request: request,
+		Response: RecordedResponse{
+			Status: res.StatusCode,
+			Header: res.Header.Clone(),
+		},
+	}}
+	r.mu.Lock()
+	r.interactions = append(r.interactions, rec)
+	r.mu.Unlock()
+	if res.Body != nil {
+		res.Body = &recordingBody{ReadCloser: res.Body, rec: rec}
+	}
+	return res, nil
+}
+
+// Cassette returns the scrubbed interactions recorded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cassette := &Cassette{Version: CassetteVersion, Interactions: []Interaction{}}
+	for _, rec := range r.interactions {
+		rec.mu.Lock()
+		interaction := rec.interaction
+		interaction.Response.Body = rec.body.String()
+		rec.mu.Unlock()
+		r.opts.scrub(&interaction)
+		cassette.Interactions = append(cassette.Interactions, interaction)
+	}
+	return cassette
+}
+
+// Save writes the recorded interactions to the cassette file.
+func (r *Recorder) Save() error {
+	return r.Cassette().Save(r.path)
+}
+
+type recordingBody struct {
+	io.ReadCloser
+	rec *recording
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.rec.mu.Lock()
+	b.rec.body.Write(p[:n])
+	b.rec.mu.Unlock()
+	return n, err
+}
+
+// Replayer is a middleware that answers requests from a cassette without
+// sending them. Each recorded interaction is used once, in recorded order
+// among the ones that match.
+type Replayer struct {
+	path string
+	opts *cassetteOptions
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayer loads the cassette file at path.
+func NewReplayer(path string, opts ...CassetteOption) (*Replayer, error) {
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Replayer{
+		path:         path,
+		opts:         newCassetteOptions(opts),
+		interactions: cassette.Interactions,
+		used:         make([]bool, len(cassette.Interactions)),
+	}, nil
+}
+
+// Option returns the request option that installs the replayer.
+func (r *Replayer) Option() option.RequestOption {
+	return option.WithMiddleware(r.Middleware)
+}
+
+// Middleware answers the request with the first unused recorded interaction
+// that matches it. It fails the request when none does.
+func (r *Replayer) Middleware(req *http.Request, _ option.MiddlewareNext) (*http.Response, error) {
+	request, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	r.opts.scrubRequest(&request)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.interactions {
+		if r.used[i] || !r.opts.matches(request, interaction.Request) {
+			continue
+		}
+		r.used[i] = true
+		header := interaction.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.Status, http.StatusText(interaction.Response.Status)),
+			StatusCode:    interaction.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("sdktest: no unused interaction in %s matches %s %s", r.path, req.Method, req.URL.RequestURI())
+}
+
+// Unused returns the recorded interactions that were not replayed.
+func (r *Replayer) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []Interaction
+	for i, interaction := range r.interactions {
+		if !r.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
+
+// UseCassette replays the cassette file at path, or records it when the file
+// does not exist yet or [RecordEnv] is set. Recordings are saved when the
+// test ends; requests then go to the client's base URL, so it must point at a
+// real server.
+func UseCassette(tb testing.TB, path string, opts ...CassetteOption) option.RequestOption {
+	tb.Helper()
+	record, _ := strconv.ParseBool(os.Getenv(RecordEnv))
+	if _, err := os.Stat(path); err != nil && os.IsNotExist(err) {
+		record = true
+	}
+	if record {
+		recorder := NewRecorder(path, opts...)
+		tb.Cleanup(func() {
+			if err := recorder.Save(); err != nil {
+				tb.Errorf("sdktest: saving cassette: %v", err)
+			}
+		})
+		return recorder.Option()
+	}
+	replayer, err := NewReplayer(path, opts...)
+	if err != nil {
+		tb.Fatalf("sdktest: %v", err)
+	}
+	return replayer.Option()
+}
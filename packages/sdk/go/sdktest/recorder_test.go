@@ -0,0 +1,154 @@
+package sdktest_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+	"github.com/skorpland/sgptcoder-sdk-go/sdktest"
+)
+
+const providerKey = "sk-ant-REDACTED"
+
+// converse creates a session, prompts it and reads the events of the reply.
+func converse(t *testing.T, client *sgptcoder.Client, prompt string) (string, []string) {
+	t.Helper()
+	ctx := context.Background()
+	config, err := client.Config.Get(ctx, sgptcoder.ConfigGetParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key := config.Provider["anthropic"].Options.APIKey; key != sdktest.Redacted && key != providerKey {
+		t.Errorf("unexpected api key %q", key)
+	}
+	session, err := client.Session.New(ctx, sgptcoder.SessionNewParams{Title: sgptcoder.F("recorded")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stream := client.Event.ListStreaming(ctx, sgptcoder.EventListParams{})
+	defer stream.Close()
+	stream.Next()
+	response, err := client.Session.Prompt(ctx, session.ID, textPrompt(prompt))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var events []string
+	for stream.Next() {
+		events = append(events, string(stream.Current().Type))
+		if stream.Current().Type == sgptcoder.EventListResponseTypeSessionIdle {
+			break
+		}
+	}
+	return response.Parts[1].AsUnion().(sgptcoder.TextPart).Text, events
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "prompt.json")
+
+	server := sdktest.NewServer(sdktest.WithConfig(`{"provider":{"anthropic":{"options":{"apiKey":"` + providerKey + `"}}}}`))
+	server.Enqueue(sdktest.Reply(sdktest.Text("Hello", " there")))
+	recorder := sdktest.NewRecorder(path)
+	recordedText, recordedEvents := converse(t, server.Client(option.WithHeader("Authorization", "Bearer "+providerKey), recorder.Option()), "hi")
+	server.Close()
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(data), providerKey) {
+		t.Error("expected the provider key to be scrubbed from the cassette")
+	}
+
+	// The server is gone, so everything comes from the cassette
+	replayer, err := sdktest.NewReplayer(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := sgptcoder.NewClient(option.WithBaseURL(server.URL), option.WithMaxRetries(0), replayer.Option())
+	replayedText, replayedEvents := converse(t, client, "hi")
+	if replayedText != recordedText || replayedText != "Hello there" {
+		t.Errorf("expected %q, got %q", recordedText, replayedText)
+	}
+	if strings.Join(replayedEvents, ",") != strings.Join(recordedEvents, ",") {
+		t.Errorf("expected events %v, got %v", recordedEvents, replayedEvents)
+	}
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Errorf("expected every interaction to be replayed, %d left", len(unused))
+	}
+
+	// A different prompt body does not match the recording
+	replayer, err = sdktest.NewReplayer(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client = sgptcoder.NewClient(option.WithBaseURL(server.URL), option.WithMaxRetries(0), replayer.Option())
+	if _, err := client.Session.Prompt(context.Background(), "ses_1", textPrompt("bye")); err == nil {
+		t.Error("expected an error for an unrecorded request")
+	}
+}
+
+func TestReplayMatching(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompt.json")
+	cassette := &sdktest.Cassette{
+		Version: sdktest.CassetteVersion,
+		Interactions: []sdktest.Interaction{{
+			Request: sdktest.RecordedRequest{
+				Method: "POST",
+				Path:   "/session/ses_1/message",
+				Query:  "directory=%2Ftmp",
+				Body:   `{"messageID":"msg_1","parts":[{"type":"text","text":"hi"}]}`,
+			},
+			Response: sdktest.RecordedResponse{
+				Status: 200,
+				Header: map[string][]string{"Content-Type": {"application/json"}},
+				Body:   `{"info":{"id":"msg_2","role":"assistant"},"parts":[]}`,
+			},
+		}},
+	}
+	if err := cassette.Save(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	prompt := textPrompt("hi")
+	prompt.MessageID = sgptcoder.F("msg_other")
+	prompt.Directory = sgptcoder.F("/tmp")
+
+	replayer, err := sdktest.NewReplayer(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := sgptcoder.NewClient(option.WithBaseURL("http://sdktest.invalid"), option.WithMaxRetries(0), replayer.Option())
+	if _, err := client.Session.Prompt(context.Background(), "ses_1", prompt); err == nil {
+		t.Error("expected a different message ID not to match")
+	}
+
+	replayer, err = sdktest.NewReplayer(path, sdktest.IgnoreBodyFields("messageID"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client = sgptcoder.NewClient(option.WithBaseURL("http://sdktest.invalid"), option.WithMaxRetries(0), replayer.Option())
+	response, err := client.Session.Prompt(context.Background(), "ses_1", prompt)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Info.ID != "msg_2" {
+		t.Errorf("expected the recorded reply, got %+v", response.Info)
+	}
+
+	replayer, err = sdktest.NewReplayer(path, sdktest.MatchOn(sdktest.MatchMethod|sdktest.MatchPath))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client = sgptcoder.NewClient(option.WithBaseURL("http://sdktest.invalid"), option.WithMaxRetries(0), replayer.Option())
+	if _, err := client.Session.Prompt(context.Background(), "ses_1", textPrompt("anything")); err != nil {
+		t.Errorf("expected method and path matching to ignore the body, got %v", err)
+	}
+}
@@ -0,0 +1,465 @@
+package sdktest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Response scripts the assistant reply to one prompt.
+type Response struct {
+	steps []Step
+}
+
+// Reply returns a response that runs the steps in order. Every reply starts
+// with a step-start part and ends with a step-finish part carrying the
+// [Usage], as replies of a real server do.
+func Reply(steps ...Step) Response {
+	return Response{steps: steps}
+}
+
+// Step is one part of a scripted reply, such as [Text] or [Tool].
+type Step interface {
+	// run reports false when the reply must stop after this step.
+	run(t *turn) bool
+}
+
+// Text streams a text part. Each chunk is appended to the part and published
+// as its own message.part.updated event.
+func Text(chunks ...string) Step {
+	return textStep{partType: "text", chunks: chunks}
+}
+
+// Reasoning streams a reasoning part like [Text] does a text part.
+func Reasoning(chunks ...string) Step {
+	return textStep{partType: "reasoning", chunks: chunks}
+}
+
+// Usage adds tokens and cost to the reply's step-finish part and to the
+// assistant message.
+func Usage(input, output int, cost float64) Step {
+	return usageStep{input: input, output: output, cost: cost}
+}
+
+// Error ends the reply with an assistant error, such as "ProviderAuthError",
+// "UnknownError" or "MessageOutputLengthError", and publishes session.error.
+func Error(name, message string) Step {
+	return errorStep{name: name, message: message}
+}
+
+// Wait pauses the reply until the channel is closed or the session is
+// aborted, which lets tests act while a reply is in progress.
+func Wait(ch <-chan struct{}) Step {
+	return waitStep{ch: ch}
+}
+
+// ToolStep is a tool call. It moves from pending to running and, unless the
+// permission is rejected, to completed or error.
+type ToolStep struct {
+	tool     string
+	input    map[string]any
+	title    string
+	output   string
+	metadata map[string]any
+	err      string
+
+	ask         bool
+	askTitle    string
+	askMetadata map[string]any
+}
+
+// Tool starts a tool call with the given input. By default it completes with
+// an empty output.
+func Tool(tool string, input map[string]any) *ToolStep {
+	if input == nil {
+		input = map[string]any{}
+	}
+	return &ToolStep{tool: tool, input: input, metadata: map[string]any{}}
+}
+
+// Title sets the title of the finished tool call.
+func (s *ToolStep) Title(title string) *ToolStep {
+	s.title = title
+	return s
+}
+
+// Output completes the tool call with the output.
+func (s *ToolStep) Output(output string) *ToolStep {
+	s.output = output
+	s.err = ""
+	return s
+}
+
+// Metadata sets the metadata of the tool call, such as the diff of an edit.
+func (s *ToolStep) Metadata(metadata map[string]any) *ToolStep {
+	s.metadata = metadata
+	return s
+}
+
+// Fail ends the tool call with an error.
+func (s *ToolStep) Fail(err string) *ToolStep {
+	s.err = err
+	return s
+}
+
+// Ask makes the tool call wait for a permission of the tool's type while it is
+// running. Metadata defaults to the tool input. A rejection fails the call and
+// ends the reply; "always" skips later requests of the type in the session.
+func (s *ToolStep) Ask(title string, metadata map[string]any) *ToolStep {
+	s.ask = true
+	s.askTitle = title
+	s.askMetadata = metadata
+	return s
+}
+
+// RejectedError is the tool error when a permission is rejected.
+const RejectedError = "The user rejected permission to use this specific tool call. You may try again with different parameters."
+
+// turn is a reply in progress.
+type turn struct {
+	s       *Server
+	ctx     context.Context
+	session *session
+	message *message
+	input   int
+	output  int
+	cost    float64
+}
+
+// addPart must be called with s.mu held.
+func (t *turn) addPart(part map[string]any) map[string]any {
+	part["id"] = t.s.id("prt")
+	part["messageID"] = t.message.info["id"]
+	part["sessionID"] = t.message.info["sessionID"]
+	t.message.parts = append(t.message.parts, part)
+	t.s.publish("message.part.updated", map[string]any{"part": part})
+	return part
+}
+
+// updatePart must be called with s.mu held.
+func (t *turn) updatePart(part map[string]any) {
+	t.s.publish("message.part.updated", map[string]any{"part": part})
+}
+
+// fail must be called with s.mu held.
+func (t *turn) fail(name, message string) {
+	err := map[string]any{"name": name, "data": map[string]any{"message": message}}
+	if name == "ProviderAuthError" {
+		err["data"].(map[string]any)["providerID"] = t.message.info["providerID"]
+	}
+	t.message.info["error"] = err
+	t.s.publish("message.updated", map[string]any{"info": t.message.info})
+	t.s.publish("session.error", map[string]any{"sessionID": t.message.info["sessionID"], "error": err})
+}
+
+type textStep struct {
+	partType string
+	chunks   []string
+}
+
+func (step textStep) run(t *turn) bool {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	part := t.addPart(map[string]any{
+		"type": step.partType,
+		"text": "",
+		"time": map[string]any{"start": now()},
+	})
+	var text strings.Builder
+	for _, chunk := range step.chunks {
+		text.WriteString(chunk)
+		part["text"] = text.String()
+		t.updatePart(part)
+	}
+	part["time"].(map[string]any)["end"] = now()
+	t.updatePart(part)
+	return true
+}
+
+type usageStep struct {
+	input  int
+	output int
+	cost   float64
+}
+
+func (step usageStep) run(t *turn) bool {
+	t.input += step.input
+	t.output += step.output
+	t.cost += step.cost
+	return true
+}
+
+type errorStep struct {
+	name    string
+	message string
+}
+
+func (step errorStep) run(t *turn) bool {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	t.fail(step.name, step.message)
+	return false
+}
+
+type waitStep struct {
+	ch <-chan struct{}
+}
+
+func (step waitStep) run(t *turn) bool {
+	select {
+	case <-step.ch:
+		return true
+	case <-t.ctx.Done():
+		return false
+	}
+}
+
+func (step *ToolStep) run(t *turn) bool {
+	t.s.mu.Lock()
+	part := t.addPart(map[string]any{
+		"type":   "tool",
+		"tool":   step.tool,
+		"callID": t.s.id("call"),
+		"state":  map[string]any{"status": "pending"},
+	})
+	start := now()
+	part["state"] = map[string]any{
+		"status": "running",
+		"input":  step.input,
+		"time":   map[string]any{"start": start},
+	}
+	t.updatePart(part)
+	t.s.mu.Unlock()
+
+	if step.ask {
+		response, ok := t.askPermission(step, part)
+		if !ok {
+			// Aborted; the reply records the abort.
+			t.s.mu.Lock()
+			part["state"] = map[string]any{
+				"status": "error",
+				"input":  step.input,
+				"error":  "Tool execution aborted",
+				"time":   map[string]any{"start": start, "end": now()},
+			}
+			t.updatePart(part)
+			t.s.mu.Unlock()
+			return false
+		}
+		if response == "reject" {
+			t.s.mu.Lock()
+			part["state"] = map[string]any{
+				"status": "error",
+				"input":  step.input,
+				"error":  RejectedError,
+				"time":   map[string]any{"start": start, "end": now()},
+			}
+			t.updatePart(part)
+			t.s.mu.Unlock()
+			return false
+		}
+	}
+
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	if step.err != "" {
+		part["state"] = map[string]any{
+			"status":   "error",
+			"input":    step.input,
+			"error":    step.err,
+			"metadata": step.metadata,
+			"time":     map[string]any{"start": start, "end": now()},
+		}
+	} else {
+		part["state"] = map[string]any{
+			"status":   "completed",
+			"input":    step.input,
+			"output":   step.output,
+			"title":    step.title,
+			"metadata": step.metadata,
+			"time":     map[string]any{"start": start, "end": now()},
+		}
+	}
+	t.updatePart(part)
+	return true
+}
+
+// askPermission publishes a permission request for the tool call and waits
+// for the answer. It reports false if the session is aborted first.
+func (t *turn) askPermission(step *ToolStep, part map[string]any) (string, bool) {
+	t.s.mu.Lock()
+	if t.session.always[step.tool] {
+		t.s.mu.Unlock()
+		return "always", true
+	}
+	metadata := step.askMetadata
+	if metadata == nil {
+		metadata = step.input
+	}
+	pending := &permission{
+		info: map[string]any{
+			"id":        t.s.id("per"),
+			"type":      step.tool,
+			"sessionID": t.message.info["sessionID"],
+			"messageID": t.message.info["id"],
+			"callID":    part["callID"],
+			"title":     step.askTitle,
+			"metadata":  metadata,
+			"time":      map[string]any{"created": now()},
+		},
+		reply: make(chan string, 1),
+	}
+	id := pending.info["id"].(string)
+	t.s.permissions[id] = pending
+	t.s.publish("permission.updated", pending.info)
+	t.s.mu.Unlock()
+
+	var response string
+	select {
+	case response = <-pending.reply:
+	case <-t.ctx.Done():
+		t.s.mu.Lock()
+		delete(t.s.permissions, id)
+		t.s.mu.Unlock()
+		return "", false
+	}
+
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	if response == "always" {
+		t.session.always[step.tool] = true
+	}
+	t.s.publish("permission.replied", map[string]any{
+		"sessionID":    t.message.info["sessionID"],
+		"permissionID": id,
+		"response":     response,
+	})
+	return response, true
+}
+
+// prompt stores the prompt, runs the scripted reply and answers with the
+// assistant message once the reply is done, as the real endpoint does.
+func (s *Server) prompt(w http.ResponseWriter, r *http.Request, session *session, body promptBody) {
+	s.mu.Lock()
+	if session.cancel != nil {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, "BusyError", "sdktest: session "+session.info["id"].(string)+" is busy")
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	session.cancel = cancel
+	sessionID := session.info["id"].(string)
+
+	prompt := Prompt{
+		SessionID:  sessionID,
+		MessageID:  body.MessageID,
+		Agent:      body.Agent,
+		ProviderID: body.Model.ProviderID,
+		ModelID:    body.Model.ModelID,
+		Parts:      body.Parts,
+	}
+	if prompt.MessageID == "" {
+		prompt.MessageID = s.id("msg")
+	}
+	if prompt.Agent == "" {
+		prompt.Agent = Agent
+	}
+	if prompt.ProviderID == "" {
+		prompt.ProviderID = ProviderID
+		prompt.ModelID = ModelID
+	}
+
+	user := &message{info: map[string]any{
+		"id":        prompt.MessageID,
+		"sessionID": sessionID,
+		"role":      "user",
+		"time":      map[string]any{"created": now()},
+	}}
+	session.messages = append(session.messages, user)
+	s.publish("message.updated", map[string]any{"info": user.info})
+	var text []string
+	for _, part := range body.Parts {
+		if _, ok := part["id"]; !ok {
+			part["id"] = s.id("prt")
+		}
+		part["messageID"] = user.info["id"]
+		part["sessionID"] = sessionID
+		user.parts = append(user.parts, part)
+		s.publish("message.part.updated", map[string]any{"part": part})
+		if part["type"] == "text" {
+			if value, ok := part["text"].(string); ok {
+				text = append(text, value)
+			}
+		}
+	}
+	prompt.Text = strings.Join(text, "\n")
+	s.prompts = append(s.prompts, prompt)
+
+	var response Response
+	switch {
+	case len(s.responses) > 0:
+		response = s.responses[0]
+		s.responses = s.responses[1:]
+	case s.handler != nil:
+		handler := s.handler
+		// The handler may call back into the server
+		s.mu.Unlock()
+		response = handler(prompt)
+		s.mu.Lock()
+	default:
+		response = Reply(Error("UnknownError", "sdktest: no response scripted for this prompt"))
+	}
+
+	assistant := &message{info: map[string]any{
+		"id":         s.id("msg"),
+		"sessionID":  sessionID,
+		"role":       "assistant",
+		"mode":       prompt.Agent,
+		"providerID": prompt.ProviderID,
+		"modelID":    prompt.ModelID,
+		"cost":       0,
+		"system":     []string{},
+		"path":       map[string]any{"cwd": Worktree, "root": Worktree},
+		"time":       map[string]any{"created": now()},
+		"tokens":     tokens(0, 0),
+	}}
+	session.messages = append(session.messages, assistant)
+	s.publish("message.updated", map[string]any{"info": assistant.info})
+	t := &turn{s: s, ctx: ctx, session: session, message: assistant}
+	t.addPart(map[string]any{"type": "step-start"})
+	s.mu.Unlock()
+
+	for _, step := range response.steps {
+		if !step.run(t) || ctx.Err() != nil {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ctx.Err() != nil {
+		t.fail("MessageAbortedError", "The operation was aborted.")
+	}
+	t.addPart(map[string]any{
+		"type":   "step-finish",
+		"cost":   t.cost,
+		"tokens": tokens(t.input, t.output),
+	})
+	assistant.info["cost"] = t.cost
+	assistant.info["tokens"] = tokens(t.input, t.output)
+	assistant.info["time"].(map[string]any)["completed"] = now()
+	s.publish("message.updated", map[string]any{"info": assistant.info})
+	session.cancel = nil
+	s.publish("session.idle", map[string]any{"sessionID": sessionID})
+	writeJSON(w, map[string]any{"info": assistant.info, "parts": assistant.parts})
+}
+
+func tokens(input, output int) map[string]any {
+	return map[string]any{
+		"input":     input,
+		"output":    output,
+		"reasoning": 0,
+		"cache":     map[string]any{"read": 0, "write": 0},
+	}
+}
@@ -0,0 +1,715 @@
+// Package sdktest provides an in-memory sgptcoder server for tests.
+//
+// [NewServer] starts an [httptest.Server] that implements the session,
+// message, permission, file, find, config and event endpoints without a model
+// provider or network access. Assistant replies are scripted with [Reply]:
+//
+//	server := sdktest.NewServer()
+//	defer server.Close()
+//	server.Enqueue(sdktest.Reply(
+//		sdktest.Text("Listing files"),
+//		sdktest.Tool("bash", map[string]any{"command": "ls"}).
+//			Ask("ls", nil).
+//			Output("main.go"),
+//	))
+//	client := server.Client()
+package sdktest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/internal"
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+)
+
+// Default names reported by the server.
+const (
+	ProviderID = "sdktest"
+	ModelID    = "fake"
+	Agent      = "build"
+	Worktree   = "/sdktest"
+)
+
+// Prompt is a prompt the server received.
+type Prompt struct {
+	SessionID  string
+	MessageID  string
+	Agent      string
+	ProviderID string
+	ModelID    string
+	// Text joins the text parts of the prompt.
+	Text string
+	// Parts are the parts as sent by the client.
+	Parts []map[string]any
+}
+
+// Server is an in-memory sgptcoder server. It is safe for concurrent use.
+type Server struct {
+	// URL is the base URL of the server, e.g. http://127.0.0.1:1234.
+	URL string
+
+	server *httptest.Server
+
+	mu          sync.Mutex
+	seq         int
+	config      json.RawMessage
+	sessions    map[string]*session
+	files       map[string]string
+	status      []sgptcoder.File
+	responses   []Response
+	handler     func(Prompt) Response
+	prompts     []Prompt
+	permissions map[string]*permission
+	subscribers map[chan []byte]struct{}
+}
+
+type session struct {
+	info     map[string]any
+	messages []*message
+	// always holds the permission types answered with "always".
+	always map[string]bool
+	cancel context.CancelFunc
+}
+
+type message struct {
+	info  map[string]any
+	parts []map[string]any
+}
+
+type permission struct {
+	info  map[string]any
+	reply chan string
+}
+
+// Option configures a [Server].
+type Option func(*Server)
+
+// WithConfig sets the JSON returned by the config endpoint.
+func WithConfig(config string) Option {
+	return func(s *Server) {
+		s.config = json.RawMessage(config)
+	}
+}
+
+// WithFiles adds files to the server's worktree, keyed by their path
+// relative to [Worktree].
+func WithFiles(files map[string]string) Option {
+	return func(s *Server) {
+		for name, content := range files {
+			s.files[path.Clean(name)] = content
+		}
+	}
+}
+
+// WithFileStatus sets the changed files returned by the file status endpoint.
+func WithFileStatus(files ...sgptcoder.File) Option {
+	return func(s *Server) {
+		s.status = files
+	}
+}
+
+// NewServer starts a server. Call [Server.Close] when done.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		config:      json.RawMessage(`{}`),
+		sessions:    map[string]*session{},
+		files:       map[string]string{},
+		permissions: map[string]*permission{},
+		subscribers: map[chan []byte]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	return s
+}
+
+// Close aborts running replies and shuts the server down.
+func (s *Server) Close() {
+	s.mu.Lock()
+	for _, session := range s.sessions {
+		if session.cancel != nil {
+			session.cancel()
+		}
+	}
+	for subscriber := range s.subscribers {
+		delete(s.subscribers, subscriber)
+		close(subscriber)
+	}
+	s.mu.Unlock()
+	s.server.CloseClientConnections()
+	s.server.Close()
+}
+
+// Client returns a client for the server. Options are applied after the base
+// URL.
+func (s *Server) Client(opts ...option.RequestOption) *sgptcoder.Client {
+	opts = append([]option.RequestOption{
+		option.WithBaseURL(s.URL),
+		option.WithHTTPClient(s.server.Client()),
+		option.WithMaxRetries(0),
+	}, opts...)
+	return sgptcoder.NewClient(opts...)
+}
+
+// Enqueue scripts the replies to the next prompts, in order.
+func (s *Server) Enqueue(responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, responses...)
+}
+
+// HandlePrompt sets the function that scripts the reply to a prompt once the
+// enqueued replies are used up. Without one such prompts fail with an
+// UnknownError.
+func (s *Server) HandlePrompt(handler func(Prompt) Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = handler
+}
+
+// Prompts returns the prompts received so far.
+func (s *Server) Prompts() []Prompt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Prompt(nil), s.prompts...)
+}
+
+// AddSession creates a session, optionally as the child of another, and
+// returns its ID.
+func (s *Server) AddSession(title, parentID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.newSession(title, parentID)
+}
+
+// Publish sends an event to every client streaming events.
+func (s *Server) Publish(eventType string, properties any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.publish(eventType, properties)
+}
+
+// publish must be called with s.mu held, which keeps events in order.
+func (s *Server) publish(eventType string, properties any) {
+	data, err := json.Marshal(map[string]any{"type": eventType, "properties": properties})
+	if err != nil {
+		panic(fmt.Sprintf("sdktest: encoding %s event: %v", eventType, err))
+	}
+	for subscriber := range s.subscribers {
+		select {
+		case subscriber <- data:
+		default:
+			// A stalled client must not block the server; drop it.
+			delete(s.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+func (s *Server) id(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s_%08d", prefix, s.seq)
+}
+
+func now() int64 {
+	return time.Now().UnixMilli()
+}
+
+func (s *Server) newSession(title, parentID string) string {
+	id := s.id("ses")
+	if title == "" {
+		title = "New session - " + time.Now().UTC().Format(time.RFC3339)
+	}
+	info := map[string]any{
+		"id":        id,
+		"title":     title,
+		"version":   "sdktest",
+		"directory": Worktree,
+		"projectID": "sdktest",
+		"time":      map[string]any{"created": now(), "updated": now()},
+	}
+	if parentID != "" {
+		info["parentID"] = parentID
+	}
+	s.sessions[id] = &session{info: info, always: map[string]bool{}}
+	s.publish("session.updated", map[string]any{"info": info})
+	return id
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(sgptcoder.ServerVersionHeader, internal.ServerVersion)
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	route := r.Method + " " + segments[0]
+	if segments[0] == "session" && len(segments) > 1 {
+		// Replace IDs so routes read like the API's paths
+		route = r.Method + " session/:id"
+		if len(segments) > 2 {
+			route += "/" + segments[2]
+		}
+		if len(segments) > 3 {
+			route += "/:id"
+		}
+	} else if len(segments) > 1 {
+		route = r.Method + " " + strings.Join(segments, "/")
+	}
+
+	switch route {
+	case "GET event":
+		s.serveEvents(w, r)
+	case "GET config":
+		s.mu.Lock()
+		config := s.config
+		s.mu.Unlock()
+		writeJSON(w, config)
+	case "GET config/providers":
+		writeJSON(w, providers())
+	case "GET agent":
+		writeJSON(w, []map[string]any{{
+			"name":       Agent,
+			"mode":       "primary",
+			"builtIn":    true,
+			"options":    map[string]any{},
+			"tools":      map[string]bool{},
+			"permission": map[string]any{"edit": "ask", "bash": map[string]string{"*": "ask"}, "webfetch": "ask"},
+		}})
+	case "GET project":
+		writeJSON(w, []map[string]any{project()})
+	case "GET project/current":
+		writeJSON(w, project())
+	case "GET path":
+		writeJSON(w, map[string]any{"config": Worktree, "directory": Worktree, "state": Worktree, "worktree": Worktree})
+	case "GET command":
+		writeJSON(w, []any{})
+	case "GET file":
+		s.listFiles(w, r.URL.Query().Get("path"))
+	case "GET file/content":
+		s.readFile(w, r.URL.Query().Get("path"))
+	case "GET file/status":
+		s.mu.Lock()
+		status := append([]sgptcoder.File{}, s.status...)
+		s.mu.Unlock()
+		writeJSON(w, status)
+	case "GET find/file":
+		s.findFiles(w, r.URL.Query().Get("query"))
+	case "GET find":
+		s.findText(w, r.URL.Query().Get("pattern"))
+	case "GET find/symbol":
+		writeJSON(w, []any{})
+	case "POST session":
+		var body struct {
+			Title    string `json:"title"`
+			ParentID string `json:"parentID"`
+		}
+		if !readJSON(w, r, &body) {
+			return
+		}
+		s.mu.Lock()
+		id := s.newSession(body.Title, body.ParentID)
+		writeJSON(w, s.sessions[id].info)
+		s.mu.Unlock()
+	case "GET session":
+		s.mu.Lock()
+		sessions := make([]map[string]any, 0, len(s.sessions))
+		for _, session := range s.sessions {
+			sessions = append(sessions, session.info)
+		}
+		sort.Slice(sessions, func(i, j int) bool {
+			return sessions[i]["id"].(string) < sessions[j]["id"].(string)
+		})
+		writeJSON(w, sessions)
+		s.mu.Unlock()
+	default:
+		if strings.HasPrefix(route, r.Method+" session/:id") {
+			s.serveSession(w, r, route, segments)
+			return
+		}
+		writeError(w, http.StatusNotFound, "NotFoundError", "sdktest: no route for "+r.Method+" "+r.URL.Path)
+	}
+}
+
+func (s *Server) serveSession(w http.ResponseWriter, r *http.Request, route string, segments []string) {
+	id := segments[1]
+	s.mu.Lock()
+	session, ok := s.sessions[id]
+	s.mu.Unlock()
+	if !ok {
+		writeJSONStatus(w, http.StatusNotFound, map[string]any{
+			"name": "NotFoundError",
+			"data": map[string]any{"message": "sdktest: session " + id + " not found", "sessionID": id},
+		})
+		return
+	}
+
+	switch route {
+	case "GET session/:id":
+		s.mu.Lock()
+		writeJSON(w, session.info)
+		s.mu.Unlock()
+	case "PATCH session/:id":
+		var body struct {
+			Title *string `json:"title"`
+		}
+		if !readJSON(w, r, &body) {
+			return
+		}
+		s.mu.Lock()
+		if body.Title != nil {
+			session.info["title"] = *body.Title
+		}
+		session.info["time"].(map[string]any)["updated"] = now()
+		s.publish("session.updated", map[string]any{"info": session.info})
+		writeJSON(w, session.info)
+		s.mu.Unlock()
+	case "DELETE session/:id":
+		s.mu.Lock()
+		if session.cancel != nil {
+			session.cancel()
+		}
+		delete(s.sessions, id)
+		s.publish("session.deleted", map[string]any{"info": session.info})
+		s.mu.Unlock()
+		writeJSON(w, true)
+	case "GET session/:id/children":
+		s.mu.Lock()
+		children := []map[string]any{}
+		for _, child := range s.sessions {
+			if child.info["parentID"] == id {
+				children = append(children, child.info)
+			}
+		}
+		sort.Slice(children, func(i, j int) bool {
+			return children[i]["id"].(string) < children[j]["id"].(string)
+		})
+		writeJSON(w, children)
+		s.mu.Unlock()
+	case "POST session/:id/abort":
+		s.mu.Lock()
+		aborted := session.cancel != nil
+		if aborted {
+			session.cancel()
+		}
+		s.mu.Unlock()
+		writeJSON(w, aborted)
+	case "POST session/:id/init", "POST session/:id/summarize":
+		writeJSON(w, true)
+	case "GET session/:id/message":
+		s.mu.Lock()
+		messages := make([]map[string]any, 0, len(session.messages))
+		before := r.URL.Query().Get("before")
+		for _, message := range session.messages {
+			if before != "" && message.info["id"].(string) >= before {
+				continue
+			}
+			messages = append(messages, map[string]any{"info": message.info, "parts": message.parts})
+		}
+		if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 && limit < len(messages) {
+			messages = messages[len(messages)-limit:]
+		}
+		writeJSON(w, messages)
+		s.mu.Unlock()
+	case "GET session/:id/message/:id":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, message := range session.messages {
+			if message.info["id"] == segments[3] {
+				writeJSON(w, map[string]any{"info": message.info, "parts": message.parts})
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "NotFoundError", "sdktest: message "+segments[3]+" not found")
+	case "POST session/:id/message":
+		var body promptBody
+		if !readJSON(w, r, &body) {
+			return
+		}
+		s.prompt(w, r, session, body)
+	case "POST session/:id/command":
+		var body struct {
+			promptBody
+			Command   string `json:"command"`
+			Arguments string `json:"arguments"`
+		}
+		if !readJSON(w, r, &body) {
+			return
+		}
+		text := strings.TrimSpace("/" + body.Command + " " + body.Arguments)
+		body.Parts = []map[string]any{{"type": "text", "text": text}}
+		s.prompt(w, r, session, body.promptBody)
+	case "POST session/:id/fork":
+		var body struct {
+			MessageID string `json:"messageID"`
+		}
+		if !readJSON(w, r, &body) {
+			return
+		}
+		s.mu.Lock()
+		if body.MessageID != "" && !slices.ContainsFunc(session.messages, func(m *message) bool { return m.info["id"] == body.MessageID }) {
+			s.mu.Unlock()
+			writeError(w, http.StatusNotFound, "NotFoundError", "sdktest: message "+body.MessageID+" not found")
+			return
+		}
+		forkID := s.newSession(session.info["title"].(string)+" (fork)", "")
+		fork := s.sessions[forkID]
+		for _, original := range session.messages {
+			if body.MessageID != "" && original.info["id"].(string) > body.MessageID {
+				break
+			}
+			copied := &message{info: maps.Clone(original.info)}
+			copied.info["id"] = s.id("msg")
+			copied.info["sessionID"] = forkID
+			for _, part := range original.parts {
+				part = maps.Clone(part)
+				part["id"] = s.id("prt")
+				part["messageID"] = copied.info["id"]
+				part["sessionID"] = forkID
+				copied.parts = append(copied.parts, part)
+			}
+			fork.messages = append(fork.messages, copied)
+		}
+		writeJSON(w, fork.info)
+		s.mu.Unlock()
+	case "POST session/:id/permissions/:id":
+		var body struct {
+			Response string `json:"response"`
+		}
+		if !readJSON(w, r, &body) {
+			return
+		}
+		s.mu.Lock()
+		permission, ok := s.permissions[segments[3]]
+		if ok {
+			delete(s.permissions, segments[3])
+		}
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, "NotFoundError", "sdktest: permission "+segments[3]+" not found")
+			return
+		}
+		permission.reply <- body.Response
+		writeJSON(w, true)
+	default:
+		writeError(w, http.StatusNotFound, "NotFoundError", "sdktest: no route for "+r.Method+" "+r.URL.Path)
+	}
+}
+
+type promptBody struct {
+	MessageID string           `json:"messageID"`
+	Agent     string           `json:"agent"`
+	Parts     []map[string]any `json:"parts"`
+	Model     struct {
+		ProviderID string `json:"providerID"`
+		ModelID    string `json:"modelID"`
+	} `json:"model"`
+}
+
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "UnknownError", "sdktest: streaming unsupported")
+		return
+	}
+	events := make(chan []byte, 1024)
+	s.mu.Lock()
+	s.subscribers[events] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		if _, ok := s.subscribers[events]; ok {
+			delete(s.subscribers, events)
+			close(events)
+		}
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, "data: {\"type\":\"server.connected\",\"properties\":{}}\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data, ok := <-events:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Server) listFiles(w http.ResponseWriter, dir string) {
+	dir = path.Clean(strings.TrimPrefix(dir, Worktree+"/"))
+	if dir == Worktree || dir == "" {
+		dir = "."
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := map[string]bool{}
+	nodes := []sgptcoder.FileNode{}
+	for name := range s.files {
+		rel := name
+		if dir != "." {
+			if !strings.HasPrefix(name, dir+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(name, dir+"/")
+		}
+		child, _, isDir := strings.Cut(rel, "/")
+		if seen[child] {
+			continue
+		}
+		seen[child] = true
+		nodePath := path.Join(dir, child)
+		node := sgptcoder.FileNode{
+			Name:     child,
+			Path:     nodePath,
+			Absolute: path.Join(Worktree, nodePath),
+			Type:     sgptcoder.FileNodeTypeFile,
+		}
+		if isDir {
+			node.Type = sgptcoder.FileNodeTypeDirectory
+		}
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Type != nodes[j].Type {
+			return nodes[i].Type == sgptcoder.FileNodeTypeDirectory
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+	writeJSON(w, nodes)
+}
+
+func (s *Server) readFile(w http.ResponseWriter, name string) {
+	name = path.Clean(strings.TrimPrefix(name, Worktree+"/"))
+	s.mu.Lock()
+	content, ok := s.files[name]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFoundError", "sdktest: file "+name+" not found")
+		return
+	}
+	writeJSON(w, map[string]any{"type": "raw", "content": content})
+}
+
+func (s *Server) findFiles(w http.ResponseWriter, query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	matches := []string{}
+	for name := range s.files {
+		if strings.Contains(strings.ToLower(name), strings.ToLower(query)) {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	writeJSON(w, matches)
+}
+
+func (s *Server) findText(w http.ResponseWriter, pattern string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.files))
+	for name := range s.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	matches := []map[string]any{}
+	for _, name := range names {
+		offset := 0
+		for i, line := range strings.SplitAfter(s.files[name], "\n") {
+			if start := strings.Index(line, pattern); pattern != "" && start >= 0 {
+				matches = append(matches, map[string]any{
+					"path":            map[string]any{"text": name},
+					"lines":           map[string]any{"text": line},
+					"line_number":     i + 1,
+					"absolute_offset": offset,
+					"submatches": []map[string]any{{
+						"match": map[string]any{"text": pattern},
+						"start": start,
+						"end":   start + len(pattern),
+					}},
+				})
+			}
+			offset += len(line)
+		}
+	}
+	writeJSON(w, matches)
+}
+
+func project() map[string]any {
+	return map[string]any{"id": "sdktest", "worktree": Worktree, "time": map[string]any{"created": 0}}
+}
+
+func providers() map[string]any {
+	return map[string]any{
+		"default": map[string]string{ProviderID: ModelID},
+		"providers": []map[string]any{{
+			"id":   ProviderID,
+			"name": "sdktest",
+			"env":  []string{},
+			"models": map[string]any{
+				ModelID: map[string]any{
+					"id":           ModelID,
+					"name":         "Fake",
+					"attachment":   true,
+					"reasoning":    true,
+					"temperature":  true,
+					"tool_call":    true,
+					"release_date": "2025-01-01",
+					"options":      map[string]any{},
+					"cost":         map[string]any{"input": 0, "output": 0},
+					"limit":        map[string]any{"context": 100000, "output": 10000},
+				},
+			},
+		}},
+	}
+}
+
+// writeJSON encodes the value before writing anything. Values the server keeps
+// changing must be written with s.mu held.
+func writeJSON(w http.ResponseWriter, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "UnknownError", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, status int, name, message string) {
+	writeJSONStatus(w, status, map[string]any{"name": name, "data": map[string]any{"message": message}})
+}
+
+func writeJSONStatus(w http.ResponseWriter, status int, value any) {
+	data, _ := json.Marshal(value)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, value any) bool {
+	if err := json.NewDecoder(r.Body).Decode(value); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "BadRequestError", "sdktest: invalid body: "+err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,362 @@
+package sdktest_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/sdktest"
+)
+
+func textPrompt(text string) sgptcoder.SessionPromptParams {
+	return sgptcoder.SessionPromptParams{
+		Parts: sgptcoder.F([]sgptcoder.SessionPromptParamsPartUnion{
+			sgptcoder.TextPartInputParam{Type: sgptcoder.F(sgptcoder.TextPartInputTypeText), Text: sgptcoder.F(text)},
+		}),
+	}
+}
+
+func partTypes(parts []sgptcoder.Part) string {
+	var types []string
+	for _, part := range parts {
+		types = append(types, string(part.Type))
+	}
+	return strings.Join(types, ",")
+}
+
+func TestPromptWithPermission(t *testing.T) {
+	server := sdktest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server.Enqueue(sdktest.Reply(
+		sdktest.Text("Let me ", "look"),
+		sdktest.Tool("bash", map[string]any{"command": "ls"}).
+			Ask("ls", nil).
+			Title("ls").
+			Output("main.go"),
+		sdktest.Usage(10, 5, 0.25),
+	))
+
+	session, err := client.Session.New(ctx, sgptcoder.SessionNewParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stream := client.Event.ListStreaming(ctx, sgptcoder.EventListParams{})
+	defer stream.Close()
+	if !stream.Next() || stream.Current().Type != sgptcoder.EventListResponseTypeServerConnected {
+		t.Fatalf("expected server.connected, got %v", stream.Err())
+	}
+
+	type result struct {
+		response *sgptcoder.SessionPromptResponse
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, err := client.Session.Prompt(ctx, session.ID, textPrompt("list files"))
+		done <- result{response, err}
+	}()
+
+	var events []string
+	for stream.Next() {
+		event := stream.Current()
+		events = append(events, string(event.Type))
+		if permission, ok := event.AsUnion().(sgptcoder.EventListResponseEventPermissionUpdated); ok {
+			if permission.Properties.Metadata["command"] != "ls" {
+				t.Errorf("expected the tool input as metadata, got %v", permission.Properties.Metadata)
+			}
+			_, err := client.Session.Permissions.Respond(ctx, session.ID, permission.Properties.ID, sgptcoder.SessionPermissionRespondParams{
+				Response: sgptcoder.F(sgptcoder.SessionPermissionRespondParamsResponseOnce),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if event.Type == sgptcoder.EventListResponseTypeSessionIdle {
+			break
+		}
+	}
+
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("unexpected error: %v", res.err)
+	}
+	if got := partTypes(res.response.Parts); got != "step-start,text,tool,step-finish" {
+		t.Errorf("unexpected parts %s", got)
+	}
+	if text := res.response.Parts[1].AsUnion().(sgptcoder.TextPart).Text; text != "Let me look" {
+		t.Errorf("expected the joined text, got %q", text)
+	}
+	tool := res.response.Parts[2].AsUnion().(sgptcoder.ToolPart)
+	if tool.State.Status != sgptcoder.ToolPartStateStatusCompleted || tool.State.Output != "main.go" {
+		t.Errorf("expected a completed tool call, got %+v", tool.State)
+	}
+	if res.response.Info.Tokens.Input != 10 || res.response.Info.Cost != 0.25 {
+		t.Errorf("expected the usage on the message, got %+v", res.response.Info.Tokens)
+	}
+	joined := strings.Join(events, ",")
+	if !strings.Contains(joined, "permission.updated") || !strings.Contains(joined, "permission.replied") {
+		t.Errorf("expected permission events, got %s", joined)
+	}
+
+	prompts := server.Prompts()
+	if len(prompts) != 1 || prompts[0].Text != "list files" || prompts[0].SessionID != session.ID {
+		t.Errorf("unexpected prompts %+v", prompts)
+	}
+	messages, err := client.Session.Messages(ctx, session.ID, sgptcoder.SessionMessagesParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*messages) != 2 {
+		t.Errorf("expected the prompt and the reply, got %d messages", len(*messages))
+	}
+}
+
+func TestPromptRejectedPermission(t *testing.T) {
+	server := sdktest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, err := client.Session.New(ctx, sgptcoder.SessionNewParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server.Enqueue(sdktest.Reply(
+		sdktest.Tool("edit", map[string]any{"filePath": "main.go"}).Ask("Edit this file: main.go", nil),
+		sdktest.Text("never sent"),
+	))
+
+	stream := client.Event.ListStreaming(ctx, sgptcoder.EventListParams{})
+	defer stream.Close()
+	stream.Next()
+	go func() {
+		for stream.Next() {
+			if permission, ok := stream.Current().AsUnion().(sgptcoder.EventListResponseEventPermissionUpdated); ok {
+				client.Session.Permissions.Respond(ctx, session.ID, permission.Properties.ID, sgptcoder.SessionPermissionRespondParams{
+					Response: sgptcoder.F(sgptcoder.SessionPermissionRespondParamsResponseReject),
+				})
+			}
+		}
+	}()
+
+	response, err := client.Session.Prompt(ctx, session.ID, textPrompt("edit"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := partTypes(response.Parts); got != "step-start,tool,step-finish" {
+		t.Errorf("expected the reply to stop, got %s", got)
+	}
+	tool := response.Parts[1].AsUnion().(sgptcoder.ToolPart)
+	if tool.State.Status != sgptcoder.ToolPartStateStatusError || tool.State.Error != sdktest.RejectedError {
+		t.Errorf("expected a rejected tool call, got %+v", tool.State)
+	}
+}
+
+func TestPromptErrorAndAbort(t *testing.T) {
+	server := sdktest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sessionID := server.AddSession("test", "")
+
+	server.Enqueue(sdktest.Reply(sdktest.Error("ProviderAuthError", "bad key")))
+	response, err := client.Session.Prompt(ctx, sessionID, textPrompt("hi"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Info.Error.Name != sgptcoder.AssistantMessageErrorNameProviderAuthError {
+		t.Errorf("expected a provider auth error, got %+v", response.Info.Error)
+	}
+
+	started := make(chan struct{})
+	server.HandlePrompt(func(prompt sdktest.Prompt) sdktest.Response {
+		close(started)
+		return sdktest.Reply(sdktest.Text("working"), sdktest.Wait(make(chan struct{})))
+	})
+	go func() {
+		<-started
+		time.Sleep(10 * time.Millisecond)
+		client.Session.Abort(ctx, sessionID, sgptcoder.SessionAbortParams{})
+	}()
+	response, err = client.Session.Prompt(ctx, sessionID, textPrompt("long task"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Info.Error.Name != sgptcoder.AssistantMessageErrorNameMessageAbortedError {
+		t.Errorf("expected an aborted message, got %+v", response.Info.Error)
+	}
+}
+
+func TestUnscriptedPrompt(t *testing.T) {
+	server := sdktest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	sessionID := server.AddSession("test", "")
+
+	response, err := client.Session.Prompt(context.Background(), sessionID, textPrompt("hi"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Info.Error.Name != sgptcoder.AssistantMessageErrorNameUnknownError {
+		t.Errorf("expected an unknown error, got %+v", response.Info.Error)
+	}
+}
+
+func TestMessagesPage(t *testing.T) {
+	server := sdktest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+	sessionID := server.AddSession("test", "")
+	for _, text := range []string{"one", "two"} {
+		server.Enqueue(sdktest.Reply(sdktest.Text(text)))
+		if _, err := client.Session.Prompt(ctx, sessionID, textPrompt(text)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	page, err := client.Session.Messages(ctx, sessionID, sgptcoder.SessionMessagesParams{Limit: sgptcoder.F(int64(2))})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*page) != 2 || (*page)[0].Info.Role != sgptcoder.MessageRoleUser {
+		t.Fatalf("expected the last exchange, got %+v", *page)
+	}
+	older, err := client.Session.Messages(ctx, sessionID, sgptcoder.SessionMessagesParams{Before: sgptcoder.F((*page)[0].Info.ID)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*older) != 2 || (*older)[1].Info.ID >= (*page)[0].Info.ID {
+		t.Errorf("expected the first exchange, got %+v", *older)
+	}
+}
+
+func TestFork(t *testing.T) {
+	server := sdktest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+	sessionID := server.AddSession("test", "")
+	for _, text := range []string{"one", "two"} {
+		server.Enqueue(sdktest.Reply(sdktest.Text(text)))
+		if _, err := client.Session.Prompt(ctx, sessionID, textPrompt(text)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	messages, err := client.Session.Messages(ctx, sessionID, sgptcoder.SessionMessagesParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fork, err := client.Session.Fork(ctx, sessionID, sgptcoder.SessionForkParams{
+		MessageID: sgptcoder.F((*messages)[1].Info.ID),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fork.ID == sessionID || fork.Title != "test (fork)" {
+		t.Errorf("expected a new session, got %+v", fork)
+	}
+	forked, err := client.Session.Messages(ctx, fork.ID, sgptcoder.SessionMessagesParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*forked) != 2 {
+		t.Fatalf("expected the first exchange, got %+v", *forked)
+	}
+	for i, message := range *forked {
+		original := (*messages)[i]
+		if message.Info.ID == original.Info.ID || message.Info.SessionID != fork.ID {
+			t.Errorf("message %d was not copied: %+v", i, message.Info)
+		}
+		if partTypes(message.Parts) != partTypes(original.Parts) || message.Parts[0].MessageID != message.Info.ID {
+			t.Errorf("parts of message %d were not copied: %+v", i, message.Parts)
+		}
+	}
+
+	_, err = client.Session.Fork(ctx, sessionID, sgptcoder.SessionForkParams{MessageID: sgptcoder.F("msg_missing")})
+	if !errors.Is(err, sgptcoder.ErrNotFound) {
+		t.Errorf("expected forking at an unknown message to fail with not found, got %v", err)
+	}
+}
+
+func TestSessionsFilesAndConfig(t *testing.T) {
+	server := sdktest.NewServer(
+		sdktest.WithConfig(`{"theme":"system"}`),
+		sdktest.WithFiles(map[string]string{
+			"main.go":          "package main\n\nfunc main() {}\n",
+			"internal/app.go":  "package internal\n",
+			"internal/util.go": "package internal\n\nfunc main() {}\n",
+		}),
+	)
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	parentID := server.AddSession("parent", "")
+	childID := server.AddSession("child", parentID)
+	children, err := client.Session.Children(ctx, parentID, sgptcoder.SessionChildrenParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*children) != 1 || (*children)[0].ID != childID || (*children)[0].ParentID != parentID {
+		t.Errorf("unexpected children %+v", *children)
+	}
+	if _, err := client.Session.Get(ctx, "ses_missing", sgptcoder.SessionGetParams{}); err == nil {
+		t.Error("expected an error for a missing session")
+	}
+
+	nodes, err := client.File.List(ctx, sgptcoder.FileListParams{Path: sgptcoder.F("")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*nodes) != 2 || (*nodes)[0].Name != "internal" || (*nodes)[0].Type != sgptcoder.FileNodeTypeDirectory {
+		t.Errorf("unexpected nodes %+v", *nodes)
+	}
+	content, err := client.File.Read(ctx, sgptcoder.FileReadParams{Path: sgptcoder.F("internal/app.go")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content.Content != "package internal\n" {
+		t.Errorf("unexpected content %q", content.Content)
+	}
+	files, err := client.Find.Files(ctx, sgptcoder.FindFilesParams{Query: sgptcoder.F("internal")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*files) != 2 {
+		t.Errorf("unexpected files %v", *files)
+	}
+	matches, err := client.Find.Text(ctx, sgptcoder.FindTextParams{Pattern: sgptcoder.F("func main")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*matches) != 2 || (*matches)[0].LineNumber != 3 {
+		t.Errorf("unexpected matches %+v", *matches)
+	}
+
+	config, err := client.Config.Get(ctx, sgptcoder.ConfigGetParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Theme != "system" {
+		t.Errorf("expected the configured theme, got %q", config.Theme)
+	}
+	providers, err := client.App.Providers(ctx, sgptcoder.AppProvidersParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if providers.Default[sdktest.ProviderID] != sdktest.ModelID {
+		t.Errorf("unexpected providers %+v", providers)
+	}
+}
@@ -116,6 +116,19 @@ func (r *SessionService) Command(ctx context.Context, id string, params SessionC
 	return
 }
 
+// Create a new session with the messages of a session, up to and including a
+// message
+func (r *SessionService) Fork(ctx context.Context, id string, params SessionForkParams, opts ...option.RequestOption) (res *Session, err error) {
+	opts = append(r.Options[:], opts...)
+	if id == "" {
+		err = errors.New("missing required id parameter")
+		return
+	}
+	path := fmt.Sprintf("session/%s/fork", id)
+	err = requestconfig.ExecuteNewRequest(ctx, http.MethodPost, path, params, &res, opts...)
+	return
+}
+
 // Get session
 func (r *SessionService) Get(ctx context.Context, id string, query SessionGetParams, opts ...option.RequestOption) (res *Session, err error) {
 	opts = append(r.Options[:], opts...)
@@ -2475,6 +2488,24 @@ func (r SessionCommandParams) URLQuery() (v url.Values) {
 	})
 }
 
+type SessionForkParams struct {
+	Directory param.Field[string] `query:"directory"`
+	// Last message to copy, all of them if omitted
+	MessageID param.Field[string] `json:"messageID"`
+}
+
+func (r SessionForkParams) MarshalJSON() (data []byte, err error) {
+	return apijson.MarshalRoot(r)
+}
+
+// URLQuery serializes [SessionForkParams]'s query parameters as `url.Values`.
+func (r SessionForkParams) URLQuery() (v url.Values) {
+	return apiquery.MarshalWithSettings(r, apiquery.QuerySettings{
+		ArrayFormat:  apiquery.ArrayQueryFormatComma,
+		NestedFormat: apiquery.NestedQueryFormatBrackets,
+	})
+}
+
 type SessionGetParams struct {
 	Directory param.Field[string] `query:"directory"`
 }
@@ -2519,7 +2550,11 @@ func (r SessionMessageParams) URLQuery() (v url.Values) {
 }
 
 type SessionMessagesParams struct {
+	// Only return messages older than this message ID
+	Before    param.Field[string] `query:"before"`
 	Directory param.Field[string] `query:"directory"`
+	// Return at most this many messages, the most recent ones
+	Limit param.Field[int64] `query:"limit"`
 }
 
 // URLQuery serializes [SessionMessagesParams]'s query parameters as `url.Values`.
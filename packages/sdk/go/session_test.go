@@ -209,6 +209,35 @@ func TestSessionCommandWithOptionalParams(t *testing.T) {
 	}
 }
 
+func TestSessionForkWithOptionalParams(t *testing.T) {
+	t.Skip("Prism tests are disabled")
+	baseURL := "http://localhost:4010"
+	if envURL, ok := os.LookupEnv("TEST_API_BASE_URL"); ok {
+		baseURL = envURL
+	}
+	if !testutil.CheckTestServer(t, baseURL) {
+		return
+	}
+	client := sgptcoder.NewClient(
+		option.WithBaseURL(baseURL),
+	)
+	_, err := client.Session.Fork(
+		context.TODO(),
+		"id",
+		sgptcoder.SessionForkParams{
+			Directory: sgptcoder.F("directory"),
+			MessageID: sgptcoder.F("msgJ!"),
+		},
+	)
+	if err != nil {
+		var apierr *sgptcoder.Error
+		if errors.As(err, &apierr) {
+			t.Log(string(apierr.DumpRequest(true)))
+		}
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+}
+
 func TestSessionGetWithOptionalParams(t *testing.T) {
 	t.Skip("Prism tests are disabled")
 	baseURL := "http://localhost:4010"
@@ -313,7 +342,9 @@ func TestSessionMessagesWithOptionalParams(t *testing.T) {
 		context.TODO(),
 		"id",
 		sgptcoder.SessionMessagesParams{
+			Before:    sgptcoder.F("before"),
 			Directory: sgptcoder.F("directory"),
+			Limit:     sgptcoder.F(int64(0)),
 		},
 	)
 	if err != nil {
@@ -0,0 +1,345 @@
+package sgptcoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+	"github.com/skorpland/sgptcoder-sdk-go/packages/ssestream"
+)
+
+// PromptStreamEventType identifies the kind of change reported by a
+// [PromptStreamEvent].
+type PromptStreamEventType string
+
+const (
+	// PromptStreamEventText reports text appended to a text part.
+	PromptStreamEventText PromptStreamEventType = "text"
+	// PromptStreamEventReasoning reports text appended to a reasoning part.
+	PromptStreamEventReasoning PromptStreamEventType = "reasoning"
+	// PromptStreamEventTool reports that a tool part changed status.
+	PromptStreamEventTool PromptStreamEventType = "tool"
+	// PromptStreamEventStepFinish reports a finished step along with its token
+	// counts and cost.
+	PromptStreamEventStepFinish PromptStreamEventType = "step-finish"
+)
+
+// PromptStreamEvent is one incremental change to the assistant reply.
+type PromptStreamEvent struct {
+	Type      PromptStreamEventType
+	MessageID string
+	PartID    string
+	// Delta is the text appended to a text or reasoning part since the previous
+	// event for that part.
+	Delta string
+	// Replace reports that the text of the part changed in a way that is not an
+	// append. Delta then holds the full text of the part.
+	Replace bool
+	// ToolStatus and PreviousToolStatus are set for tool events.
+	// PreviousToolStatus is empty the first time a tool part is seen.
+	ToolStatus         ToolPartStateStatus
+	PreviousToolStatus ToolPartStateStatus
+	// Part is the current state of the part. For step-finish events it is a
+	// [StepFinishPart] carrying the step's tokens and cost.
+	Part PartUnion
+}
+
+// ErrPromptReverted is returned by [PromptStream.Err] when the prompt or its
+// reply was removed from the session while streaming, e.g. by a revert.
+var ErrPromptReverted = errors.New("sgptcoder: prompt was reverted while streaming")
+
+// PromptSessionError is returned by [PromptStream.Err] when the session
+// reports an error, such as an aborted message or a provider failure, while
+// the reply is streaming.
+type PromptSessionError struct {
+	SessionID string
+	Name      string
+	Message   string
+	Err       EventListResponseEventSessionErrorPropertiesError
+}
+
+func (e *PromptSessionError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("sgptcoder: session %s: %s", e.SessionID, e.Name)
+	}
+	return fmt.Sprintf("sgptcoder: session %s: %s: %s", e.SessionID, e.Name, e.Message)
+}
+
+// PromptStream yields the incremental reply to a prompt submitted with
+// [SessionService.PromptStreaming].
+type PromptStream struct {
+	events    *ssestream.Stream[EventListResponse]
+	cancel    context.CancelFunc
+	sessionID string
+
+	userMessageID string
+	submitted     bool
+	messages      map[string]AssistantMessage
+	texts         map[string]string
+	tools         map[string]ToolPartStateStatus
+	steps         map[string]bool
+
+	pending []PromptStreamEvent
+	cur     PromptStreamEvent
+	done    bool
+	err     error
+
+	mu         sync.Mutex
+	response   *SessionPromptResponse
+	promptErr  error
+	promptDone chan struct{}
+}
+
+// PromptStreaming submits a prompt like [SessionService.Prompt] but returns
+// immediately with a stream of changes to the assistant reply. Only the
+// assistant messages answering this prompt are reported; the stream ends once
+// the session goes idle, or with an error if the session reports one, the
+// prompt is reverted, or the prompt request itself fails.
+//
+//	stream := client.Session.PromptStreaming(ctx, session.ID, params)
+//	defer stream.Close()
+//	for stream.Next() {
+//		if evt := stream.Current(); evt.Type == sgptcoder.PromptStreamEventText {
+//			fmt.Print(evt.Delta)
+//		}
+//	}
+//	if err := stream.Err(); err != nil {
+//		...
+//	}
+//
+// The event stream is opened before the prompt is sent so that no part of the
+// reply is missed.
+func (r *SessionService) PromptStreaming(ctx context.Context, id string, params SessionPromptParams, opts ...option.RequestOption) *PromptStream {
+	ctx, cancel := context.WithCancel(ctx)
+	events := NewEventService(r.Options...).ListStreaming(ctx, EventListParams{Directory: params.Directory}, opts...)
+
+	s := &PromptStream{
+		events:     events,
+		cancel:     cancel,
+		sessionID:  id,
+		messages:   map[string]AssistantMessage{},
+		texts:      map[string]string{},
+		tools:      map[string]ToolPartStateStatus{},
+		steps:      map[string]bool{},
+		promptDone: make(chan struct{}),
+	}
+	if params.MessageID.Present {
+		s.userMessageID = params.MessageID.Value
+	}
+
+	// The server greets every subscriber with server.connected, after which
+	// it is safe to submit the prompt.
+	if !events.Next() {
+		s.finish(events.Err())
+		if s.err == nil {
+			s.err = errors.New("sgptcoder: event stream closed before the prompt was sent")
+		}
+		close(s.promptDone)
+		return s
+	}
+	s.handle(events.Current().AsUnion())
+
+	go func() {
+		defer close(s.promptDone)
+		res, err := r.Prompt(ctx, id, params, opts...)
+		s.mu.Lock()
+		s.response, s.promptErr = res, err
+		s.mu.Unlock()
+		if err != nil {
+			// Unblock Next, which is waiting on events that will never come.
+			cancel()
+		}
+	}()
+	return s
+}
+
+// Next advances to the next change of the reply. It returns false when the
+// reply is complete or an error occurred; see [PromptStream.Err].
+func (s *PromptStream) Next() bool {
+	for {
+		if len(s.pending) > 0 {
+			s.cur, s.pending = s.pending[0], s.pending[1:]
+			return true
+		}
+		if s.done {
+			return false
+		}
+		if !s.events.Next() {
+			err := s.events.Err()
+			s.mu.Lock()
+			if s.promptErr != nil {
+				err = s.promptErr
+			}
+			s.mu.Unlock()
+			if err == nil {
+				err = errors.New("sgptcoder: event stream ended before the session went idle")
+			}
+			s.finish(err)
+			continue
+		}
+		s.handle(s.events.Current().AsUnion())
+	}
+}
+
+func (s *PromptStream) handle(event EventListResponseUnion) {
+	if sessionID, ok := EventSessionID(event); !ok || sessionID != s.sessionID {
+		return
+	}
+
+	switch e := event.(type) {
+	case EventListResponseEventMessageUpdated:
+		switch info := e.Properties.Info.AsUnion().(type) {
+		case UserMessage:
+			if s.userMessageID == "" {
+				s.userMessageID = info.ID
+			}
+			if info.ID == s.userMessageID {
+				s.submitted = true
+			}
+		case AssistantMessage:
+			// Summaries written by compaction are not part of the reply.
+			if s.userMessageID == "" || info.ID <= s.userMessageID || info.Summary {
+				return
+			}
+			s.messages[info.ID] = info
+		}
+	case EventListResponseEventMessagePartUpdated:
+		if _, ok := s.messages[e.Properties.Part.MessageID]; ok {
+			s.handlePart(e.Properties.Part.AsUnion())
+		}
+	case EventListResponseEventMessageRemoved:
+		if _, ok := s.messages[e.Properties.MessageID]; ok || e.Properties.MessageID == s.userMessageID {
+			s.finish(ErrPromptReverted)
+		}
+	case EventListResponseEventSessionError:
+		err := &PromptSessionError{
+			SessionID: s.sessionID,
+			Name:      string(e.Properties.Error.Name),
+			Err:       e.Properties.Error,
+		}
+		switch casted := e.Properties.Error.AsUnion().(type) {
+		case ProviderAuthError:
+			err.Message = casted.Data.Message
+		case UnknownError:
+			err.Message = casted.Data.Message
+		case MessageAbortedError:
+			err.Message = casted.Data.Message
+		}
+		s.finish(err)
+	case EventListResponseEventSessionIdle:
+		// An idle event before the prompt reached the session belongs to
+		// whatever the session was doing before.
+		if s.submitted {
+			s.finish(nil)
+		}
+	}
+}
+
+func (s *PromptStream) handlePart(part PartUnion) {
+	switch p := part.(type) {
+	case TextPart:
+		s.appendText(PromptStreamEventText, p.MessageID, p.ID, p.Text, p)
+	case ReasoningPart:
+		s.appendText(PromptStreamEventReasoning, p.MessageID, p.ID, p.Text, p)
+	case ToolPart:
+		previous := s.tools[p.ID]
+		if previous == p.State.Status {
+			return
+		}
+		s.tools[p.ID] = p.State.Status
+		s.pending = append(s.pending, PromptStreamEvent{
+			Type:               PromptStreamEventTool,
+			MessageID:          p.MessageID,
+			PartID:             p.ID,
+			ToolStatus:         p.State.Status,
+			PreviousToolStatus: previous,
+			Part:               p,
+		})
+	case StepFinishPart:
+		if s.steps[p.ID] {
+			return
+		}
+		s.steps[p.ID] = true
+		s.pending = append(s.pending, PromptStreamEvent{
+			Type:      PromptStreamEventStepFinish,
+			MessageID: p.MessageID,
+			PartID:    p.ID,
+			Part:      p,
+		})
+	}
+}
+
+func (s *PromptStream) appendText(typ PromptStreamEventType, messageID, partID, text string, part PartUnion) {
+	previous := s.texts[partID]
+	if text == previous {
+		return
+	}
+	s.texts[partID] = text
+
+	event := PromptStreamEvent{
+		Type:      typ,
+		MessageID: messageID,
+		PartID:    partID,
+		Part:      part,
+	}
+	if strings.HasPrefix(text, previous) {
+		event.Delta = text[len(previous):]
+	} else {
+		event.Delta = text
+		event.Replace = true
+	}
+	s.pending = append(s.pending, event)
+}
+
+func (s *PromptStream) finish(err error) {
+	if s.done {
+		return
+	}
+	s.done = true
+	s.err = err
+	s.events.Close()
+}
+
+// Current returns the change the stream last advanced to.
+func (s *PromptStream) Current() PromptStreamEvent {
+	return s.cur
+}
+
+// Err returns the error that ended the stream, if any. An aborted prompt is
+// reported as a [*PromptSessionError] named "MessageAbortedError".
+func (s *PromptStream) Err() error {
+	return s.err
+}
+
+// Messages returns the latest known state of the assistant messages that
+// answer the prompt, in the order they were created.
+func (s *PromptStream) Messages() []AssistantMessage {
+	messages := make([]AssistantMessage, 0, len(s.messages))
+	for _, message := range s.messages {
+		messages = append(messages, message)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].ID < messages[j].ID
+	})
+	return messages
+}
+
+// Response waits for the underlying prompt request to return and reports its
+// result. Close the stream first if the reply is no longer needed, which
+// cancels the request.
+func (s *PromptStream) Response() (*SessionPromptResponse, error) {
+	<-s.promptDone
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.response, s.promptErr
+}
+
+// Close stops streaming and cancels the prompt request if it is still running.
+func (s *PromptStream) Close() error {
+	s.cancel()
+	return s.events.Close()
+}
@@ -0,0 +1,85 @@
+package sgptcoder_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+)
+
+func TestSessionPromptStreaming(t *testing.T) {
+	prompted := make(chan struct{})
+	events := []string{
+		// Another session is ignored.
+		`{"type":"message.part.updated","properties":{"part":{"id":"prt_x","messageID":"msg_x","sessionID":"ses_other","type":"text","text":"nope"}}}`,
+		`{"type":"message.updated","properties":{"info":{"id":"msg_1","sessionID":"ses_1","role":"user","time":{"created":1}}}}`,
+		`{"type":"message.updated","properties":{"info":{"id":"msg_2","sessionID":"ses_1","role":"assistant","time":{"created":2},"cost":0,"mode":"build","modelID":"m","providerID":"p","path":{"cwd":"/","root":"/"},"system":[],"tokens":{"input":0,"output":0,"reasoning":0,"cache":{"read":0,"write":0}}}}}`,
+		`{"type":"message.part.updated","properties":{"part":{"id":"prt_1","messageID":"msg_1","sessionID":"ses_1","type":"text","text":"prompt text"}}}`,
+		`{"type":"message.part.updated","properties":{"part":{"id":"prt_2","messageID":"msg_2","sessionID":"ses_1","type":"text","text":"Hel"}}}`,
+		`{"type":"message.part.updated","properties":{"part":{"id":"prt_2","messageID":"msg_2","sessionID":"ses_1","type":"text","text":"Hello"}}}`,
+		`{"type":"message.part.updated","properties":{"part":{"id":"prt_3","messageID":"msg_2","sessionID":"ses_1","type":"tool","tool":"bash","callID":"c1","state":{"status":"running","input":{},"time":{"start":1}}}}}`,
+		`{"type":"message.part.updated","properties":{"part":{"id":"prt_3","messageID":"msg_2","sessionID":"ses_1","type":"tool","tool":"bash","callID":"c1","state":{"status":"completed","input":{},"output":"ok","title":"t","metadata":{},"time":{"start":1,"end":2}}}}}`,
+		`{"type":"message.part.updated","properties":{"part":{"id":"prt_4","messageID":"msg_2","sessionID":"ses_1","type":"step-finish","cost":0.5,"tokens":{"input":10,"output":5,"reasoning":0,"cache":{"read":0,"write":0}}}}}`,
+		`{"type":"session.idle","properties":{"sessionID":"ses_1"}}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/event":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"type\":\"server.connected\",\"properties\":{}}\n\n")
+			w.(http.Flusher).Flush()
+			<-prompted
+			for _, event := range events {
+				fmt.Fprintf(w, "data: %s\n\n", event)
+			}
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		case "/session/ses_1/message":
+			close(prompted)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"info":{"id":"msg_2","sessionID":"ses_1","role":"assistant"},"parts":[]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := sgptcoder.NewClient(option.WithBaseURL(server.URL))
+	stream := client.Session.PromptStreaming(context.Background(), "ses_1", sgptcoder.SessionPromptParams{
+		Parts: sgptcoder.F([]sgptcoder.SessionPromptParamsPartUnion{
+			sgptcoder.TextPartInputParam{Type: sgptcoder.F(sgptcoder.TextPartInputTypeText), Text: sgptcoder.F("hi")},
+		}),
+	})
+	defer stream.Close()
+
+	var got []string
+	for stream.Next() {
+		evt := stream.Current()
+		switch evt.Type {
+		case sgptcoder.PromptStreamEventText:
+			got = append(got, "text:"+evt.Delta)
+		case sgptcoder.PromptStreamEventTool:
+			got = append(got, fmt.Sprintf("tool:%s->%s", evt.PreviousToolStatus, evt.ToolStatus))
+		case sgptcoder.PromptStreamEventStepFinish:
+			step := evt.Part.(sgptcoder.StepFinishPart)
+			got = append(got, fmt.Sprintf("step:%v", step.Tokens.Input))
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "text:Hel,text:lo,tool:->running,tool:running->completed,step:10"
+	if strings.Join(got, ",") != want {
+		t.Errorf("expected %s, got %s", want, strings.Join(got, ","))
+	}
+	if messages := stream.Messages(); len(messages) != 1 || messages[0].ID != "msg_2" {
+		t.Errorf("expected the reply message msg_2, got %+v", messages)
+	}
+}
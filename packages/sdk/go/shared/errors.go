@@ -0,0 +1,19 @@
+package shared
+
+// The server's named errors implement error, so that they can be returned and
+// matched with errors.As like other Go errors.
+
+func (r ProviderAuthError) Error() string {
+	return "provider " + r.Data.ProviderID + ": " + r.Data.Message
+}
+
+func (r UnknownError) Error() string {
+	return r.Data.Message
+}
+
+func (r MessageAbortedError) Error() string {
+	if r.Data.Message == "" {
+		return "message aborted"
+	}
+	return r.Data.Message
+}
@@ -0,0 +1,300 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/packages/ssestream"
+)
+
+// Budget limits the usage of a session and its child sessions. Zero limits
+// are not enforced.
+type Budget struct {
+	// Cost is the limit in dollars.
+	Cost float64
+	// Tokens is the limit on tokens of every kind.
+	Tokens float64
+	// WarnAt is the fraction of a limit at which to warn, 0.8 if zero.
+	WarnAt float64
+	// Abort aborts the session once it goes over the budget.
+	Abort bool
+}
+
+// used returns how far usage has gone into the budget, as a fraction of
+// the closest limit.
+func (b Budget) used(totals Totals) float64 {
+	used := 0.0
+	if b.Cost > 0 {
+		used = totals.Cost / b.Cost
+	}
+	if b.Tokens > 0 && totals.Tokens.Total()/b.Tokens > used {
+		used = totals.Tokens.Total() / b.Tokens
+	}
+	return used
+}
+
+func (b Budget) warnAt() float64 {
+	if b.WarnAt > 0 {
+		return b.WarnAt
+	}
+	return 0.8
+}
+
+// Alert reports that a session neared or went over its budget.
+type Alert struct {
+	// SessionID is the session the budget was set on.
+	SessionID string
+	Budget    Budget
+	// Usage is the usage of the session and its child sessions.
+	Usage Usage
+	// Exceeded is false for the warning and true once the budget is used up.
+	Exceeded bool
+	// Aborted reports that the session was aborted.
+	Aborted bool
+}
+
+// Budgets enforces budgets on sessions, following their usage from an event
+// stream. Each budget alerts once when its usage reaches [Budget.WarnAt], and
+// once more when it is exceeded. If aborting a session over its budget fails,
+// it is tried again on the next update of its usage, and the exceeded alert
+// is sent again once the session is aborted.
+type Budgets struct {
+	counter *Counter
+	alert   func(Alert)
+	onErr   func(error)
+
+	mu       sync.Mutex
+	budgets  map[string]*budgetState
+	sessions map[string]string
+}
+
+type budgetState struct {
+	budget   Budget
+	messages map[string]MessageUsage
+	warned   bool
+	exceeded bool
+	aborted  bool
+}
+
+func (s *budgetState) usage() Usage {
+	var usage Usage
+	for _, message := range s.messages {
+		usage.Add(message.Model, message.Totals)
+	}
+	return usage
+}
+
+// Budgets returns an empty set of budgets that fetches sessions with the
+// counter and calls alert, on the goroutine applying events, when a session
+// nears or goes over its budget.
+func (c *Counter) Budgets(alert func(Alert)) *Budgets {
+	return &Budgets{
+		counter:  c,
+		alert:    alert,
+		budgets:  map[string]*budgetState{},
+		sessions: map[string]string{},
+	}
+}
+
+// OnError calls fn with the errors [Budgets.Sync] meets counting usage and
+// aborting sessions. Sync keeps enforcing budgets after such an error.
+func (b *Budgets) OnError(fn func(error)) {
+	b.onErr = fn
+}
+
+// Set sets the budget of a session, counting the usage the session and its
+// child sessions already have. An existing budget of the session is
+// replaced; alerts it sent are not sent again.
+func (b *Budgets) Set(ctx context.Context, sessionID string, budget Budget) error {
+	usage, err := b.counter.Session(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	state := &budgetState{budget: budget, messages: map[string]MessageUsage{}}
+	if previous, ok := b.budgets[sessionID]; ok {
+		state.warned, state.exceeded, state.aborted = previous.warned, previous.exceeded, previous.aborted
+	}
+	b.budgets[sessionID] = state
+	b.add(sessionID, usage, state)
+	b.mu.Unlock()
+	return b.check(ctx, sessionID, sessionID)
+}
+
+func (b *Budgets) add(budgetID string, usage *SessionUsage, state *budgetState) {
+	b.sessions[usage.Session.ID] = budgetID
+	for _, message := range usage.Messages {
+		state.messages[message.ID] = message
+	}
+	for _, child := range usage.Children {
+		b.add(budgetID, child, state)
+	}
+}
+
+// Remove removes the budget of a session.
+func (b *Budgets) Remove(sessionID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.budgets, sessionID)
+	for id, budgetID := range b.sessions {
+		if budgetID == sessionID {
+			delete(b.sessions, id)
+		}
+	}
+}
+
+// Usage returns the usage counted against the budget of a session.
+func (b *Budgets) Usage(sessionID string) (Usage, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.budgets[sessionID]
+	if !ok {
+		return Usage{}, false
+	}
+	return state.usage(), true
+}
+
+// Apply counts the usage reported by an event. It aborts sessions that go
+// over a budget with [Budget.Abort] set, and returns the error of aborting.
+func (b *Budgets) Apply(ctx context.Context, event sgptcoder.EventListResponseUnion) error {
+	switch e := event.(type) {
+	case sgptcoder.EventListResponseEventSessionUpdated:
+		// Child sessions count against the budget of their parent
+		b.mu.Lock()
+		if budgetID, ok := b.sessions[e.Properties.Info.ParentID]; ok && e.Properties.Info.ParentID != "" {
+			b.sessions[e.Properties.Info.ID] = budgetID
+		}
+		b.mu.Unlock()
+	case sgptcoder.EventListResponseEventMessageUpdated:
+		assistant, ok := e.Properties.Info.AsUnion().(sgptcoder.AssistantMessage)
+		if !ok {
+			return nil
+		}
+		b.mu.Lock()
+		budgetID, ok := b.sessions[assistant.SessionID]
+		if ok {
+			b.budgets[budgetID].messages[assistant.ID] = b.counter.message(assistant)
+		}
+		b.mu.Unlock()
+		if ok {
+			return b.check(ctx, budgetID, assistant.SessionID)
+		}
+	case sgptcoder.EventListResponseEventMessageRemoved:
+		b.mu.Lock()
+		if budgetID, ok := b.sessions[e.Properties.SessionID]; ok {
+			delete(b.budgets[budgetID].messages, e.Properties.MessageID)
+		}
+		b.mu.Unlock()
+	}
+	return nil
+}
+
+// check alerts about the budget of a session, and aborts the session that
+// used it up along with the session the budget is set on.
+func (b *Budgets) check(ctx context.Context, budgetID string, sessionID string) error {
+	b.mu.Lock()
+	state, ok := b.budgets[budgetID]
+	retry := ok && state.exceeded && state.budget.Abort && !state.aborted
+	if !ok || (state.exceeded && !retry) {
+		b.mu.Unlock()
+		return nil
+	}
+	usage := state.usage()
+	used := state.budget.used(usage.Totals)
+	alert := Alert{SessionID: budgetID, Budget: state.budget, Usage: usage}
+	switch {
+	case retry || used >= 1:
+		state.exceeded = true
+		alert.Exceeded = true
+	case used >= state.budget.warnAt() && !state.warned:
+		state.warned = true
+	default:
+		b.mu.Unlock()
+		return nil
+	}
+	b.mu.Unlock()
+
+	var err error
+	if alert.Exceeded && state.budget.Abort {
+		ids := []string{budgetID}
+		if sessionID != budgetID {
+			ids = append(ids, sessionID)
+		}
+		for _, id := range ids {
+			_, abortErr := b.counter.client.Session.Abort(ctx, id, sgptcoder.SessionAbortParams{Directory: b.counter.query(b.counter.directory)}, b.counter.opts...)
+			err = errors.Join(err, abortErr)
+		}
+		alert.Aborted = err == nil
+		b.mu.Lock()
+		state.aborted = alert.Aborted
+		b.mu.Unlock()
+	}
+	if b.alert != nil && (!retry || alert.Aborted) {
+		b.alert(alert)
+	}
+	return err
+}
+
+// Sync applies the events of a stream until it ends or ctx is done. The
+// usage of every budget is counted again on each server.connected event,
+// so usage reported while disconnected is not missed. Errors counting usage
+// or aborting sessions do not end Sync, see [Budgets.OnError].
+func (b *Budgets) Sync(ctx context.Context, stream sgptcoder.EventStream) error {
+	for stream.Next() {
+		switch e := stream.Current().AsUnion().(type) {
+		case sgptcoder.EventListResponseEventServerConnected:
+			if err := b.reload(ctx); err != nil {
+				b.reportError(err)
+			}
+		case nil:
+		default:
+			if err := b.Apply(ctx, e); err != nil {
+				b.reportError(err)
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func (b *Budgets) reload(ctx context.Context) error {
+	b.mu.Lock()
+	budgets := make(map[string]Budget, len(b.budgets))
+	for id, state := range b.budgets {
+		budgets[id] = state.budget
+	}
+	b.mu.Unlock()
+	// A budget that fails to reload keeps its usage so far
+	var errs error
+	for id, budget := range budgets {
+		err := b.Set(ctx, id, budget)
+		if errors.Is(err, sgptcoder.ErrSessionNotFound) {
+			b.Remove(id)
+			continue
+		}
+		errs = errors.Join(errs, err)
+	}
+	return errs
+}
+
+func (b *Budgets) reportError(err error) {
+	if b.onErr != nil && !errors.Is(err, context.Canceled) {
+		b.onErr(err)
+	}
+}
+
+// Run opens a reconnecting event stream and enforces the budgets until ctx
+// is done or the stream gives up.
+func (b *Budgets) Run(ctx context.Context, reconnect ssestream.ReconnectOptions) error {
+	query := sgptcoder.EventListParams{Directory: b.counter.query(b.counter.directory)}
+	stream := b.counter.client.Event.ListStreamingReconnecting(ctx, query, reconnect, b.counter.opts...)
+	defer stream.Close()
+	return b.Sync(ctx, stream)
+}
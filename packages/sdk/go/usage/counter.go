@@ -0,0 +1,263 @@
+package usage
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/internal/param"
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+)
+
+// Counter fetches the usage of sessions from the API.
+type Counter struct {
+	client    *sgptcoder.Client
+	opts      []option.RequestOption
+	directory string
+	pricing   Pricing
+}
+
+// Option configures a [Counter].
+type Option func(*Counter)
+
+// WithDirectory counts the sessions of the given project directory instead
+// of the server's current one.
+func WithDirectory(directory string) Option {
+	return func(c *Counter) {
+		c.directory = directory
+	}
+}
+
+// WithPricing prices the messages the server recorded no cost for, such as
+// messages of models added to the configuration after they were sent.
+func WithPricing(pricing Pricing) Option {
+	return func(c *Counter) {
+		c.pricing = pricing
+	}
+}
+
+// WithRequestOptions adds request options to every request the counter
+// sends.
+func WithRequestOptions(opts ...option.RequestOption) Option {
+	return func(c *Counter) {
+		c.opts = append(c.opts, opts...)
+	}
+}
+
+// New returns a counter that fetches sessions with the given client.
+func New(client *sgptcoder.Client, opts ...Option) *Counter {
+	c := &Counter{client: client}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// MessageUsage is the usage of one assistant message.
+type MessageUsage struct {
+	ID      string
+	Model   Model
+	Created time.Time
+	Totals
+}
+
+// SessionUsage is the usage of a session and its child sessions.
+type SessionUsage struct {
+	Session sgptcoder.Session
+	// Messages are the assistant messages of the session, oldest first.
+	Messages []MessageUsage
+	// Own adds up the messages of the session, without its children.
+	Own      Usage
+	Children []*SessionUsage
+}
+
+// Total returns the usage of the session and all of its descendants.
+func (s *SessionUsage) Total() Usage {
+	var total Usage
+	total.Merge(s.Own)
+	for _, child := range s.Children {
+		total.Merge(child.Total())
+	}
+	return total
+}
+
+// Session fetches the usage of a session and its child sessions.
+func (c *Counter) Session(ctx context.Context, sessionID string) (*SessionUsage, error) {
+	session, err := c.client.Session.Get(ctx, sessionID, sgptcoder.SessionGetParams{Directory: c.query(c.directory)}, c.opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.session(ctx, *session, c.directory)
+}
+
+func (c *Counter) session(ctx context.Context, session sgptcoder.Session, directory string) (*SessionUsage, error) {
+	messages, err := c.messages(ctx, session.ID, directory)
+	if err != nil {
+		return nil, err
+	}
+	result := &SessionUsage{Session: session, Messages: messages}
+	for _, message := range messages {
+		result.Own.Add(message.Model, message.Totals)
+	}
+
+	children, err := c.client.Session.Children(ctx, session.ID, sgptcoder.SessionChildrenParams{Directory: c.query(directory)}, c.opts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range *children {
+		usage, err := c.session(ctx, child, directory)
+		if errors.Is(err, sgptcoder.ErrSessionNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Children = append(result.Children, usage)
+	}
+	return result, nil
+}
+
+func (c *Counter) messages(ctx context.Context, sessionID string, directory string) ([]MessageUsage, error) {
+	messages, err := c.client.Session.Messages(ctx, sessionID, sgptcoder.SessionMessagesParams{Directory: c.query(directory)}, c.opts...)
+	if err != nil {
+		return nil, err
+	}
+	var result []MessageUsage
+	for _, message := range *messages {
+		if assistant, ok := message.Info.AsUnion().(sgptcoder.AssistantMessage); ok {
+			result = append(result, c.message(assistant))
+		}
+	}
+	return result, nil
+}
+
+func (c *Counter) message(message sgptcoder.AssistantMessage) MessageUsage {
+	model, totals := FromMessage(message)
+	return MessageUsage{
+		ID:      message.ID,
+		Model:   model,
+		Created: time.UnixMilli(int64(message.Time.Created)),
+		Totals:  c.pricing.price(model, totals),
+	}
+}
+
+// query returns the directory parameter of a request, leaving it out for
+// the server's current directory.
+func (c *Counter) query(directory string) param.Field[string] {
+	if directory == "" {
+		return param.Field[string]{}
+	}
+	return sgptcoder.F(directory)
+}
+
+// Report is the usage over a period of time.
+type Report struct {
+	// From and To bound the period, To excluded. Zero values leave it open.
+	From time.Time
+	To   time.Time
+	Usage
+	// Directories breaks the usage down by the directory of the sessions.
+	Directories map[string]Usage
+}
+
+// Report adds up the assistant messages created from from until to, in the
+// sessions of every project the server knows, or only of the counter's
+// directory if it has one. Zero times leave the period open on that side.
+func (c *Counter) Report(ctx context.Context, from, to time.Time) (*Report, error) {
+	directories := []string{c.directory}
+	if c.directory == "" {
+		projects, err := c.client.Project.List(ctx, sgptcoder.ProjectListParams{}, c.opts...)
+		if err != nil {
+			return nil, err
+		}
+		directories = directories[:0]
+		for _, project := range *projects {
+			directories = append(directories, project.Worktree)
+		}
+	}
+
+	report := &Report{From: from, To: to, Directories: map[string]Usage{}}
+	seen := map[string]bool{}
+	for _, directory := range directories {
+		sessions, err := c.client.Session.List(ctx, sgptcoder.SessionListParams{Directory: c.query(directory)}, c.opts...)
+		if err != nil {
+			return nil, err
+		}
+		for _, session := range *sessions {
+			if seen[session.ID] || !report.overlaps(session) {
+				continue
+			}
+			seen[session.ID] = true
+			messages, err := c.messages(ctx, session.ID, directory)
+			if errors.Is(err, sgptcoder.ErrSessionNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			for _, message := range messages {
+				if !report.includes(message.Created) {
+					continue
+				}
+				report.Add(message.Model, message.Totals)
+				usage := report.Directories[session.Directory]
+				usage.Add(message.Model, message.Totals)
+				report.Directories[session.Directory] = usage
+			}
+		}
+	}
+	return report, nil
+}
+
+func (r *Report) includes(t time.Time) bool {
+	return (r.From.IsZero() || !t.Before(r.From)) && (r.To.IsZero() || t.Before(r.To))
+}
+
+// overlaps reports whether a session was active during the period, so
+// sessions outside of it are not fetched.
+func (r *Report) overlaps(session sgptcoder.Session) bool {
+	created := time.UnixMilli(int64(session.Time.Created))
+	updated := time.UnixMilli(int64(session.Time.Updated))
+	return (r.From.IsZero() || !updated.Before(r.From)) && (r.To.IsZero() || created.Before(r.To))
+}
+
+// WriteCSV writes the report with a row per directory and model, for
+// spreadsheets.
+func (r *Report) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"directory", "provider", "model", "messages", "input_tokens", "output_tokens", "reasoning_tokens", "cache_read_tokens", "cache_write_tokens", "cost"})
+	directories := make([]string, 0, len(r.Directories))
+	for directory := range r.Directories {
+		directories = append(directories, directory)
+	}
+	sort.Strings(directories)
+	for _, directory := range directories {
+		usage := r.Directories[directory]
+		for _, model := range usage.SortedModels() {
+			totals := usage.Models[model]
+			out.Write([]string{
+				directory,
+				model.ProviderID,
+				model.ModelID,
+				strconv.Itoa(totals.Messages),
+				formatCount(totals.Tokens.Input),
+				formatCount(totals.Tokens.Output),
+				formatCount(totals.Tokens.Reasoning),
+				formatCount(totals.Tokens.CacheRead),
+				formatCount(totals.Tokens.CacheWrite),
+				strconv.FormatFloat(totals.Cost, 'f', 6, 64),
+			})
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func formatCount(count float64) string {
+	return strconv.FormatFloat(count, 'f', -1, 64)
+}
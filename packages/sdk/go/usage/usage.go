@@ -0,0 +1,177 @@
+// Package usage adds up the tokens and cost of sessions.
+//
+// [FromMessage] and [FromStep] read the usage of single assistant messages
+// and steps. A [Counter] fetches whole sessions, with their child sessions,
+// and builds a [Report] of the spend per directory and model over a date
+// range. [Budgets] follow an event stream to warn about, or abort, sessions
+// that go over a limit.
+package usage
+
+import (
+	"sort"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+)
+
+// Tokens counts tokens by kind.
+type Tokens struct {
+	Input      float64
+	Output     float64
+	Reasoning  float64
+	CacheRead  float64
+	CacheWrite float64
+}
+
+// Add returns the sum of two token counts.
+func (t Tokens) Add(other Tokens) Tokens {
+	return Tokens{
+		Input:      t.Input + other.Input,
+		Output:     t.Output + other.Output,
+		Reasoning:  t.Reasoning + other.Reasoning,
+		CacheRead:  t.CacheRead + other.CacheRead,
+		CacheWrite: t.CacheWrite + other.CacheWrite,
+	}
+}
+
+// Total returns the number of tokens of every kind.
+func (t Tokens) Total() float64 {
+	return t.Input + t.Output + t.Reasoning + t.CacheRead + t.CacheWrite
+}
+
+// Totals is the usage of one or more assistant messages.
+type Totals struct {
+	Tokens Tokens
+	// Cost is in dollars.
+	Cost float64
+	// Messages is the number of assistant messages counted.
+	Messages int
+}
+
+// Add returns the sum of two totals.
+func (t Totals) Add(other Totals) Totals {
+	return Totals{
+		Tokens:   t.Tokens.Add(other.Tokens),
+		Cost:     t.Cost + other.Cost,
+		Messages: t.Messages + other.Messages,
+	}
+}
+
+// Model identifies a model of a provider.
+type Model struct {
+	ProviderID string
+	ModelID    string
+}
+
+func (m Model) String() string {
+	return m.ProviderID + "/" + m.ModelID
+}
+
+// Usage is a total broken down by model.
+type Usage struct {
+	Totals
+	Models map[Model]Totals
+}
+
+// Add counts the totals of a model.
+func (u *Usage) Add(model Model, totals Totals) {
+	if u.Models == nil {
+		u.Models = map[Model]Totals{}
+	}
+	u.Totals = u.Totals.Add(totals)
+	u.Models[model] = u.Models[model].Add(totals)
+}
+
+// Merge counts all of another usage.
+func (u *Usage) Merge(other Usage) {
+	for model, totals := range other.Models {
+		u.Add(model, totals)
+	}
+}
+
+// SortedModels returns the models of the usage, most expensive first.
+func (u Usage) SortedModels() []Model {
+	models := make([]Model, 0, len(u.Models))
+	for model := range u.Models {
+		models = append(models, model)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		a, b := u.Models[models[i]], u.Models[models[j]]
+		if a.Cost != b.Cost {
+			return a.Cost > b.Cost
+		}
+		return models[i].String() < models[j].String()
+	})
+	return models
+}
+
+// FromMessage returns the usage of an assistant message and the model that
+// replied.
+func FromMessage(message sgptcoder.AssistantMessage) (Model, Totals) {
+	tokens := message.Tokens
+	return Model{ProviderID: message.ProviderID, ModelID: message.ModelID}, Totals{
+		Tokens: Tokens{
+			Input:      tokens.Input,
+			Output:     tokens.Output,
+			Reasoning:  tokens.Reasoning,
+			CacheRead:  tokens.Cache.Read,
+			CacheWrite: tokens.Cache.Write,
+		},
+		Cost:     message.Cost,
+		Messages: 1,
+	}
+}
+
+// FromStep returns the usage of one step of an assistant message. The steps
+// of a message add up to the usage of the message.
+func FromStep(step sgptcoder.StepFinishPart) Totals {
+	tokens := step.Tokens
+	return Totals{
+		Tokens: Tokens{
+			Input:      tokens.Input,
+			Output:     tokens.Output,
+			Reasoning:  tokens.Reasoning,
+			CacheRead:  tokens.Cache.Read,
+			CacheWrite: tokens.Cache.Write,
+		},
+		Cost: step.Cost,
+	}
+}
+
+// Pricing holds the price of models in dollars per million tokens, as
+// reported by [sgptcoder.AppService.Providers].
+type Pricing map[Model]sgptcoder.ModelCost
+
+// PricingFromProviders returns the pricing of every model of the providers.
+func PricingFromProviders(providers []sgptcoder.Provider) Pricing {
+	pricing := Pricing{}
+	for _, provider := range providers {
+		for id, model := range provider.Models {
+			pricing[Model{ProviderID: provider.ID, ModelID: id}] = model.Cost
+		}
+	}
+	return pricing
+}
+
+// Cost returns what the tokens cost with a model, the way the server
+// computes it, and false if the model has no price. Reasoning tokens are
+// billed as part of the output.
+func (p Pricing) Cost(model Model, tokens Tokens) (float64, bool) {
+	cost, ok := p[model]
+	if !ok {
+		return 0, false
+	}
+	return (tokens.Input*cost.Input +
+		tokens.Output*cost.Output +
+		tokens.CacheRead*cost.CacheRead +
+		tokens.CacheWrite*cost.CacheWrite) / 1_000_000, true
+}
+
+// price fills in the cost of totals the server recorded no cost for.
+func (p Pricing) price(model Model, totals Totals) Totals {
+	if totals.Cost == 0 && p != nil {
+		if cost, ok := p.Cost(model, totals.Tokens); ok {
+			totals.Cost = cost
+		}
+	}
+	return totals
+}
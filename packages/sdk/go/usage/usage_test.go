@@ -0,0 +1,243 @@
+package usage_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/option"
+	"github.com/skorpland/sgptcoder-sdk-go/sdktest"
+	"github.com/skorpland/sgptcoder-sdk-go/usage"
+)
+
+var model = usage.Model{ProviderID: sdktest.ProviderID, ModelID: sdktest.ModelID}
+
+func prompt(t *testing.T, client *sgptcoder.Client, sessionID string) {
+	t.Helper()
+	params, err := sgptcoder.NewPromptBuilder().Text("hi").Params()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.Session.Prompt(context.Background(), sessionID, params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// sessionWithChild returns a session that cost $0.50 with a child session
+// that cost $0.25.
+func sessionWithChild(t *testing.T, server *sdktest.Server) (string, string) {
+	t.Helper()
+	client := server.Client()
+	parentID := server.AddSession("parent", "")
+	childID := server.AddSession("child", parentID)
+	server.Enqueue(sdktest.Reply(sdktest.Text("a"), sdktest.Usage(100, 20, 0.5)))
+	prompt(t, client, parentID)
+	server.Enqueue(sdktest.Reply(sdktest.Text("b"), sdktest.Usage(10, 5, 0.25)))
+	prompt(t, client, childID)
+	return parentID, childID
+}
+
+func TestCounterSession(t *testing.T) {
+	server := sdktest.NewServer()
+	defer server.Close()
+	parentID, childID := sessionWithChild(t, server)
+
+	session, err := usage.New(server.Client()).Session(context.Background(), parentID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(session.Messages) != 1 || session.Own.Cost != 0.5 || session.Own.Tokens.Input != 100 {
+		t.Errorf("expected the session's own usage, got %+v", session.Own)
+	}
+	if len(session.Children) != 1 || session.Children[0].Session.ID != childID {
+		t.Fatalf("expected the child session, got %+v", session.Children)
+	}
+	total := session.Total()
+	if total.Cost != 0.75 || total.Messages != 2 || total.Tokens.Total() != 135 {
+		t.Errorf("expected the total of both sessions, got %+v", total.Totals)
+	}
+	if models := total.SortedModels(); len(models) != 1 || models[0] != model || total.Models[model].Cost != 0.75 {
+		t.Errorf("expected the usage per model, got %+v", total.Models)
+	}
+}
+
+func TestCounterReport(t *testing.T) {
+	server := sdktest.NewServer()
+	defer server.Close()
+	sessionWithChild(t, server)
+	ctx := context.Background()
+	counter := usage.New(server.Client())
+
+	report, err := counter.Report(ctx, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Cost != 0.75 || report.Directories[sdktest.Worktree].Messages != 2 {
+		t.Errorf("expected the usage of every session, got %+v", report)
+	}
+
+	var out strings.Builder
+	if err := report.WriteCSV(&out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "directory,provider,model,messages,input_tokens,output_tokens,reasoning_tokens,cache_read_tokens,cache_write_tokens,cost\n" +
+		sdktest.Worktree + ",sdktest,fake,2,110,25,0,0,0,0.750000\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+
+	report, err = counter.Report(ctx, time.Time{}, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Messages != 0 || len(report.Directories) != 0 {
+		t.Errorf("expected nothing before the sessions, got %+v", report)
+	}
+}
+
+func TestPricing(t *testing.T) {
+	var provider sgptcoder.Provider
+	if err := provider.UnmarshalJSON([]byte(`{"id":"anthropic","name":"Anthropic","env":[],"models":{"sonnet":{"id":"sonnet","cost":{"input":3,"output":15,"cache_read":0.3,"cache_write":3.75}}}}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pricing := usage.PricingFromProviders([]sgptcoder.Provider{provider})
+	cost, ok := pricing.Cost(usage.Model{ProviderID: "anthropic", ModelID: "sonnet"}, usage.Tokens{
+		Input:      1_000_000,
+		Output:     100_000,
+		Reasoning:  50_000,
+		CacheRead:  1_000_000,
+		CacheWrite: 0,
+	})
+	if !ok || fmt.Sprintf("%.2f", cost) != "4.80" {
+		t.Errorf("expected $4.80, got %v", cost)
+	}
+	if _, ok := pricing.Cost(model, usage.Tokens{Input: 1}); ok {
+		t.Error("expected no price for an unknown model")
+	}
+}
+
+func TestBudgets(t *testing.T) {
+	server := sdktest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	parentID, childID := sessionWithChild(t, server)
+	ctx := context.Background()
+
+	var alerts []usage.Alert
+	budgets := usage.New(client).Budgets(func(alert usage.Alert) {
+		alerts = append(alerts, alert)
+	})
+	if err := budgets.Set(ctx, parentID, usage.Budget{Cost: 1, Abort: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alerts) != 0 {
+		t.Fatalf("expected no alert under budget, got %+v", alerts)
+	}
+
+	// Keep the session busy, so there is something to abort
+	release := make(chan struct{})
+	defer close(release)
+	server.Enqueue(sdktest.Reply(sdktest.Wait(release)))
+	done := make(chan error, 1)
+	go func() {
+		params, _ := sgptcoder.NewPromptBuilder().Text("more").Params()
+		_, err := client.Session.Prompt(ctx, parentID, params)
+		done <- err
+	}()
+	for {
+		messages, err := client.Session.Messages(ctx, parentID, sgptcoder.SessionMessagesParams{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(*messages) == 4 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	apply := func(messageID string, cost float64) {
+		t.Helper()
+		var event sgptcoder.EventListResponse
+		data := fmt.Sprintf(`{"type":"message.updated","properties":{"info":{"id":%q,"sessionID":%q,"role":"assistant","providerID":"sdktest","modelID":"fake","cost":%v,"tokens":{"input":1,"output":1,"reasoning":0,"cache":{"read":0,"write":0}},"time":{"created":1}}}}`, messageID, childID, cost)
+		if err := event.UnmarshalJSON([]byte(data)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := budgets.Apply(ctx, event.AsUnion()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	apply("msg_z1", 0.1)
+	if len(alerts) != 1 || alerts[0].Exceeded || alerts[0].SessionID != parentID {
+		t.Fatalf("expected a warning, got %+v", alerts)
+	}
+	apply("msg_z1", 0.15)
+	if len(alerts) != 1 {
+		t.Fatalf("expected a single warning, got %+v", alerts)
+	}
+	apply("msg_z2", 0.2)
+	if len(alerts) != 2 || !alerts[1].Exceeded || !alerts[1].Aborted {
+		t.Fatalf("expected the budget to be exceeded, got %+v", alerts)
+	}
+	if cost := alerts[1].Usage.Cost; fmt.Sprintf("%.2f", cost) != "1.10" {
+		t.Errorf("expected updates of a message to replace its cost, got %v", cost)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the session to be aborted")
+	}
+}
+
+func TestBudgetsRetryFailedAbort(t *testing.T) {
+	server := sdktest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	parentID, childID := sessionWithChild(t, server)
+	ctx := context.Background()
+
+	var aborts int32
+	failAbort := option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/abort") && atomic.AddInt32(&aborts, 1) == 1 {
+			return nil, errors.New("connection reset")
+		}
+		return next(req)
+	})
+	var alerts []usage.Alert
+	budgets := usage.New(client, usage.WithRequestOptions(failAbort, option.WithMaxRetries(0))).Budgets(func(alert usage.Alert) {
+		alerts = append(alerts, alert)
+	})
+	if err := budgets.Set(ctx, parentID, usage.Budget{Cost: 1, Abort: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	apply := func(messageID string) error {
+		var event sgptcoder.EventListResponse
+		data := fmt.Sprintf(`{"type":"message.updated","properties":{"info":{"id":%q,"sessionID":%q,"role":"assistant","providerID":"sdktest","modelID":"fake","cost":1,"tokens":{"input":1,"output":1,"reasoning":0,"cache":{"read":0,"write":0}},"time":{"created":1}}}}`, messageID, childID)
+		if err := event.UnmarshalJSON([]byte(data)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return budgets.Apply(ctx, event.AsUnion())
+	}
+	if err := apply("msg_z1"); err == nil {
+		t.Fatal("expected the failed abort to be reported")
+	}
+	if len(alerts) != 1 || !alerts[0].Exceeded || alerts[0].Aborted {
+		t.Fatalf("expected an exceeded alert without abort, got %+v", alerts)
+	}
+	if err := apply("msg_z2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alerts) != 2 || !alerts[1].Aborted {
+		t.Fatalf("expected the abort to be retried, got %+v", alerts)
+	}
+	if err := apply("msg_z3"); err != nil || len(alerts) != 2 {
+		t.Fatalf("expected no more alerts once aborted, got %+v, %v", alerts, err)
+	}
+}
@@ -54,6 +54,8 @@ import type {
   SessionShellResponses,
   SessionRevertData,
   SessionRevertResponses,
+  SessionForkData,
+  SessionForkResponses,
   SessionUnrevertData,
   SessionUnrevertResponses,
   PostSessionIdPermissionsPermissionIdData,
@@ -426,6 +428,20 @@ class Session extends _HeyApiClient {
     })
   }
 
+  /**
+   * Create a new session with the messages of a session, up to and including a message
+   */
+  public fork<ThrowOnError extends boolean = false>(options: Options<SessionForkData, ThrowOnError>) {
+    return (options.client ?? this._client).post<SessionForkResponses, unknown, ThrowOnError>({
+      url: "/session/{id}/fork",
+      ...options,
+      headers: {
+        "Content-Type": "application/json",
+        ...options.headers,
+      },
+    })
+  }
+
   /**
    * Restore all reverted messages
    */
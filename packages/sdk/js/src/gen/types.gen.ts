@@ -38,6 +38,14 @@ export type KeybindsConfig = {
    * Create/update AGENTS.md
    */
   project_init?: string
+  /**
+   * Show permission decisions and rules
+   */
+  permission_log?: string
+  /**
+   * Review pending permissions
+   */
+  permission_review?: string
   /**
    * Toggle tool details
    */
@@ -50,6 +58,10 @@ export type KeybindsConfig = {
    * Export session to editor
    */
   session_export?: string
+  /**
+   * Import session from an exported JSON transcript
+   */
+  session_import?: string
   /**
    * Create a new session
    */
@@ -110,6 +122,14 @@ export type KeybindsConfig = {
    * Navigate to last message
    */
   messages_last?: string
+  /**
+   * Move the message cursor up
+   */
+  messages_previous?: string
+  /**
+   * Move the message cursor down
+   */
+  messages_next?: string
   /**
    * Copy message
    */
@@ -122,6 +142,14 @@ export type KeybindsConfig = {
    * Redo message
    */
   messages_redo?: string
+  /**
+   * Find in messages
+   */
+  messages_search?: string
+  /**
+   * Yank a code block of the latest reply
+   */
+  messages_yank?: string
   /**
    * List available models
    */
@@ -179,29 +207,21 @@ export type KeybindsConfig = {
    */
   switch_agent_reverse?: string
   /**
-   * @deprecated Currently not available. List files
+   * Browse project files
    */
   file_list?: string
   /**
-   * @deprecated Close file
+   * Close file viewer
    */
   file_close?: string
   /**
-   * @deprecated Search file
+   * Search project files
    */
   file_search?: string
   /**
-   * @deprecated Split/unified diff
+   * Toggle file content/unified/split diff
    */
   file_diff_toggle?: string
-  /**
-   * @deprecated Navigate to previous message
-   */
-  messages_previous?: string
-  /**
-   * @deprecated Navigate to next message
-   */
-  messages_next?: string
   /**
    * @deprecated Toggle layout
    */
@@ -1576,6 +1596,14 @@ export type SessionMessagesData = {
   }
   query?: {
     directory?: string
+    /**
+     * Return at most this many messages, the most recent ones
+     */
+    limit?: number
+    /**
+     * Only return messages older than this message ID
+     */
+    before?: string
   }
   url: "/session/{id}/message"
 }
@@ -1741,6 +1769,31 @@ export type SessionRevertResponses = {
 
 export type SessionRevertResponse = SessionRevertResponses[keyof SessionRevertResponses]
 
+export type SessionForkData = {
+  body?: {
+    /**
+     * Last message to copy, all of them if omitted
+     */
+    messageID?: string
+  }
+  path: {
+    id: string
+  }
+  query?: {
+    directory?: string
+  }
+  url: "/session/{id}/fork"
+}
+
+export type SessionForkResponses = {
+  /**
+   * Created session
+   */
+  200: Session
+}
+
+export type SessionForkResponse = SessionForkResponses[keyof SessionForkResponses]
+
 export type SessionUnrevertData = {
   body?: never
   path: {
@@ -280,9 +280,12 @@ export namespace Config {
       editor_open: z.string().optional().default("<leader>e").describe("Open external editor"),
       theme_list: z.string().optional().default("<leader>t").describe("List available themes"),
       project_init: z.string().optional().default("<leader>i").describe("Create/update AGENTS.md"),
+      permission_log: z.string().optional().default("none").describe("Show permission decisions and rules"),
+      permission_review: z.string().optional().default("<leader>p").describe("Review pending permissions"),
       tool_details: z.string().optional().default("<leader>d").describe("Toggle tool details"),
       thinking_blocks: z.string().optional().default("<leader>b").describe("Toggle thinking blocks"),
       session_export: z.string().optional().default("<leader>x").describe("Export session to editor"),
+      session_import: z.string().optional().default("none").describe("Import session from an exported JSON transcript"),
       session_new: z.string().optional().default("<leader>n").describe("Create a new session"),
       session_list: z.string().optional().default("<leader>l").describe("List all sessions"),
       session_timeline: z.string().optional().default("<leader>g").describe("Show session timeline"),
@@ -306,9 +309,17 @@ export namespace Config {
         .describe("Scroll messages down by half page"),
       messages_first: z.string().optional().default("ctrl+g").describe("Navigate to first message"),
       messages_last: z.string().optional().default("ctrl+alt+g").describe("Navigate to last message"),
+      messages_previous: z.string().optional().default("ctrl+up").describe("Move the message cursor up"),
+      messages_next: z.string().optional().default("ctrl+down").describe("Move the message cursor down"),
       messages_copy: z.string().optional().default("<leader>y").describe("Copy message"),
       messages_undo: z.string().optional().default("<leader>u").describe("Undo message"),
       messages_redo: z.string().optional().default("<leader>r").describe("Redo message"),
+      messages_search: z.string().optional().default("ctrl+alt+f").describe("Find in messages"),
+      messages_yank: z.string().optional().default("none").describe("Yank a code block of the latest reply"),
+      file_list: z.string().optional().default("<leader>f").describe("Browse project files"),
+      file_search: z.string().optional().default("<leader>/").describe("Search project files"),
+      file_diff_toggle: z.string().optional().default("<leader>v").describe("Toggle file content/unified/split diff"),
+      file_close: z.string().optional().default("esc").describe("Close file viewer"),
       model_list: z.string().optional().default("<leader>m").describe("List available models"),
       model_cycle_recent: z.string().optional().default("f2").describe("Next recent model"),
       model_cycle_recent_reverse: z.string().optional().default("shift+f2").describe("Previous recent model"),
@@ -332,12 +343,6 @@ export namespace Config {
         .optional()
         .default("shift+tab")
         .describe("@deprecated use agent_cycle_reverse. Previous agent"),
-      file_list: z.string().optional().default("none").describe("@deprecated Currently not available. List files"),
-      file_close: z.string().optional().default("none").describe("@deprecated Close file"),
-      file_search: z.string().optional().default("none").describe("@deprecated Search file"),
-      file_diff_toggle: z.string().optional().default("none").describe("@deprecated Split/unified diff"),
-      messages_previous: z.string().optional().default("none").describe("@deprecated Navigate to previous message"),
-      messages_next: z.string().optional().default("none").describe("@deprecated Navigate to next message"),
       messages_layout_toggle: z.string().optional().default("none").describe("@deprecated Toggle layout"),
       messages_revert: z.string().optional().default("none").describe("@deprecated use messages_undo. Revert message"),
     })
@@ -9,6 +9,7 @@ import z from "zod/v4"
 import { Provider } from "../provider/provider"
 import { mapValues } from "remeda"
 import { NamedError } from "../util/error"
+import { Storage } from "../storage/storage"
 import { ModelsDev } from "../provider/models"
 import { Ripgrep } from "../file/ripgrep"
 import { Config } from "../config/config"
@@ -29,6 +30,9 @@ import { SessionPrompt } from "../session/prompt"
 import { SessionCompaction } from "../session/compaction"
 import { SessionRevert } from "../session/revert"
 import { lazy } from "../util/lazy"
+import { Installation } from "../installation"
+import fs from "fs"
+import path from "path"
 
 const ERRORS = {
   400: {
@@ -86,6 +90,19 @@ export namespace Server {
         log.error("failed", {
           error: err,
         })
+        if (err instanceof Storage.NotFoundError) {
+          return c.json(err.toObject(), {
+            status: 404,
+          })
+        }
+        if (err instanceof Session.BusyError) {
+          return c.json(
+            { name: "BusyError", data: { message: err.message } },
+            {
+              status: 409,
+            },
+          )
+        }
         if (err instanceof NamedError) {
           return c.json(err.toObject(), {
             status: 400,
@@ -111,6 +128,10 @@ export namespace Server {
           })
         }
       })
+      .use(async (c, next) => {
+        c.header("x-sgptcoder-version", Installation.VERSION)
+        await next()
+      })
       .use(async (c, next) => {
         const directory = c.req.query("directory") ?? process.cwd()
         return Instance.provide(directory, async () => {
@@ -639,8 +660,21 @@ export namespace Server {
             id: z.string().meta({ description: "Session ID" }),
           }),
         ),
+        validator(
+          "query",
+          z.object({
+            limit: z.coerce
+              .number()
+              .int()
+              .positive()
+              .optional()
+              .meta({ description: "Return at most this many messages, the most recent ones" }),
+            before: z.string().optional().meta({ description: "Only return messages older than this message ID" }),
+          }),
+        ),
         async (c) => {
-          const messages = await Session.messages(c.req.valid("param").id)
+          const query = c.req.valid("query")
+          const messages = await Session.messages(c.req.valid("param").id, query)
           return c.json(messages)
         },
       )
@@ -808,6 +842,41 @@ export namespace Server {
           return c.json(session)
         },
       )
+      .post(
+        "/session/:id/fork",
+        describeRoute({
+          description: "Create a new session with the messages of a session, up to and including a message",
+          operationId: "session.fork",
+          responses: {
+            200: {
+              description: "Created session",
+              content: {
+                "application/json": {
+                  schema: resolver(Session.Info),
+                },
+              },
+            },
+          },
+        }),
+        validator(
+          "param",
+          z.object({
+            id: z.string(),
+          }),
+        ),
+        validator(
+          "json",
+          z.object({
+            messageID: z.string().optional().meta({ description: "Last message to copy, all of them if omitted" }),
+          }),
+        ),
+        async (c) => {
+          const id = c.req.valid("param").id
+          const body = c.req.valid("json")
+          const session = await Session.fork(id, body.messageID)
+          return c.json(session)
+        },
+      )
       .post(
         "/session/:id/unrevert",
         describeRoute({
@@ -1438,6 +1507,32 @@ export namespace Server {
       idleTimeout: 0,
       fetch: App().fetch,
     })
+    announce(server.url)
     return server
   }
+
+  // Running servers are listed in the state directory so that clients, such
+  // as editor plugins, can find them. The SDKs probe each entry, so files left
+  // behind by a crash are harmless.
+  function announce(url: URL) {
+    const dir = path.join(Global.Path.state, "server")
+    const file = path.join(dir, `${process.pid}-${url.port}.json`)
+    try {
+      fs.mkdirSync(dir, { recursive: true })
+      fs.writeFileSync(
+        file,
+        JSON.stringify({
+          url: url.toString(),
+          pid: process.pid,
+          version: Installation.VERSION,
+          directory: process.cwd(),
+          time: Date.now(),
+        }),
+      )
+    } catch (error) {
+      log.error("failed to announce server", { error })
+      return
+    }
+    process.on("exit", () => fs.rmSync(file, { force: true }))
+  }
 }
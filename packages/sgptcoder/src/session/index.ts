@@ -139,7 +139,12 @@ export namespace Session {
   }
 
   export async function get(id: string) {
-    const read = await Storage.read<Info>(["session", Instance.project.id, id])
+    const read = await Storage.read<Info>(["session", Instance.project.id, id]).catch((e) => {
+      if (Storage.NotFoundError.isInstance(e)) {
+        throw new Storage.NotFoundError({ message: `Session not found: ${id}`, sessionID: id }, { cause: e })
+      }
+      throw e
+    })
     return read as Info
   }
 
@@ -194,9 +199,15 @@ export namespace Session {
     return result
   }
 
-  export async function messages(sessionID: string) {
+  export async function messages(sessionID: string, options?: { limit?: number; before?: string }) {
     const result = [] as MessageV2.WithParts[]
-    for (const p of await Storage.list(["message", sessionID])) {
+    let keys = await Storage.list(["message", sessionID])
+    if (options?.before) {
+      const before = options.before
+      keys = keys.filter((key) => key[key.length - 1] < before)
+    }
+    if (options?.limit) keys = keys.slice(-options.limit)
+    for (const p of keys) {
       const read = await Storage.read<MessageV2.Info>(p)
       result.push({
         info: read,
@@ -207,6 +218,36 @@ export namespace Session {
     return result
   }
 
+  export async function fork(sessionID: string, messageID?: string) {
+    const session = await get(sessionID)
+    const msgs = await messages(sessionID)
+    if (messageID && !msgs.some((msg) => msg.info.id === messageID)) {
+      throw new Storage.NotFoundError({ message: `Message not found: ${messageID}` })
+    }
+    const result = await createNext({
+      directory: session.directory,
+      title: session.title + " (fork)",
+    })
+    for (const msg of msgs) {
+      if (messageID && msg.info.id > messageID) break
+      const info = {
+        ...msg.info,
+        id: Identifier.ascending("message"),
+        sessionID: result.id,
+      }
+      await updateMessage(info)
+      for (const part of msg.parts) {
+        await updatePart({
+          ...part,
+          id: Identifier.ascending("part"),
+          messageID: info.id,
+          sessionID: result.id,
+        })
+      }
+    }
+    return result
+  }
+
   export async function getMessage(sessionID: string, messageID: string) {
     return {
       info: await Storage.read<MessageV2.Info>(["message", sessionID, messageID]),
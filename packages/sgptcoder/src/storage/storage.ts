@@ -5,12 +5,33 @@ import { Global } from "../global"
 import { lazy } from "../util/lazy"
 import { Lock } from "../util/lock"
 import { $ } from "bun"
+import z from "zod/v4"
+import { NamedError } from "../util/error"
 
 export namespace Storage {
   const log = Log.create({ service: "storage" })
 
   type Migration = (dir: string) => Promise<void>
 
+  export const NotFoundError = NamedError.create(
+    "NotFoundError",
+    z.object({
+      message: z.string(),
+      // Set when the missing resource is a session
+      sessionID: z.string().optional(),
+    }),
+  )
+
+  // Missing files are reported as NotFoundError so the server can answer 404
+  function notFound(key: string[]) {
+    return (e: any): never => {
+      if (e?.code === "ENOENT") {
+        throw new NotFoundError({ message: `Resource not found: ${key.join("/")}` }, { cause: e })
+      }
+      throw e
+    }
+  }
+
   const MIGRATIONS: Migration[] = [
     async (dir) => {
       const project = path.resolve(dir, "../project")
@@ -135,14 +156,14 @@ export namespace Storage {
     const dir = await state().then((x) => x.dir)
     const target = path.join(dir, ...key) + ".json"
     using _ = await Lock.read(target)
-    return Bun.file(target).json() as Promise<T>
+    return (await Bun.file(target).json().catch(notFound(key))) as T
   }
 
   export async function update<T>(key: string[], fn: (draft: T) => void) {
     const dir = await state().then((x) => x.dir)
     const target = path.join(dir, ...key) + ".json"
     using _ = await Lock.write("storage")
-    const content = await Bun.file(target).json()
+    const content = await Bun.file(target).json().catch(notFound(key))
     fn(content)
     await Bun.write(target, JSON.stringify(content, null, 2))
     return content as T
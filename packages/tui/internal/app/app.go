@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"slices"
 	"strings"
@@ -13,42 +14,46 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/mirror"
 	"github.com/skorpland/sgptcoder/internal/clipboard"
 	"github.com/skorpland/sgptcoder/internal/commands"
 	"github.com/skorpland/sgptcoder/internal/components/toast"
 	"github.com/skorpland/sgptcoder/internal/id"
+	"github.com/skorpland/sgptcoder/internal/permission"
 	"github.com/skorpland/sgptcoder/internal/styles"
 	"github.com/skorpland/sgptcoder/internal/theme"
 	"github.com/skorpland/sgptcoder/internal/util"
 )
 
-type Message struct {
-	Info  sgptcoder.MessageUnion
-	Parts []sgptcoder.PartUnion
-}
+type Message = mirror.Message
 
 type App struct {
-	Project           sgptcoder.Project
-	Agents            []sgptcoder.Agent
-	Providers         []sgptcoder.Provider
-	Version           string
-	StatePath         string
-	Config            *sgptcoder.Config
-	Client            *sgptcoder.Client
-	State             *State
-	AgentIndex        int
-	Provider          *sgptcoder.Provider
-	Model             *sgptcoder.Model
-	Session           *sgptcoder.Session
-	Messages          []Message
+	Project    sgptcoder.Project
+	Agents     []sgptcoder.Agent
+	Providers  []sgptcoder.Provider
+	Version    string
+	StatePath  string
+	Config     *sgptcoder.Config
+	Client     *sgptcoder.Client
+	State      *State
+	AgentIndex int
+	Provider   *sgptcoder.Provider
+	Model      *sgptcoder.Model
+	Session    *sgptcoder.Session
+	Messages   []Message
+	// HasOlderMessages reports that the session has messages older than the
+	// ones loaded, see [App.LoadOlderMessages].
+	HasOlderMessages  bool
 	Permissions       []sgptcoder.Permission
 	CurrentPermission sgptcoder.Permission
+	PermissionLog     *permission.Log
 	Commands          commands.CommandRegistry
 	InitialModel      *string
 	InitialPrompt     *string
 	InitialAgent      *string
 	InitialSession    *string
 	compactCancel     context.CancelFunc
+	replay            []Prompt
 	IsLeaderSequence  bool
 	IsBashMode        bool
 	ScrollSpeed       int
@@ -70,6 +75,12 @@ type SessionUnrevertedMsg struct {
 	Session sgptcoder.Session
 }
 type SessionLoadedMsg struct{}
+type OlderMessagesLoadedMsg struct {
+	SessionID string
+	Messages  []Message
+	More      bool
+	Err       error
+}
 type ModelSelectedMsg struct {
 	Provider sgptcoder.Provider
 	Model    sgptcoder.Model
@@ -205,6 +216,7 @@ func New(
 		AgentIndex:     agentIndex,
 		Session:        &sgptcoder.Session{},
 		Messages:       []Message{},
+		PermissionLog:  permission.NewLog(),
 		Commands:       commands.LoadFromConfig(configInfo, *customCommands),
 		InitialModel:   initialModel,
 		InitialPrompt:  initialPrompt,
@@ -804,7 +816,7 @@ func (a *App) SendPrompt(ctx context.Context, prompt Prompt) (*App, tea.Cmd) {
 			}),
 			Agent:     sgptcoder.F(a.Agent().Name),
 			MessageID: sgptcoder.F(messageID),
-			Parts:     sgptcoder.F(message.ToSessionChatParams()),
+			Parts:     sgptcoder.F(promptParts(message)),
 		})
 		if err != nil {
 			errormsg := fmt.Sprintf("failed to send message: %v", err)
@@ -894,6 +906,8 @@ func (a *App) Cancel(ctx context.Context, sessionID string) error {
 		a.compactCancel()
 		a.compactCancel = nil
 	}
+	// Interrupting a replayed import stops it
+	a.StopReplay()
 
 	_, err := a.Client.Session.Abort(ctx, sessionID, sgptcoder.SessionAbortParams{})
 	if err != nil {
@@ -924,6 +938,23 @@ func (a *App) DeleteSession(ctx context.Context, sessionID string) error {
 	return nil
 }
 
+// ForkSession creates a new session with the messages of a session up to and
+// including the given message.
+func (a *App) ForkSession(
+	ctx context.Context,
+	sessionID string,
+	messageID string,
+) (*sgptcoder.Session, error) {
+	session, err := a.Client.Session.Fork(ctx, sessionID, sgptcoder.SessionForkParams{
+		MessageID: sgptcoder.F(messageID),
+	})
+	if err != nil {
+		slog.Error("Failed to fork session", "error", err)
+		return nil, err
+	}
+	return session, nil
+}
+
 func (a *App) UpdateSession(ctx context.Context, sessionID string, title string) error {
 	_, err := a.Client.Session.Update(ctx, sessionID, sgptcoder.SessionUpdateParams{
 		Title: sgptcoder.F(title),
@@ -935,16 +966,42 @@ func (a *App) UpdateSession(ctx context.Context, sessionID string, title string)
 	return nil
 }
 
+// MessagePageSize is the number of messages loaded at a time when a session
+// is opened, and each time the conversation is scrolled up to the oldest
+// loaded message.
+const MessagePageSize = 100
+
 func (a *App) ListMessages(ctx context.Context, sessionId string) ([]Message, error) {
-	response, err := a.Client.Session.Messages(ctx, sessionId, sgptcoder.SessionMessagesParams{})
+	messages, _, err := a.ListMessagesPage(ctx, sessionId, "", 0)
+	return messages, err
+}
+
+// ListMessagesPage lists up to limit of the most recent messages older than
+// the before message, or all of them if limit is zero, and reports whether
+// there are older messages still.
+func (a *App) ListMessagesPage(ctx context.Context, sessionId string, before string, limit int) ([]Message, bool, error) {
+	params := sgptcoder.SessionMessagesParams{}
+	if before != "" {
+		params.Before = sgptcoder.F(before)
+	}
+	if limit > 0 {
+		// One more tells whether there are older messages
+		params.Limit = sgptcoder.F(int64(limit + 1))
+	}
+	response, err := a.Client.Session.Messages(ctx, sessionId, params)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	if response == nil {
-		return []Message{}, nil
+		return []Message{}, false, nil
+	}
+	page := *response
+	more := limit > 0 && len(page) > limit
+	if more {
+		page = page[len(page)-limit:]
 	}
 	messages := []Message{}
-	for _, message := range *response {
+	for _, message := range page {
 		msg := Message{
 			Info:  message.Info.AsUnion(),
 			Parts: []sgptcoder.PartUnion{},
@@ -954,7 +1011,32 @@ func (a *App) ListMessages(ctx context.Context, sessionId string) ([]Message, er
 		}
 		messages = append(messages, msg)
 	}
-	return messages, nil
+	return messages, more, nil
+}
+
+// LoadOlderMessages loads the page of messages before the oldest loaded one.
+func (a *App) LoadOlderMessages() tea.Cmd {
+	return a.loadOlderMessages(MessagePageSize)
+}
+
+// LoadAllOlderMessages loads every message before the oldest loaded one.
+func (a *App) LoadAllOlderMessages() tea.Cmd {
+	return a.loadOlderMessages(0)
+}
+
+func (a *App) loadOlderMessages(limit int) tea.Cmd {
+	if !a.HasOlderMessages || len(a.Messages) == 0 {
+		return nil
+	}
+	sessionID := a.Session.ID
+	before := mirror.MessageID(a.Messages[0].Info)
+	return func() tea.Msg {
+		messages, more, err := a.ListMessagesPage(context.Background(), sessionID, before, limit)
+		if err != nil {
+			slog.Error("Failed to load older messages", "error", err)
+		}
+		return OlderMessagesLoadedMsg{SessionID: sessionID, Messages: messages, More: more, Err: err}
+	}
 }
 
 func (a *App) ListProviders(ctx context.Context) ([]sgptcoder.Provider, error) {
@@ -973,3 +1055,39 @@ func (a *App) ListProviders(ctx context.Context) ([]sgptcoder.Provider, error) {
 // func (a *App) loadCustomKeybinds() {
 //
 // }
+
+// OpenInEditor writes content to a temporary file named after pattern, as in
+// os.CreateTemp, and opens it in $EDITOR. The file is removed once the editor
+// closes.
+func OpenInEditor(content string, pattern string) tea.Cmd {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		return toast.NewErrorToast("No EDITOR set, can't open editor")
+	}
+
+	tmpfile, err := os.CreateTemp("", pattern)
+	if err != nil {
+		slog.Error("Failed to create temp file", "error", err)
+		return toast.NewErrorToast("Failed to create temporary file.")
+	}
+	_, err = tmpfile.WriteString(content)
+	tmpfile.Close()
+	if err != nil {
+		slog.Error("Failed to write to temp file", "error", err)
+		os.Remove(tmpfile.Name())
+		return toast.NewErrorToast("Failed to write temporary file.")
+	}
+
+	parts := strings.Fields(editor)
+	c := exec.Command(parts[0], append(parts[1:], tmpfile.Name())...) //nolint:gosec
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		if err != nil {
+			slog.Error("Failed to open editor", "error", err)
+		}
+		os.Remove(tmpfile.Name())
+		return nil
+	})
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"slices"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/mirror"
+)
+
+func (a *App) messageIndex(id string) int {
+	return mirror.Index(a.Messages, id)
+}
+
+// UpdateMessage applies a message.updated event to the current session.
+func (a *App) UpdateMessage(info sgptcoder.Message) {
+	if info.SessionID != a.Session.ID {
+		return
+	}
+	a.Messages = mirror.UpdateMessage(a.Messages, info)
+}
+
+// RemoveMessage applies a message.removed event to the current session.
+func (a *App) RemoveMessage(sessionID, id string) {
+	if sessionID != a.Session.ID {
+		return
+	}
+	a.Messages = mirror.RemoveMessage(a.Messages, id)
+}
+
+// UpdatePart applies a message.part.updated event to the current session.
+func (a *App) UpdatePart(part sgptcoder.Part) {
+	if part.SessionID != a.Session.ID {
+		return
+	}
+	a.Messages = mirror.UpdatePart(a.Messages, part)
+}
+
+// RemovePart applies a message.part.removed event to the current session.
+func (a *App) RemovePart(sessionID, messageID, id string) {
+	if sessionID != a.Session.ID {
+		return
+	}
+	a.Messages = mirror.RemovePart(a.Messages, messageID, id)
+}
+
+// AddPermission queues a permission request from a permission.updated event.
+func (a *App) AddPermission(permission sgptcoder.Permission) {
+	a.Permissions = append(a.Permissions, permission)
+	a.CurrentPermission = a.Permissions[0]
+}
+
+// RemovePermission drops a permission request once it has been replied to.
+func (a *App) RemovePermission(permissionID string) {
+	index := slices.IndexFunc(a.Permissions, func(p sgptcoder.Permission) bool {
+		return p.ID == permissionID
+	})
+	if index > -1 {
+		a.Permissions = append(a.Permissions[:index], a.Permissions[index+1:]...)
+	}
+	if a.CurrentPermission.ID == permissionID {
+		if len(a.Permissions) > 0 {
+			a.CurrentPermission = a.Permissions[0]
+		} else {
+			a.CurrentPermission = sgptcoder.Permission{}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder/internal/attachment"
+	"github.com/skorpland/sgptcoder/internal/components/toast"
+	"github.com/skorpland/sgptcoder/internal/util"
+)
+
+// ImportMode selects how an imported transcript is brought into a new session.
+type ImportMode int
+
+const (
+	// ImportRehydrate shows the imported conversation in a new session without
+	// sending anything. The model does not see the imported messages.
+	ImportRehydrate ImportMode = iota
+	// ImportReplay re-submits the user prompts of the transcript into a new
+	// session one at a time, waiting for each reply before sending the next.
+	ImportReplay
+)
+
+// ImportSession starts a new session from the messages of an exported
+// session. File and symbol attachments that pointed into the original
+// project directory are moved to the current one.
+func (a *App) ImportSession(
+	ctx context.Context,
+	source sgptcoder.Session,
+	messages []Message,
+	mode ImportMode,
+) (*App, tea.Cmd) {
+	var prompts []Prompt
+	if mode == ImportReplay {
+		for _, message := range messages {
+			if _, ok := message.Info.(sgptcoder.UserMessage); !ok {
+				continue
+			}
+			prompt, err := PromptFromMessage(message)
+			if err != nil {
+				continue
+			}
+			prompt.Text = strings.TrimRight(prompt.Text, " ")
+			for _, att := range prompt.Attachments {
+				rebaseAttachment(att, source.Directory, util.CwdPath)
+			}
+			if prompt.Text == "" && len(prompt.Attachments) == 0 {
+				continue
+			}
+			prompts = append(prompts, *prompt)
+		}
+		if len(prompts) == 0 {
+			return a, toast.NewInfoToast("No prompts to replay.")
+		}
+	}
+
+	params := sgptcoder.SessionNewParams{}
+	if source.Title != "" {
+		params.Title = sgptcoder.F("Imported: " + source.Title)
+	}
+	session, err := a.Client.Session.New(ctx, params)
+	if err != nil {
+		return a, toast.NewErrorToast(fmt.Sprintf("Failed to create session: %v", err))
+	}
+
+	a.Session = session
+	a.Messages = []Message{}
+	a.HasOlderMessages = false
+	a.StopReplay()
+	if mode == ImportRehydrate {
+		a.Messages = messages
+	}
+	cmds := []tea.Cmd{
+		util.CmdHandler(SessionCreatedMsg{Session: session}),
+		util.CmdHandler(SessionLoadedMsg{}),
+	}
+
+	if mode == ImportReplay {
+		a.replay = prompts
+		var cmd tea.Cmd
+		a, cmd = a.ReplayNext(ctx)
+		return a, tea.Sequence(tea.Batch(cmds...), cmd)
+	}
+	cmds = append(cmds, toast.NewSuccessToast(fmt.Sprintf("Imported %d messages", len(messages))))
+	return a, tea.Batch(cmds...)
+}
+
+// IsReplaying reports whether imported prompts are still waiting to be sent.
+func (a *App) IsReplaying() bool {
+	return len(a.replay) > 0
+}
+
+// ReplayNext sends the next imported prompt. It is called when the session
+// goes idle after the previous reply.
+func (a *App) ReplayNext(ctx context.Context) (*App, tea.Cmd) {
+	if len(a.replay) == 0 {
+		return a, nil
+	}
+	prompt := a.replay[0]
+	a.replay = a.replay[1:]
+	remaining := len(a.replay)
+
+	var cmd tea.Cmd
+	a, cmd = a.SendPrompt(ctx, prompt)
+	if remaining == 0 {
+		return a, cmd
+	}
+	return a, tea.Batch(cmd, toast.NewInfoToast(fmt.Sprintf("Replaying, %d prompts left", remaining)))
+}
+
+// StopReplay drops the imported prompts that have not been sent yet, such as
+// when the session is interrupted or another one is opened.
+func (a *App) StopReplay() {
+	a.replay = nil
+}
+
+// rebaseAttachment points a file or symbol attachment from the original
+// project directory at the same path in the current one.
+func rebaseAttachment(att *attachment.Attachment, from, to string) {
+	if from == "" || to == "" || from == to {
+		return
+	}
+	var path *string
+	switch source := att.Source.(type) {
+	case *attachment.FileSource:
+		path = &source.Path
+	case *attachment.SymbolSource:
+		path = &source.Path
+	default:
+		return
+	}
+	relative, err := filepath.Rel(from, *path)
+	if err != nil || strings.HasPrefix(relative, "..") {
+		return
+	}
+	rebased := filepath.Join(to, relative)
+	// Symbol URLs carry the range as a query after the path.
+	att.URL = strings.Replace(att.URL, "file://"+*path, "file://"+rebased, 1)
+	*path = rebased
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/skorpland/sgptcoder/internal/attachment"
+)
+
+func TestRebaseAttachment(t *testing.T) {
+	tests := []struct {
+		name         string
+		attachment   *attachment.Attachment
+		expectedPath string
+		expectedURL  string
+	}{
+		{
+			name: "file inside the project",
+			attachment: &attachment.Attachment{
+				Type:   "file",
+				URL:    "file:///home/me/project/src/main.go",
+				Source: &attachment.FileSource{Path: "/home/me/project/src/main.go"},
+			},
+			expectedPath: "/work/project/src/main.go",
+			expectedURL:  "file:///work/project/src/main.go",
+		},
+		{
+			name: "symbol keeps its range query",
+			attachment: &attachment.Attachment{
+				Type:   "symbol",
+				URL:    "file:///home/me/project/main.go?start=1&end=2",
+				Source: &attachment.SymbolSource{Path: "/home/me/project/main.go"},
+			},
+			expectedPath: "/work/project/main.go",
+			expectedURL:  "file:///work/project/main.go?start=1&end=2",
+		},
+		{
+			name: "file outside the project",
+			attachment: &attachment.Attachment{
+				Type:   "file",
+				URL:    "file:///etc/hosts",
+				Source: &attachment.FileSource{Path: "/etc/hosts"},
+			},
+			expectedPath: "/etc/hosts",
+			expectedURL:  "file:///etc/hosts",
+		},
+		{
+			name: "data url",
+			attachment: &attachment.Attachment{
+				Type:   "file",
+				URL:    "data:image/png;base64,AAAA",
+				Source: &attachment.FileSource{Path: "/home/me/project/image.png"},
+			},
+			expectedPath: "/work/project/image.png",
+			expectedURL:  "data:image/png;base64,AAAA",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rebaseAttachment(tt.attachment, "/home/me/project", "/work/project")
+
+			var path string
+			switch source := tt.attachment.Source.(type) {
+			case *attachment.FileSource:
+				path = source.Path
+			case *attachment.SymbolSource:
+				path = source.Path
+			}
+			if path != tt.expectedPath {
+				t.Errorf("path = %q, want %q", path, tt.expectedPath)
+			}
+			if tt.attachment.URL != tt.expectedURL {
+				t.Errorf("URL = %q, want %q", tt.attachment.URL, tt.expectedURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder/internal/components/toast"
+	"github.com/skorpland/sgptcoder/internal/permission"
+)
+
+// PermissionRequest returns a permission with the context permission rules
+// match against.
+func (a *App) PermissionRequest(p sgptcoder.Permission) permission.Request {
+	request := permission.Request{Permission: p, Root: a.Project.Worktree}
+	if index := a.messageIndex(p.MessageID); index > -1 {
+		if info, ok := a.Messages[index].Info.(sgptcoder.AssistantMessage); ok {
+			request.Agent = info.Mode
+		}
+	}
+	if request.Agent == "" && p.SessionID == a.Session.ID {
+		request.Agent = a.Agent().Name
+	}
+	return request
+}
+
+// AutoRespond answers the permission with the first matching permission rule.
+// It reports false when no rule matches and the user has to decide.
+func (a *App) AutoRespond(p sgptcoder.Permission) (tea.Cmd, bool) {
+	request := a.PermissionRequest(p)
+	rule, ok := permission.Rules(a.State.PermissionRules).Match(request)
+	if !ok {
+		return nil, false
+	}
+	response, _ := permission.ParseResponse(rule.Response)
+	slog.Debug("Permission answered by rule", "permission", p.ID, "rule", rule.String())
+	cmd := a.respond(request, response, &rule)
+	if response == sgptcoder.SessionPermissionRespondParamsResponseReject {
+		// Say why the tool failed; approvals stay quiet.
+		cmd = tea.Batch(cmd, toast.NewInfoToast("Rejected by rule: "+p.Title))
+	}
+	return cmd, true
+}
+
+// RespondToPermission answers a permission the user decided on and takes it
+// off the queue.
+func (a *App) RespondToPermission(
+	p sgptcoder.Permission,
+	response sgptcoder.SessionPermissionRespondParamsResponse,
+) tea.Cmd {
+	a.RemovePermission(p.ID)
+	return a.respond(a.PermissionRequest(p), response, nil)
+}
+
+func (a *App) respond(
+	request permission.Request,
+	response sgptcoder.SessionPermissionRespondParamsResponse,
+	rule *permission.Rule,
+) tea.Cmd {
+	if a.PermissionLog != nil {
+		a.PermissionLog.Add(permission.Decision{
+			Request:  request,
+			Response: response,
+			Rule:     rule,
+		})
+	}
+	p := request.Permission
+	return func() tea.Msg {
+		resp, err := a.Client.Session.Permissions.Respond(
+			context.Background(),
+			p.SessionID,
+			p.ID,
+			sgptcoder.SessionPermissionRespondParams{Response: sgptcoder.F(response)},
+		)
+		if err != nil {
+			slog.Error("Failed to respond to permission request", "error", err)
+			return toast.NewErrorToast("Failed to respond to permission request")()
+		}
+		slog.Debug("Responded to permission request", "response", resp)
+		return nil
+	}
+}
+
+// AddPermissionRule saves a permission rule. Later requests it matches are
+// answered without asking.
+func (a *App) AddPermissionRule(rule permission.Rule) tea.Cmd {
+	if err := rule.Validate(); err != nil {
+		return toast.NewErrorToast(err.Error())
+	}
+	a.State.PermissionRules = append(a.State.PermissionRules, rule)
+	return tea.Batch(
+		a.SaveState(),
+		toast.NewSuccessToast(fmt.Sprintf("Added rule: %s", rule)),
+	)
+}
+
+// RemovePermissionRule deletes the permission rule at index.
+func (a *App) RemovePermissionRule(index int) tea.Cmd {
+	if index < 0 || index >= len(a.State.PermissionRules) {
+		return nil
+	}
+	a.State.PermissionRules = append(
+		a.State.PermissionRules[:index],
+		a.State.PermissionRules[index+1:]...,
+	)
+	return a.SaveState()
+}
+
+// PermissionSessions returns the current session and the sessions it
+// started, such as task subagents, mapping each ID to the session title.
+func (a *App) PermissionSessions(ctx context.Context) map[string]string {
+	sessions := map[string]string{}
+	if a.Session.ID == "" {
+		return sessions
+	}
+	sessions[a.Session.ID] = a.Session.Title
+
+	// Only walk the session tree when something else is waiting
+	walk := slices.ContainsFunc(a.Permissions, func(p sgptcoder.Permission) bool {
+		return p.SessionID != a.Session.ID
+	})
+	queue := []string{a.Session.ID}
+	for walk && len(queue) > 0 {
+		parentID := queue[0]
+		queue = queue[1:]
+		children, err := a.Client.Session.Children(ctx, parentID, sgptcoder.SessionChildrenParams{})
+		if err != nil {
+			slog.Error("Failed to get session children", "session", parentID, "error", err)
+			continue
+		}
+		for _, child := range *children {
+			if _, ok := sessions[child.ID]; ok {
+				continue
+			}
+			sessions[child.ID] = child.Title
+			queue = append(queue, child.ID)
+		}
+	}
+	return sessions
+}
+
+// PendingPermissions returns the queued permissions of the given sessions,
+// oldest first.
+func (a *App) PendingPermissions(sessions map[string]string) []sgptcoder.Permission {
+	var permissions []sgptcoder.Permission
+	for _, p := range a.Permissions {
+		if _, ok := sessions[p.SessionID]; ok {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/sdktest"
+)
+
+func TestPendingPermissionsIncludesChildSessions(t *testing.T) {
+	server := sdktest.NewServer()
+	defer server.Close()
+
+	parentID := server.AddSession("parent", "")
+	childID := server.AddSession("child", parentID)
+	grandchildID := server.AddSession("grandchild", childID)
+	otherID := server.AddSession("other", "")
+
+	a := &App{
+		Client:  server.Client(),
+		Session: &sgptcoder.Session{ID: parentID, Title: "parent"},
+		Permissions: []sgptcoder.Permission{
+			{ID: "per_1", SessionID: grandchildID},
+			{ID: "per_2", SessionID: otherID},
+			{ID: "per_3", SessionID: parentID},
+		},
+	}
+
+	sessions := a.PermissionSessions(context.Background())
+	if len(sessions) != 3 || sessions[grandchildID] != "grandchild" {
+		t.Fatalf("expected the session tree, got %v", sessions)
+	}
+	pending := a.PendingPermissions(sessions)
+	if len(pending) != 2 || pending[0].ID != "per_1" || pending[1].ID != "per_3" {
+		t.Errorf("expected the permissions of the session tree, got %+v", pending)
+	}
+}
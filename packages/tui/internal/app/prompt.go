@@ -129,7 +129,9 @@ func (p Prompt) ToMessage(
 	}
 }
 
-func (m Message) ToPrompt() (*Prompt, error) {
+// PromptFromMessage rebuilds the prompt that produced a user message, with
+// its attachments, so it can be edited or sent again.
+func PromptFromMessage(m Message) (*Prompt, error) {
 	switch m.Info.(type) {
 	case sgptcoder.UserMessage:
 		text := ""
@@ -207,7 +209,7 @@ func (m Message) ToPrompt() (*Prompt, error) {
 	return nil, errors.New("unknown message type")
 }
 
-func (m Message) ToSessionChatParams() []sgptcoder.SessionPromptParamsPartUnion {
+func promptParts(m Message) []sgptcoder.SessionPromptParamsPartUnion {
 	parts := []sgptcoder.SessionPromptParamsPartUnion{}
 	for _, part := range m.Parts {
 		switch p := part.(type) {
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/skorpland/sgptcoder/internal/permission"
 )
 
 type ModelUsage struct {
@@ -37,8 +39,17 @@ type State struct {
 	MessageHistory     []Prompt              `toml:"message_history"`
 	ShowToolDetails    *bool                 `toml:"show_tool_details"`
 	ShowThinkingBlocks *bool                 `toml:"show_thinking_blocks"`
+	PermissionRules    []permission.Rule     `toml:"permission_rules"`
+	// Folds records, by session, the messages, tool calls and thinking blocks
+	// that were folded (true) or unfolded (false) one at a time. Only the
+	// sessions in FoldedSessions, the most recently folded in first, are kept.
+	Folds          map[string]map[string]bool `toml:"folds"`
+	FoldedSessions []string                   `toml:"folded_sessions"`
 }
 
+// maxFoldedSessions is how many sessions the folds are remembered for.
+const maxFoldedSessions = 50
+
 func NewState() *State {
 	return &State{
 		Theme:              "sgptcoder",
@@ -130,6 +141,65 @@ func (s *State) AddPromptToHistory(prompt Prompt) {
 	}
 }
 
+// Fold returns whether a message or part of a session was folded or unfolded
+// one at a time, and if so which.
+func (s *State) Fold(sessionID, id string) (folded bool, ok bool) {
+	folded, ok = s.Folds[sessionID][id]
+	return folded, ok
+}
+
+// SetFold records that a message or part of a session was folded or unfolded.
+func (s *State) SetFold(sessionID, id string, folded bool) {
+	if s.Folds == nil {
+		s.Folds = make(map[string]map[string]bool)
+	}
+	if s.Folds[sessionID] == nil {
+		s.Folds[sessionID] = make(map[string]bool)
+	}
+	s.Folds[sessionID][id] = folded
+	s.FoldedSessions = slices.DeleteFunc(s.FoldedSessions, func(id string) bool { return id == sessionID })
+	s.FoldedSessions = append([]string{sessionID}, s.FoldedSessions...)
+	s.trimFolds()
+}
+
+// trimFolds forgets the folds of all but the most recently folded in
+// sessions. Sessions missing from FoldedSessions, such as in state files
+// written before it was kept, count as the least recent.
+func (s *State) trimFolds() {
+	var missing []string
+	for sessionID := range s.Folds {
+		if !slices.Contains(s.FoldedSessions, sessionID) {
+			missing = append(missing, sessionID)
+		}
+	}
+	slices.Sort(missing)
+	s.FoldedSessions = slices.DeleteFunc(append(s.FoldedSessions, missing...), func(id string) bool {
+		_, ok := s.Folds[id]
+		return !ok
+	})
+	if len(s.FoldedSessions) > maxFoldedSessions {
+		for _, sessionID := range s.FoldedSessions[maxFoldedSessions:] {
+			delete(s.Folds, sessionID)
+		}
+		s.FoldedSessions = s.FoldedSessions[:maxFoldedSessions]
+	}
+}
+
+// ClearFold forgets that a message or part of a session was folded or
+// unfolded, leaving it to the tool details and thinking blocks toggles.
+func (s *State) ClearFold(sessionID, id string) {
+	delete(s.Folds[sessionID], id)
+	if len(s.Folds[sessionID]) == 0 {
+		s.ClearFolds(sessionID)
+	}
+}
+
+// ClearFolds forgets the folds of a session.
+func (s *State) ClearFolds(sessionID string) {
+	delete(s.Folds, sessionID)
+	s.FoldedSessions = slices.DeleteFunc(s.FoldedSessions, func(id string) bool { return id == sessionID })
+}
+
 // SaveState writes the provided Config struct to the specified TOML file.
 // It will create the file if it doesn't exist, or overwrite it if it does.
 func SaveState(filePath string, state *State) error {
@@ -169,6 +239,7 @@ func LoadState(filePath string) (*State, error) {
 			att.RestoreSourceType()
 		}
 	}
+	state.trimFolds()
 
 	return &state, nil
 }
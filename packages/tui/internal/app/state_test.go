@@ -0,0 +1,81 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestFoldsSurviveSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+	state := NewState()
+	state.SetFold("ses_1", "prt_bash", true)
+	state.SetFold("ses_1", "prt_edit", false)
+	state.SetFold("ses_2", "msg_1", true)
+	if err := SaveState(path, state); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folded, ok := loaded.Fold("ses_1", "prt_bash"); !ok || !folded {
+		t.Errorf("prt_bash: got %v, %v, want folded", folded, ok)
+	}
+	if folded, ok := loaded.Fold("ses_1", "prt_edit"); !ok || folded {
+		t.Errorf("prt_edit: got %v, %v, want unfolded", folded, ok)
+	}
+	if _, ok := loaded.Fold("ses_2", "prt_bash"); ok {
+		t.Error("folds leaked between sessions")
+	}
+
+	loaded.ClearFold("ses_2", "msg_1")
+	if _, ok := loaded.Folds["ses_2"]; ok {
+		t.Error("session without folds was kept")
+	}
+	loaded.ClearFolds("ses_1")
+	if _, ok := loaded.Fold("ses_1", "prt_bash"); ok {
+		t.Error("folds of a cleared session were kept")
+	}
+}
+
+func TestFoldsKeepRecentSessions(t *testing.T) {
+	state := NewState()
+	for i := range maxFoldedSessions {
+		state.SetFold(fmt.Sprintf("ses_%d", i), "msg_1", true)
+	}
+	// Folding in the oldest session again keeps it
+	state.SetFold("ses_0", "msg_2", true)
+	for i := range 5 {
+		state.SetFold(fmt.Sprintf("ses_new_%d", i), "msg_1", true)
+	}
+
+	if len(state.Folds) != maxFoldedSessions || len(state.FoldedSessions) != maxFoldedSessions {
+		t.Fatalf("got folds for %d sessions, %d in order, want %d", len(state.Folds), len(state.FoldedSessions), maxFoldedSessions)
+	}
+	if _, ok := state.Fold("ses_0", "msg_1"); !ok {
+		t.Error("expected the folds of a recently folded in session to be kept")
+	}
+	if _, ok := state.Fold("ses_1", "msg_1"); ok {
+		t.Error("expected the folds of the least recent session to be forgotten")
+	}
+	if state.FoldedSessions[0] != "ses_new_4" {
+		t.Errorf("expected the latest session first, got %v", state.FoldedSessions[:3])
+	}
+
+	// Sessions from state files without an order are kept, as the least recent
+	path := filepath.Join(t.TempDir(), "state")
+	state = NewState()
+	state.Folds = map[string]map[string]bool{"ses_old": {"msg_1": true}}
+	if err := SaveState(path, state); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.FoldedSessions) != 1 || loaded.FoldedSessions[0] != "ses_old" {
+		t.Errorf("expected the session to be adopted, got %v", loaded.FoldedSessions)
+	}
+}
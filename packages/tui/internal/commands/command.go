@@ -32,6 +32,12 @@ type Command struct {
 	Keybindings []Keybinding
 	Trigger     []string
 	Custom      bool
+	// AcceptsArgs is set on built-in commands that take the text typed after
+	// their trigger; for the others that text is sent as a prompt.
+	AcceptsArgs bool
+	// Args is the text typed after the trigger when a built-in command is run
+	// from the editor, e.g. the path in "/export notes.md".
+	Args string
 }
 
 func (c Command) Keys() []string {
@@ -132,6 +138,7 @@ const (
 	SessionInterruptCommand         CommandName = "session_interrupt"
 	SessionCompactCommand           CommandName = "session_compact"
 	SessionExportCommand            CommandName = "session_export"
+	SessionImportCommand            CommandName = "session_import"
 	ToolDetailsCommand              CommandName = "tool_details"
 	ThinkingBlocksCommand           CommandName = "thinking_blocks"
 	ModelListCommand                CommandName = "model_list"
@@ -143,6 +150,8 @@ const (
 	FileSearchCommand               CommandName = "file_search"
 	FileDiffToggleCommand           CommandName = "file_diff_toggle"
 	ProjectInitCommand              CommandName = "project_init"
+	PermissionLogCommand            CommandName = "permission_log"
+	PermissionReviewCommand         CommandName = "permission_review"
 	InputClearCommand               CommandName = "input_clear"
 	InputPasteCommand               CommandName = "input_paste"
 	InputSubmitCommand              CommandName = "input_submit"
@@ -159,6 +168,8 @@ const (
 	MessagesCopyCommand             CommandName = "messages_copy"
 	MessagesUndoCommand             CommandName = "messages_undo"
 	MessagesRedoCommand             CommandName = "messages_redo"
+	MessagesSearchCommand           CommandName = "messages_search"
+	MessagesYankCommand             CommandName = "messages_yank"
 	AppExitCommand                  CommandName = "app_exit"
 )
 
@@ -209,6 +220,14 @@ func LoadFromConfig(config *sgptcoder.Config, customCommands []sgptcoder.Command
 			Description: "export conversation",
 			Keybindings: parseBindings("<leader>x"),
 			Trigger:     []string{"export"},
+			AcceptsArgs: true,
+		},
+		{
+			Name:        SessionImportCommand,
+			Description: "import conversation",
+			Keybindings: parseBindings("none"),
+			Trigger:     []string{"import"},
+			AcceptsArgs: true,
 		},
 		{
 			Name:        SessionNewCommand,
@@ -310,12 +329,47 @@ func LoadFromConfig(config *sgptcoder.Config, customCommands []sgptcoder.Command
 			Keybindings: parseBindings("<leader>t"),
 			Trigger:     []string{"themes"},
 		},
+		{
+			Name:        FileListCommand,
+			Description: "browse files",
+			Keybindings: parseBindings("<leader>f"),
+			Trigger:     []string{"files"},
+		},
+		{
+			Name:        FileSearchCommand,
+			Description: "search files",
+			Keybindings: parseBindings("<leader>/"),
+			Trigger:     []string{"find"},
+		},
+		{
+			Name:        FileDiffToggleCommand,
+			Description: "toggle file diff",
+			Keybindings: parseBindings("<leader>v"),
+			Trigger:     []string{"diff"},
+		},
+		{
+			Name:        FileCloseCommand,
+			Description: "close file",
+			Keybindings: parseBindings("esc"),
+		},
 		{
 			Name:        ProjectInitCommand,
 			Description: "create/update AGENTS.md",
 			Keybindings: parseBindings("<leader>i"),
 			Trigger:     []string{"init"},
 		},
+		{
+			Name:        PermissionLogCommand,
+			Description: "permission decisions",
+			Keybindings: parseBindings("none"),
+			Trigger:     []string{"permissions"},
+		},
+		{
+			Name:        PermissionReviewCommand,
+			Description: "review pending permissions",
+			Keybindings: parseBindings("<leader>p"),
+			Trigger:     []string{"review"},
+		},
 		{
 			Name:        InputClearCommand,
 			Description: "clear input",
@@ -367,12 +421,29 @@ func LoadFromConfig(config *sgptcoder.Config, customCommands []sgptcoder.Command
 			Description: "last message",
 			Keybindings: parseBindings("ctrl+alt+g"),
 		},
+		{
+			Name:        MessagesPreviousCommand,
+			Description: "previous message",
+			Keybindings: parseBindings("ctrl+up"),
+		},
+		{
+			Name:        MessagesNextCommand,
+			Description: "next message",
+			Keybindings: parseBindings("ctrl+down"),
+		},
 
 		{
 			Name:        MessagesCopyCommand,
 			Description: "copy message",
 			Keybindings: parseBindings("<leader>y"),
 		},
+		{
+			Name:        MessagesYankCommand,
+			Description: "yank code block",
+			Keybindings: parseBindings("none"),
+			Trigger:     []string{"yank"},
+			AcceptsArgs: true,
+		},
 		{
 			Name:        MessagesUndoCommand,
 			Description: "undo last message",
@@ -385,6 +456,12 @@ func LoadFromConfig(config *sgptcoder.Config, customCommands []sgptcoder.Command
 			Keybindings: parseBindings("<leader>r"),
 			Trigger:     []string{"redo"},
 		},
+		{
+			Name:        MessagesSearchCommand,
+			Description: "find in messages",
+			Keybindings: parseBindings("ctrl+alt+f"),
+			Trigger:     []string{"search"},
+		},
 		{
 			Name:        AppExitCommand,
 			Description: "exit the app",
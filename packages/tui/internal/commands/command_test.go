@@ -0,0 +1,20 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+)
+
+func TestTriggersAreUnique(t *testing.T) {
+	registry := LoadFromConfig(&sgptcoder.Config{}, nil)
+	owners := map[string]CommandName{}
+	for _, command := range registry.Sorted() {
+		for _, trigger := range command.Trigger {
+			if owner, ok := owners[trigger]; ok {
+				t.Errorf("trigger %q is used by both %s and %s", trigger, owner, command.Name)
+			}
+			owners[trigger] = command.Name
+		}
+	}
+}
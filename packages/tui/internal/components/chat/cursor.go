@@ -0,0 +1,328 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/mirror"
+	"github.com/skorpland/sgptcoder/internal/app"
+	"github.com/skorpland/sgptcoder/internal/commands"
+	"github.com/skorpland/sgptcoder/internal/components/dialog"
+	"github.com/skorpland/sgptcoder/internal/components/toast"
+	"github.com/skorpland/sgptcoder/internal/exporter"
+	"github.com/skorpland/sgptcoder/internal/styles"
+	"github.com/skorpland/sgptcoder/internal/theme"
+	"github.com/skorpland/sgptcoder/internal/util"
+)
+
+const cursorBarHeight = 1
+
+// RerunPromptMsg asks for a model to send a prompt again with.
+type RerunPromptMsg struct {
+	Prompt app.Prompt
+}
+
+type foldKind uint8
+
+const (
+	foldMessage foldKind = iota
+	foldTool
+	foldThinking
+)
+
+// foldable is a message, tool call or thinking block that can be folded and
+// unfolded, with the lines it spans. The title is the line that folds it when
+// clicked, or -1 if clicking doesn't.
+type foldable struct {
+	id      string
+	message string
+	kind    foldKind
+	title   int
+	start   int
+	end     int
+}
+
+// folded returns whether a message or part is folded, given whether it is
+// folded unless it was folded or unfolded on its own.
+func folded(folds map[string]bool, id string, otherwise bool) bool {
+	if folded, ok := folds[id]; ok {
+		return folded
+	}
+	return otherwise
+}
+
+// foldedOtherwise returns whether an item is folded unless it was folded or
+// unfolded on its own: tool calls follow the tool details toggle, thinking
+// blocks and messages are unfolded.
+func (m *messagesComponent) foldedOtherwise(item foldable) bool {
+	return item.kind == foldTool && !m.showToolDetails
+}
+
+func (m *messagesComponent) isFolded(item foldable) bool {
+	folds := m.app.State.Folds[m.app.Session.ID]
+	return folded(folds, item.id, m.foldedOtherwise(item))
+}
+
+// toggleFold folds or unfolds an item, remembering it for the session unless
+// that is what the toggles would do anyway.
+func (m *messagesComponent) toggleFold(item foldable) tea.Cmd {
+	fold := !m.isFolded(item)
+	if fold == m.foldedOtherwise(item) {
+		m.app.State.ClearFold(m.app.Session.ID, item.id)
+	} else {
+		m.app.State.SetFold(m.app.Session.ID, item.id, fold)
+	}
+	m.stale[item.message] = true
+	return tea.Batch(m.renderView(), m.app.SaveState())
+}
+
+// itemAt returns the item whose title is at the given line.
+func (m *messagesComponent) itemAt(line int) (foldable, bool) {
+	for _, item := range m.items {
+		if item.title == line {
+			return item, true
+		}
+	}
+	return foldable{}, false
+}
+
+func (m *messagesComponent) cursorIndex() int {
+	return slices.IndexFunc(m.items, func(item foldable) bool { return item.id == m.cursor })
+}
+
+func (m *messagesComponent) HasCursor() bool {
+	return m.cursor != ""
+}
+
+// Previous moves the cursor to the previous message, tool call or thinking
+// block, starting from the bottom of the view.
+func (m *messagesComponent) Previous() (tea.Model, tea.Cmd) {
+	index := m.cursorIndex() - 1
+	if m.cursor == "" {
+		bottom := m.offset() + m.viewport.Height()
+		index = len(m.items) - 1
+		for index > 0 && m.items[index].start >= bottom {
+			index--
+		}
+	}
+	return m, m.moveCursor(index)
+}
+
+// Next moves the cursor to the next message, tool call or thinking block,
+// starting from the top of the view.
+func (m *messagesComponent) Next() (tea.Model, tea.Cmd) {
+	index := m.cursorIndex() + 1
+	if m.cursor == "" {
+		top := m.offset()
+		index = 0
+		for index < len(m.items)-1 && m.items[index].end < top {
+			index++
+		}
+	}
+	return m, m.moveCursor(index)
+}
+
+// moveCursor puts the cursor on an item and scrolls it into view.
+func (m *messagesComponent) moveCursor(index int) tea.Cmd {
+	if len(m.items) == 0 {
+		return nil
+	}
+	item := m.items[max(0, min(index, len(m.items)-1))]
+	opening := m.cursor == ""
+	m.cursor = item.id
+	if opening {
+		m.viewport.SetHeight(m.viewportHeight())
+	}
+
+	offset := m.offset()
+	height := m.viewport.Height()
+	if item.start < offset {
+		offset = item.start
+	} else if item.end >= offset+height {
+		offset = min(item.start, item.end-height+1)
+	}
+	m.setWindow(offset)
+	return m.scrolled()
+}
+
+// closeCursor removes the cursor.
+func (m *messagesComponent) closeCursor() {
+	m.cursor = ""
+	m.viewport.SetHeight(m.viewportHeight())
+	m.setWindow(m.offset())
+}
+
+func (m *messagesComponent) updateCursor(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	previous := m.app.Commands[commands.MessagesPreviousCommand]
+	next := m.app.Commands[commands.MessagesNextCommand]
+	first := m.app.Commands[commands.MessagesFirstCommand]
+	last := m.app.Commands[commands.MessagesLastCommand]
+	key := msg.String()
+	switch {
+	case key == "esc", key == "ctrl+c":
+		m.closeCursor()
+		return m, nil
+	case key == "up", key == "ctrl+p", previous.Matches(msg, false):
+		return m.Previous()
+	case key == "down", key == "ctrl+n", next.Matches(msg, false):
+		return m.Next()
+	case key == "g", key == "home", first.Matches(msg, false):
+		return m, m.moveCursor(0)
+	case key == "G", key == "end", last.Matches(msg, false):
+		return m, m.moveCursor(len(m.items) - 1)
+	}
+
+	index := m.cursorIndex()
+	if index < 0 {
+		return m, nil
+	}
+	item := m.items[index]
+	position := slices.IndexFunc(m.app.Messages, func(message app.Message) bool {
+		return mirror.MessageID(message.Info) == item.message
+	})
+	if position < 0 {
+		return m, nil
+	}
+	message := m.app.Messages[position]
+
+	switch key {
+	case "enter", "space":
+		return m, m.toggleFold(item)
+	case "y":
+		text := messageText(message)
+		if text == "" {
+			return m, toast.NewInfoToast("No text in this message")
+		}
+		return m, tea.Batch(app.SetClipboard(text), toast.NewSuccessToast("Message copied to clipboard"))
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		n := int(key[0] - '0')
+		blocks := util.CodeBlocks(messageText(message))
+		if n > len(blocks) {
+			return m, toast.NewInfoToast(fmt.Sprintf("No code block %d in this message", n))
+		}
+		return m, tea.Batch(
+			app.SetClipboard(blocks[n-1].Content),
+			toast.NewSuccessToast(fmt.Sprintf("Code block %d copied to clipboard", n)),
+		)
+	case "e":
+		markdown := exporter.ToolCalls(message)
+		if markdown == "" {
+			return m, toast.NewInfoToast("No tool calls in this message")
+		}
+		return m, app.OpenInEditor(markdown, "tools-*.md")
+	case "r":
+		m.closeCursor()
+		return m, util.CmdHandler(dialog.RestoreToMessageMsg{MessageID: item.message, Index: position})
+	case "f":
+		m.closeCursor()
+		sessionID := m.app.Session.ID
+		return m, func() tea.Msg {
+			session, err := m.app.ForkSession(context.Background(), sessionID, item.message)
+			if err != nil {
+				return toast.NewErrorToast("Failed to fork session")()
+			}
+			return app.SessionSelectedMsg(session)
+		}
+	case "m":
+		// An assistant message is re-run from the prompt that produced it
+		for ; position >= 0; position-- {
+			if _, ok := m.app.Messages[position].Info.(sgptcoder.UserMessage); ok {
+				break
+			}
+		}
+		if position < 0 {
+			return m, nil
+		}
+		prompt, err := app.PromptFromMessage(m.app.Messages[position])
+		if err != nil {
+			return m, toast.NewErrorToast("Failed to rebuild the prompt")
+		}
+		m.closeCursor()
+		return m, util.CmdHandler(RerunPromptMsg{Prompt: *prompt})
+	}
+	return m, nil
+}
+
+// messageText returns the Markdown text of a message, leaving out text added
+// by tools.
+func messageText(message app.Message) string {
+	var texts []string
+	for _, part := range message.Parts {
+		if part, ok := part.(sgptcoder.TextPart); ok && !part.Synthetic {
+			texts = append(texts, strings.TrimSpace(part.Text))
+		}
+	}
+	return strings.TrimSpace(strings.Join(texts, "\n\n"))
+}
+
+// markCursor draws a bar beside the lines of the item under the cursor, in a
+// window starting at the given line.
+func (m *messagesComponent) markCursor(window []string, start int) {
+	index := m.cursorIndex()
+	if index < 0 {
+		return
+	}
+	t := theme.CurrentTheme()
+	marker := styles.NewStyle().Foreground(t.Accent()).Background(t.Background()).Render("▌")
+	item := m.items[index]
+	for line := max(item.start, start); line <= item.end && line < start+len(window); line++ {
+		i := line - start
+		window[i] = marker + ansi.Cut(window[i], 1, ansi.StringWidth(window[i]))
+	}
+}
+
+// cursorView renders the bar shown below the messages while the cursor is on
+// them, with what it is on and the keys that act on it.
+func (m *messagesComponent) cursorView() string {
+	t := theme.CurrentTheme()
+	bgColor := t.BackgroundElement()
+	base := styles.NewStyle().Foreground(t.Text()).Background(bgColor).Render
+	muted := styles.NewStyle().Foreground(t.TextMuted()).Background(bgColor).Render
+
+	label := ""
+	action := "fold"
+	if index := m.cursorIndex(); index >= 0 {
+		item := m.items[index]
+		switch item.kind {
+		case foldMessage:
+			label = "message"
+		case foldTool:
+			label = "tool call"
+		case foldThinking:
+			label = "thinking"
+		}
+		if m.isFolded(item) {
+			action = "unfold"
+		}
+	}
+	label = styles.NewStyle().Foreground(t.Primary()).Background(bgColor).Bold(true).Render(" " + label)
+	// The least important hints are left out when they don't fit
+	hints := ""
+	closeHint := base("esc") + muted(" close ")
+	for _, hint := range [][2]string{
+		{"↑↓", "move"},
+		{"enter", action},
+		{"y", "copy"},
+		{"1-9", "code"},
+		{"r", "revert"},
+		{"f", "fork"},
+		{"m", "model"},
+		{"e", "tools"},
+	} {
+		with := hints + base(hint[0]) + muted(" "+hint[1]+"  ")
+		if lipgloss.Width(label)+lipgloss.Width(with+closeHint) > m.width {
+			break
+		}
+		hints = with
+	}
+	hints += closeHint
+	space := max(0, m.width-lipgloss.Width(label)-lipgloss.Width(hints))
+	spacer := styles.NewStyle().Background(bgColor).Width(space).Render("")
+	return label + spacer + hints
+}
@@ -135,7 +135,7 @@ func (m *editorComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.Session.ID == m.app.Session.ID {
 			switch msg.Message.Info.(type) {
 			case sgptcoder.UserMessage:
-				prompt, err := msg.Message.ToPrompt()
+				prompt, err := app.PromptFromMessage(msg.Message)
 				if err != nil {
 					return m, toast.NewErrorToast("Failed to revert message")
 				}
@@ -522,6 +522,25 @@ func (m *editorComponent) Submit() (tea.Model, tea.Cmd) {
 
 			return m, tea.Batch(cmds...)
 		}
+
+		// Built-in commands that accept arguments run with them
+		args := strings.TrimSpace(strings.TrimPrefix(expandedValue, commandName))
+		for _, command := range m.app.Commands {
+			if command.Custom || !command.MatchesTrigger(commandName) {
+				continue
+			}
+			if args != "" && !command.AcceptsArgs {
+				break
+			}
+			command.Args = args
+			cmds = append(cmds, util.CmdHandler(commands.ExecuteCommandMsg(command)))
+
+			updated, cmd := m.Clear()
+			m = updated.(*editorComponent)
+			cmds = append(cmds, cmd)
+
+			return m, tea.Batch(cmds...)
+		}
 	}
 
 	attachments := m.textarea.GetAttachments()
@@ -205,6 +205,40 @@ func renderContentBlock(
 	return content
 }
 
+// codeBlockMarker stands for the space between the number and the language
+// in the label numberCodeBlocks puts at the top of each code block of a
+// reply. It is kept through rendering to tell the label lines apart, and
+// swapped for a space when the lines are laid out.
+const codeBlockMarker = "\uE000"
+
+// numberCodeBlocks labels the code blocks of a text part of a reply, numbering
+// them on from the given number of code blocks in its earlier text parts.
+func numberCodeBlocks(text string, before int) string {
+	return util.NumberCodeBlocks(text, func(n int, block util.CodeBlock) string {
+		return fmt.Sprintf("[%d]%s%s", before+n, codeBlockMarker, block.Language)
+	})
+}
+
+// labelCodeBlocks styles the label lines of a rendered reply.
+func labelCodeBlocks(content string, backgroundColor compat.AdaptiveColor) string {
+	if !strings.Contains(content, codeBlockMarker) {
+		return content
+	}
+	t := theme.CurrentTheme()
+	style := styles.NewStyle().Foreground(t.TextMuted()).Background(backgroundColor)
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		plain := ansi.Strip(line)
+		if !strings.Contains(plain, codeBlockMarker) {
+			continue
+		}
+		label := strings.TrimLeft(plain, " ")
+		indent := plain[:len(plain)-len(label)]
+		lines[i] = style.Width(ansi.StringWidth(line)).Render(indent + strings.TrimSpace(label))
+	}
+	return strings.Join(lines, "\n")
+}
+
 func renderText(
 	app *app.App,
 	message sgptcoder.MessageUnion,
@@ -235,7 +269,7 @@ func renderText(
 		if casted.Time.Completed > 0 {
 			ts = time.UnixMilli(int64(casted.Time.Completed))
 		}
-		content = util.ToMarkdown(text, width, backgroundColor)
+		content = labelCodeBlocks(util.ToMarkdown(text, width, backgroundColor), backgroundColor)
 		if isThinking {
 			var label string
 			if shimmer {
@@ -453,6 +487,78 @@ func renderText(
 	return ""
 }
 
+// renderFoldedMessage renders a folded message as its first line of text.
+func renderFoldedMessage(app *app.App, message app.Message, width int) string {
+	t := theme.CurrentTheme()
+	text := ""
+	tools := 0
+	for _, part := range message.Parts {
+		switch part := part.(type) {
+		case sgptcoder.TextPart:
+			if text == "" && !part.Synthetic {
+				text, _, _ = strings.Cut(strings.TrimSpace(part.Text), "\n")
+			}
+		case sgptcoder.ToolPart:
+			tools++
+		}
+	}
+	if text == "" && tools > 0 {
+		text = fmt.Sprintf("%d tool calls", tools)
+		if tools == 1 {
+			text = "1 tool call"
+		}
+	}
+
+	if _, ok := message.Info.(sgptcoder.UserMessage); ok {
+		return renderContentBlock(
+			app,
+			renderFoldedTitle(text, t.BackgroundPanel(), width),
+			width,
+			WithTextColor(t.Text()),
+			WithBorderColor(t.Secondary()),
+		)
+	}
+	return renderContentBlock(
+		app,
+		renderFoldedTitle(text, t.Background(), width),
+		width,
+		WithNoBorder(),
+		WithBackgroundColor(t.Background()),
+	)
+}
+
+// renderFoldedThinking renders a folded thinking block as its label.
+func renderFoldedThinking(app *app.App, width int) string {
+	t := theme.CurrentTheme()
+	return renderContentBlock(
+		app,
+		renderFoldedTitle("Thinking...", t.BackgroundPanel(), width),
+		width,
+		WithTextColor(t.Text()),
+		WithBackgroundColor(t.BackgroundPanel()),
+		WithBorderColor(t.BackgroundPanel()),
+	)
+}
+
+// renderFoldedTitle renders the one line left of something folded, marked as
+// folded.
+func renderFoldedTitle(text string, backgroundColor compat.AdaptiveColor, width int) string {
+	t := theme.CurrentTheme()
+	marker := styles.NewStyle().
+		Background(backgroundColor).
+		Foreground(t.TextMuted()).
+		Render(" (folded)")
+	text = truncate.StringWithTail(text, uint(max(0, width-6-lipgloss.Width(marker))), "...")
+	text = styles.NewStyle().
+		Background(backgroundColor).
+		Foreground(t.Text()).
+		Render(text)
+	return styles.NewStyle().
+		Background(backgroundColor).
+		Width(width - 6).
+		Render(text + marker)
+}
+
 func renderToolDetails(
 	app *app.App,
 	toolCall sgptcoder.ToolPart,
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"slices"
 	"sort"
 	"strconv"
@@ -14,6 +15,7 @@ import (
 	"github.com/charmbracelet/lipgloss/v2"
 	"github.com/charmbracelet/x/ansi"
 	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder-sdk-go/mirror"
 	"github.com/skorpland/sgptcoder/internal/app"
 	"github.com/skorpland/sgptcoder/internal/commands"
 	"github.com/skorpland/sgptcoder/internal/components/dialog"
@@ -38,9 +40,15 @@ type MessagesComponent interface {
 	GotoTop() (tea.Model, tea.Cmd)
 	GotoBottom() (tea.Model, tea.Cmd)
 	CopyLastMessage() (tea.Model, tea.Cmd)
+	CodeBlocks() []util.CodeBlock
 	UndoLastMessage() (tea.Model, tea.Cmd)
 	RedoLastMessage() (tea.Model, tea.Cmd)
 	ScrollToMessage(messageID string) (tea.Model, tea.Cmd)
+	StartSearch() (tea.Model, tea.Cmd)
+	Searching() bool
+	Previous() (tea.Model, tea.Cmd)
+	Next() (tea.Model, tea.Cmd)
+	HasCursor() bool
 }
 
 type messagesComponent struct {
@@ -61,6 +69,19 @@ type messagesComponent struct {
 	selection          *selection
 	messagePositions   map[string]int // map message ID to line position
 	animating          bool
+	search             *messageSearch
+	blocks             map[string]*messageBlock // rendered messages by ID
+	stale              map[string]bool          // messages changed since the last render
+	generation         int                      // counts cache clears, to drop renders started before
+	lines              []string                 // rendered lines of the whole conversation
+	kinds              []lineKind
+	starts             []messageStart
+	windowStart        int // first of the lines given to the viewport
+	windowEnd          int
+	loadingOlder       bool
+	olderFailed        bool       // loading older messages failed, not retried until the session is opened again
+	items              []foldable // what can be folded, in order
+	cursor             string     // ID of the item under the cursor
 }
 
 type selection struct {
@@ -110,18 +131,26 @@ func (m *messagesComponent) Init() tea.Cmd {
 func (m *messagesComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		if m.search != nil {
+			return m.updateSearch(msg)
+		}
+		if m.cursor != "" {
+			return m.updateCursor(msg)
+		}
 	case shimmerTickMsg:
 		if !m.app.HasAnimatingWork() {
 			m.animating = false
 			return m, nil
 		}
+		m.markAnimating()
 		return m, tea.Sequence(
 			m.renderView(),
 			tea.Tick(90*time.Millisecond, func(t time.Time) tea.Msg { return shimmerTickMsg{} }),
 		)
 	case tea.MouseClickMsg:
-		slog.Info("mouse", "x", msg.X, "y", msg.Y, "offset", m.viewport.YOffset)
-		y := msg.Y + m.viewport.YOffset
+		slog.Info("mouse", "x", msg.X, "y", msg.Y, "offset", m.offset())
+		y := msg.Y + m.offset()
 		if y > 0 {
 			m.selection = &selection{
 				startY: y,
@@ -131,7 +160,8 @@ func (m *messagesComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 			slog.Info("mouse selection", "start", fmt.Sprintf("%d,%d", m.selection.startX, m.selection.startY), "end", fmt.Sprintf("%d,%d", m.selection.endX, m.selection.endY))
-			return m, m.renderView()
+			m.refresh()
+			return m, nil
 		}
 
 	case tea.MouseMotionMsg:
@@ -140,30 +170,39 @@ func (m *messagesComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				startX: m.selection.startX,
 				startY: m.selection.startY,
 				endX:   msg.X + 1,
-				endY:   msg.Y + m.viewport.YOffset,
+				endY:   msg.Y + m.offset(),
 			}
-			return m, m.renderView()
+			m.refresh()
+			return m, nil
 		}
 
 	case tea.MouseReleaseMsg:
+		if m.selection != nil && m.selection.endY < 0 && m.search == nil {
+			// A click on a title folds or unfolds it
+			line := m.selection.startY - lipgloss.Height(m.header)
+			if item, ok := m.itemAt(line); ok {
+				m.selection = nil
+				m.refresh()
+				return m, m.toggleFold(item)
+			}
+		}
 		if m.selection != nil {
 			m.selection = nil
-			if len(m.clipboard) > 0 {
-				content := strings.Join(m.clipboard, "\n")
-				m.clipboard = []string{}
+			content := strings.Join(m.clipboard, "\n")
+			m.refresh()
+			if content != "" {
 				return m, tea.Sequence(
-					m.renderView(),
 					app.SetClipboard(content),
 					toast.NewSuccessToast("Copied to clipboard"),
 				)
 			}
-			return m, m.renderView()
+			return m, nil
 		}
 	case tea.WindowSizeMsg:
 		effectiveWidth := msg.Width - 4
 		// Clear cache on resize since width affects rendering
 		if m.width != effectiveWidth {
-			m.cache.Clear()
+			m.clearCache()
 		}
 		m.width = effectiveWidth
 		m.height = msg.Height - 7
@@ -171,15 +210,13 @@ func (m *messagesComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.loading = true
 		return m, m.renderView()
 	case app.SendPrompt:
-		m.viewport.GotoBottom()
-		m.tail = true
+		m.gotoBottom()
 		return m, nil
 	case app.SendCommand:
-		m.viewport.GotoBottom()
-		m.tail = true
+		m.gotoBottom()
 		return m, nil
 	case dialog.ThemeSelectedMsg:
-		m.cache.Clear()
+		m.clearCache()
 		m.loading = true
 		return m, m.renderView()
 	case ToggleToolDetailsMsg:
@@ -195,13 +232,13 @@ func (m *messagesComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.loading = true
 		return m, m.renderView()
 	case app.SessionClearedMsg:
-		m.cache.Clear()
+		m.clearCache()
 		m.tail = true
 		m.loading = true
 		return m, m.renderView()
 	case app.SessionUnrevertedMsg:
 		if msg.Session.ID == m.app.Session.ID {
-			m.cache.Clear()
+			m.clearCache()
 			m.tail = true
 			return m, m.renderView()
 		}
@@ -218,13 +255,27 @@ func (m *messagesComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Clear cache only if switching between different session families
 		if currentParent != targetParent {
-			m.cache.Clear()
+			m.clearCache()
 		}
+		m.olderFailed = false
 
-		m.viewport.GotoBottom()
+		m.gotoBottom()
+	case app.OlderMessagesLoadedMsg:
+		m.loadingOlder = false
+		if msg.SessionID != m.app.Session.ID {
+			break
+		}
+		if msg.Err != nil {
+			m.olderFailed = true
+			break
+		}
+		if m.search != nil {
+			return m, tea.Batch(m.renderView(), m.loadAll())
+		}
+		return m, m.renderView()
 	case app.MessageRevertedMsg:
 		if msg.Session.ID == m.app.Session.ID {
-			m.cache.Clear()
+			m.clearCache()
 			m.tail = true
 			return m, m.renderView()
 		}
@@ -235,6 +286,7 @@ func (m *messagesComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	case sgptcoder.EventListResponseEventMessageUpdated:
 		if msg.Properties.Info.SessionID == m.app.Session.ID {
+			m.stale[msg.Properties.Info.ID] = true
 			cmds = append(cmds, m.renderView())
 		}
 	case sgptcoder.EventListResponseEventSessionError:
@@ -243,17 +295,18 @@ func (m *messagesComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	case sgptcoder.EventListResponseEventMessagePartUpdated:
 		if msg.Properties.Part.SessionID == m.app.Session.ID {
+			m.stale[msg.Properties.Part.MessageID] = true
 			cmds = append(cmds, m.renderView())
 		}
 	case sgptcoder.EventListResponseEventMessageRemoved:
 		if msg.Properties.SessionID == m.app.Session.ID {
-			m.cache.Clear()
+			m.clearCache()
 			cmds = append(cmds, m.renderView())
 		}
 	case sgptcoder.EventListResponseEventMessagePartRemoved:
 		if msg.Properties.SessionID == m.app.Session.ID {
 			// Clear the cache when a part is removed to ensure proper re-rendering
-			m.cache.Clear()
+			m.clearCache()
 			cmds = append(cmds, m.renderView())
 		}
 	case sgptcoder.EventListResponseEventPermissionUpdated:
@@ -266,23 +319,37 @@ func (m *messagesComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.partCount = msg.partCount
 		m.lineCount = msg.lineCount
 		m.rendering = false
-		m.clipboard = msg.clipboard
 		m.loading = false
-		m.messagePositions = msg.messagePositions
-		m.tail = m.viewport.AtBottom()
+		if msg.generation == m.generation {
+			m.blocks = msg.blocks
+		}
 
 		// Preserve scroll across reflow
-		// if the user was at bottom, keep following; otherwise restore the previous offset.
-		wasAtBottom := m.viewport.AtBottom()
-		prevYOffset := m.viewport.YOffset
-		m.viewport = msg.viewport
-		if wasAtBottom {
-			m.viewport.GotoBottom()
-		} else {
-			m.viewport.YOffset = prevYOffset
+		// if the user was at bottom, keep following; otherwise keep the
+		// message at the top of the view in place.
+		offset := m.offset()
+		anchor, delta := m.anchor(offset)
+		m.header = msg.header
+		m.lines = msg.lines
+		m.kinds = msg.kinds
+		m.starts = msg.starts
+		m.items = msg.items
+		m.messagePositions = msg.messagePositions
+		if m.cursor != "" && m.cursorIndex() < 0 {
+			m.cursor = ""
+		}
+		if position, ok := m.messagePositions[anchor]; ok {
+			offset = position + delta
+		}
+		m.viewport.SetHeight(m.viewportHeight())
+		if m.tail {
+			offset = m.maxOffset()
+		}
+		m.setWindow(offset)
+		if m.search != nil {
+			m.highlight(true)
 		}
 
-		m.header = msg.header
 		if m.dirty {
 			cmds = append(cmds, m.renderView())
 		}
@@ -294,23 +361,175 @@ func (m *messagesComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
-	m.tail = m.viewport.AtBottom()
 	viewport, cmd := m.viewport.Update(msg)
 	m.viewport = viewport
-	cmds = append(cmds, cmd)
+	cmds = append(cmds, cmd, m.scrolled())
 
 	return m, tea.Batch(cmds...)
 }
 
 type renderCompleteMsg struct {
-	viewport         viewport.Model
-	clipboard        []string
+	generation       int
 	header           string
+	blocks           map[string]*messageBlock
+	lines            []string
+	kinds            []lineKind
+	starts           []messageStart
+	items            []foldable
 	partCount        int
 	lineCount        int
 	messagePositions map[string]int
 }
 
+// renderOptions are what every message is rendered with. Cached messages that
+// were rendered with other options are rendered again.
+type renderOptions struct {
+	width                    int
+	showToolDetails          bool
+	showThinkingBlocks       bool
+	revertMessageID          string
+	permissionID             string
+	lastAssistantMessage     string
+	lastStreamingReasoningID string
+	folds                    bool   // whether items folded on their own are
+	codeBlocksMessageID      string // the reply whose code blocks are labeled, see latestCodeBlocks
+}
+
+// renderState is carried from one message to the next while rendering.
+type renderState struct {
+	reverted             bool
+	revertedMessageCount int
+	revertedToolCount    int
+	orphanedToolCalls    []sgptcoder.ToolPart
+}
+
+func (s renderState) clone() renderState {
+	s.orphanedToolCalls = slices.Clone(s.orphanedToolCalls)
+	return s
+}
+
+func (s renderState) equal(other renderState) bool {
+	return s.reverted == other.reverted &&
+		s.revertedMessageCount == other.revertedMessageCount &&
+		s.revertedToolCount == other.revertedToolCount &&
+		slices.EqualFunc(s.orphanedToolCalls, other.orphanedToolCalls, func(a, b sgptcoder.ToolPart) bool {
+			return a.ID == b.ID && a.State.Status == b.State.Status
+		})
+}
+
+// messageBlock is a rendered message, kept between renders so that only the
+// messages that changed are rendered again.
+type messageBlock struct {
+	options renderOptions
+	in      renderState
+	out     renderState
+	lines   []string
+	kinds   []lineKind
+	items   []foldable
+	parts   int
+}
+
+// renderedBlock is a block of a rendered message, with the message or part it
+// folds when its title is clicked and the tool calls listed under its text.
+type renderedBlock struct {
+	content string
+	fold    string
+	kind    foldKind
+	tools   []sgptcoder.ToolPart
+}
+
+// lineKind tells the content lines of a rendered message, which can be
+// selected, from the borders of its blocks, the gaps between them and the
+// labels of code blocks.
+type lineKind uint8
+
+const (
+	lineBorder lineKind = iota
+	lineContent
+	lineGap
+	lineLabel
+)
+
+// messageStart is the line before the first line of a message.
+type messageStart struct {
+	id   string
+	line int
+}
+
+// appendBlocks splits rendered blocks into lines, with a gap after each, and
+// finishes the labels of code blocks.
+func appendBlocks(lines []string, kinds []lineKind, blocks ...string) ([]string, []lineKind) {
+	for _, block := range blocks {
+		blockLines := strings.Split(block, "\n")
+		for index, line := range blockLines {
+			kind := lineContent
+			if index == 0 || index == len(blockLines)-1 {
+				kind = lineBorder
+			} else if strings.Contains(line, codeBlockMarker) {
+				kind = lineLabel
+				line = strings.Replace(line, codeBlockMarker, " ", 1)
+			}
+			lines = append(lines, line)
+			kinds = append(kinds, kind)
+		}
+		lines = append(lines, "")
+		kinds = append(kinds, lineGap)
+	}
+	return lines, kinds
+}
+
+// layoutBlocks splits the rendered blocks of a message into lines, and finds
+// the lines of the message and of what it can fold.
+func layoutBlocks(message string, blocks []renderedBlock) ([]string, []lineKind, []foldable) {
+	var lines []string
+	var kinds []lineKind
+	var items []foldable
+	item := foldable{id: message, message: message, kind: foldMessage, title: -1}
+	for _, block := range blocks {
+		start := len(lines)
+		lines, kinds = appendBlocks(lines, kinds, block.content)
+		end := len(lines) - 2 // without the gap after the block
+		switch block.fold {
+		case "":
+		case message:
+			item.title = start + 1
+		default:
+			items = append(items, foldable{
+				id:      block.fold,
+				message: message,
+				kind:    block.kind,
+				title:   start + 1,
+				start:   start,
+				end:     end,
+			})
+		}
+		tools := block.tools
+		for line := start; line <= end && len(tools) > 0; line++ {
+			if strings.HasPrefix(strings.TrimSpace(ansi.Strip(lines[line])), "∟ ") {
+				items = append(items, foldable{
+					id:      tools[0].ID,
+					message: tools[0].MessageID,
+					kind:    foldTool,
+					title:   line,
+					start:   line,
+					end:     line,
+				})
+				tools = tools[1:]
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return lines, kinds, items
+	}
+	item.end = len(lines) - 2
+	return lines, kinds, append([]foldable{item}, items...)
+}
+
+// renderView renders the messages that changed since the last render and lays
+// out the lines of every loaded message, reusing the blocks of the others.
+// Only the lines around the view are given to the viewport, see setWindow,
+// but each render still joins the lines of all loaded messages, so its cost
+// grows with how much of the conversation has been loaded.
 func (m *messagesComponent) renderView() tea.Cmd {
 	if m.rendering {
 		slog.Debug("pending render, skipping")
@@ -322,28 +541,38 @@ func (m *messagesComponent) renderView() tea.Cmd {
 	m.dirty = false
 	m.rendering = true
 
-	viewport := m.viewport
-	tail := m.tail
+	// Searching shows what the search looks through
+	searching := m.search != nil
+	showToolDetails := m.showToolDetails || searching
+	showThinkingBlocks := m.showThinkingBlocks || (searching && m.search.thinking)
+	var folds map[string]bool
+	if !searching {
+		folds = maps.Clone(m.app.State.Folds[m.app.Session.ID])
+	}
+	previous := m.blocks
+	stale := m.stale
+	m.stale = make(map[string]bool)
+	generation := m.generation
 
 	return func() tea.Msg {
 		header := m.renderHeader()
 		measure := util.Measure("messages.renderView")
 		defer measure()
 
-		t := theme.CurrentTheme()
-		blocks := make([]string, 0)
-		partCount := 0
-		lineCount := 0
-		messagePositions := make(map[string]int) // Track message ID to line position
-
-		orphanedToolCalls := make([]sgptcoder.ToolPart, 0)
-
-		width := m.width // always use full width
+		opts := renderOptions{
+			width:                m.width, // always use full width
+			showToolDetails:      showToolDetails,
+			showThinkingBlocks:   showThinkingBlocks,
+			revertMessageID:      m.app.Session.Revert.MessageID,
+			permissionID:         m.app.CurrentPermission.ID,
+			lastAssistantMessage: "zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz",
+			folds:                !searching,
+		}
+		opts.codeBlocksMessageID, _ = latestCodeBlocks(m.app.Messages, opts.revertMessageID)
 
 		// Find the last streaming ReasoningPart to only shimmer that one
-		lastStreamingReasoningID := ""
-		if m.showThinkingBlocks {
-			for mi := len(m.app.Messages) - 1; mi >= 0 && lastStreamingReasoningID == ""; mi-- {
+		if showThinkingBlocks {
+			for mi := len(m.app.Messages) - 1; mi >= 0 && opts.lastStreamingReasoningID == ""; mi-- {
 				if _, ok := m.app.Messages[mi].Info.(sgptcoder.AssistantMessage); !ok {
 					continue
 				}
@@ -351,7 +580,7 @@ func (m *messagesComponent) renderView() tea.Cmd {
 				for pi := len(parts) - 1; pi >= 0; pi-- {
 					if rp, ok := parts[pi].(sgptcoder.ReasoningPart); ok {
 						if strings.TrimSpace(rp.Text) != "" && rp.Time.End == 0 {
-							lastStreamingReasoningID = rp.ID
+							opts.lastStreamingReasoningID = rp.ID
 							break
 						}
 					}
@@ -359,355 +588,65 @@ func (m *messagesComponent) renderView() tea.Cmd {
 			}
 		}
 
-		reverted := false
-		revertedMessageCount := 0
-		revertedToolCount := 0
-		lastAssistantMessage := "zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz"
 		for _, msg := range slices.Backward(m.app.Messages) {
 			if assistant, ok := msg.Info.(sgptcoder.AssistantMessage); ok {
 				if assistant.Time.Completed > 0 {
 					break
 				}
-				lastAssistantMessage = assistant.ID
+				opts.lastAssistantMessage = assistant.ID
 				break
 			}
 		}
-		for _, message := range m.app.Messages {
-			var content string
-			var cached bool
-			error := ""
-
-			switch casted := message.Info.(type) {
-			case sgptcoder.UserMessage:
-				// Track the position of this user message
-				messagePositions[casted.ID] = lineCount
-
-				if casted.ID == m.app.Session.Revert.MessageID {
-					reverted = true
-					revertedMessageCount = 1
-					revertedToolCount = 0
-					continue
-				}
-				if reverted {
-					revertedMessageCount++
-					continue
-				}
 
-				for partIndex, part := range message.Parts {
-					switch part := part.(type) {
-					case sgptcoder.TextPart:
-						if part.Synthetic {
-							continue
-						}
-						if part.Text == "" {
-							continue
-						}
-						remainingParts := message.Parts[partIndex+1:]
-						fileParts := make([]sgptcoder.FilePart, 0)
-						agentParts := make([]sgptcoder.AgentPart, 0)
-						for _, part := range remainingParts {
-							switch part := part.(type) {
-							case sgptcoder.FilePart:
-								if part.Source.Text.Start >= 0 && part.Source.Text.End >= part.Source.Text.Start {
-									fileParts = append(fileParts, part)
-								}
-							case sgptcoder.AgentPart:
-								if part.Source.Start >= 0 && part.Source.End >= part.Source.Start {
-									agentParts = append(agentParts, part)
-								}
-							}
-						}
-						flexItems := []layout.FlexItem{}
-						if len(fileParts) > 0 {
-							fileStyle := styles.NewStyle().Background(t.BackgroundElement()).Foreground(t.TextMuted()).Padding(0, 1)
-							mediaTypeStyle := styles.NewStyle().Background(t.Secondary()).Foreground(t.BackgroundPanel()).Padding(0, 1)
-							for _, filePart := range fileParts {
-								mediaType := ""
-								switch filePart.Mime {
-								case "text/plain":
-									mediaType = "txt"
-								case "image/png", "image/jpeg", "image/gif", "image/webp":
-									mediaType = "img"
-									mediaTypeStyle = mediaTypeStyle.Background(t.Accent())
-								case "application/pdf":
-									mediaType = "pdf"
-									mediaTypeStyle = mediaTypeStyle.Background(t.Primary())
-								}
-								flexItems = append(flexItems, layout.FlexItem{
-									View: mediaTypeStyle.Render(mediaType) + fileStyle.Render(filePart.Filename),
-								})
-							}
-						}
-						bgColor := t.BackgroundPanel()
-						files := layout.Render(
-							layout.FlexOptions{
-								Background: &bgColor,
-								Width:      width - 6,
-								Direction:  layout.Column,
-							},
-							flexItems...,
-						)
-
-						author := m.app.Config.Username
-						isQueued := casted.ID > lastAssistantMessage
-						key := m.cache.GenerateKey(casted.ID, part.Text, width, files, author, isQueued)
-						content, cached = m.cache.Get(key)
-						if !cached {
-							content = renderText(
-								m.app,
-								message.Info,
-								part.Text,
-								author,
-								m.showToolDetails,
-								width,
-								files,
-								false,
-								isQueued,
-								false,
-								fileParts,
-								agentParts,
-							)
-							m.cache.Set(key, content)
-						}
-						if content != "" {
-							partCount++
-							lineCount += lipgloss.Height(content) + 1
-							blocks = append(blocks, content)
-						}
-					}
-				}
-
-			case sgptcoder.AssistantMessage:
-				if casted.Summary {
-					continue
-				}
-				if casted.ID == m.app.Session.Revert.MessageID {
-					reverted = true
-					revertedMessageCount = 1
-					revertedToolCount = 0
-				}
-				hasTextPart := false
-				hasContent := false
-				for partIndex, p := range message.Parts {
-					switch part := p.(type) {
-					case sgptcoder.TextPart:
-						if reverted {
-							continue
-						}
-						if strings.TrimSpace(part.Text) == "" {
-							continue
-						}
-						hasTextPart = true
-						finished := part.Time.End > 0
-						remainingParts := message.Parts[partIndex+1:]
-						toolCallParts := make([]sgptcoder.ToolPart, 0)
-
-						// sometimes tool calls happen without an assistant message
-						// these should be included in this assistant message as well
-						if len(orphanedToolCalls) > 0 {
-							toolCallParts = append(toolCallParts, orphanedToolCalls...)
-							orphanedToolCalls = make([]sgptcoder.ToolPart, 0)
-						}
-
-						remaining := true
-						for _, part := range remainingParts {
-							if !remaining {
-								break
-							}
-							switch part := part.(type) {
-							case sgptcoder.TextPart:
-								// we only want tool calls associated with the current text part.
-								// if we hit another text part, we're done.
-								remaining = false
-							case sgptcoder.ToolPart:
-								toolCallParts = append(toolCallParts, part)
-								if part.State.Status != sgptcoder.ToolPartStateStatusCompleted && part.State.Status != sgptcoder.ToolPartStateStatusError {
-									// i don't think there's a case where a tool call isn't in result state
-									// and the message time is 0, but just in case
-									finished = false
-								}
-							}
-						}
-
-						if finished {
-							key := m.cache.GenerateKey(casted.ID, part.Text, width, m.showToolDetails, toolCallParts)
-							content, cached = m.cache.Get(key)
-							if !cached {
-								content = renderText(
-									m.app,
-									message.Info,
-									part.Text,
-									casted.ModelID,
-									m.showToolDetails,
-									width,
-									"",
-									false,
-									false,
-									false,
-									[]sgptcoder.FilePart{},
-									[]sgptcoder.AgentPart{},
-									toolCallParts...,
-								)
-								m.cache.Set(key, content)
-							}
-						} else {
-							content = renderText(
-								m.app,
-								message.Info,
-								part.Text,
-								casted.ModelID,
-								m.showToolDetails,
-								width,
-								"",
-								false,
-								false,
-								false,
-								[]sgptcoder.FilePart{},
-								[]sgptcoder.AgentPart{},
-								toolCallParts...,
-							)
-						}
-						if content != "" {
-							partCount++
-							lineCount += lipgloss.Height(content) + 1
-							blocks = append(blocks, content)
-							hasContent = true
-						}
-					case sgptcoder.ToolPart:
-						if reverted {
-							revertedToolCount++
-							continue
-						}
-
-						permission := sgptcoder.Permission{}
-						if m.app.CurrentPermission.CallID == part.CallID {
-							permission = m.app.CurrentPermission
-						}
-
-						if !m.showToolDetails && permission.ID == "" {
-							if !hasTextPart {
-								orphanedToolCalls = append(orphanedToolCalls, part)
-							}
-							continue
-						}
-
-						if part.State.Status == sgptcoder.ToolPartStateStatusCompleted || part.State.Status == sgptcoder.ToolPartStateStatusError {
-							key := m.cache.GenerateKey(casted.ID,
-								part.ID,
-								m.showToolDetails,
-								width,
-								permission.ID,
-							)
-							content, cached = m.cache.Get(key)
-							if !cached {
-								content = renderToolDetails(
-									m.app,
-									part,
-									permission,
-									width,
-								)
-								m.cache.Set(key, content)
-							}
-						} else {
-							// if the tool call isn't finished, don't cache
-							content = renderToolDetails(
-								m.app,
-								part,
-								permission,
-								width,
-							)
-						}
-						if content != "" {
-							partCount++
-							lineCount += lipgloss.Height(content) + 1
-							blocks = append(blocks, content)
-							hasContent = true
-						}
-					case sgptcoder.ReasoningPart:
-						if reverted {
-							continue
-						}
-						if !m.showThinkingBlocks {
-							continue
-						}
-						if part.Text != "" {
-							text := part.Text
-							shimmer := part.Time.End == 0 && part.ID == lastStreamingReasoningID
-							content = renderText(
-								m.app,
-								message.Info,
-								text,
-								casted.ModelID,
-								m.showToolDetails,
-								width,
-								"",
-								true,
-								false,
-								shimmer,
-								[]sgptcoder.FilePart{},
-								[]sgptcoder.AgentPart{},
-							)
-							partCount++
-							lineCount += lipgloss.Height(content) + 1
-							blocks = append(blocks, content)
-							hasContent = true
-						}
-					}
-				}
-
-				switch err := casted.Error.AsUnion().(type) {
-				case nil:
-				case sgptcoder.AssistantMessageErrorMessageOutputLengthError:
-					error = "Message output length exceeded"
-				case sgptcoder.ProviderAuthError:
-					error = err.Data.Message
-				case sgptcoder.MessageAbortedError:
-					error = "Request was aborted"
-				case sgptcoder.UnknownError:
-					error = err.Data.Message
-				}
-
-				if !hasContent && error == "" && !reverted {
-					content = renderText(
-						m.app,
-						message.Info,
-						"Generating...",
-						casted.ModelID,
-						m.showToolDetails,
-						width,
-						"",
-						false,
-						false,
-						false,
-						[]sgptcoder.FilePart{},
-						[]sgptcoder.AgentPart{},
-					)
-					partCount++
-					lineCount += lipgloss.Height(content) + 1
-					blocks = append(blocks, content)
-				}
+		// Render the messages that changed, reusing the others
+		var state renderState
+		blocks := make(map[string]*messageBlock, len(m.app.Messages))
+		lines := []string{""}
+		kinds := []lineKind{lineBorder}
+		starts := make([]messageStart, 0, len(m.app.Messages))
+		items := make([]foldable, 0, len(m.app.Messages))
+		messagePositions := make(map[string]int) // Track message ID to line position
+		partCount := 0
+		for _, message := range m.app.Messages {
+			id := mirror.MessageID(message.Info)
+			starts = append(starts, messageStart{id: id, line: len(lines) - 1})
+			messagePositions[id] = len(lines) - 1
+
+			block, ok := previous[id]
+			if !ok || stale[id] || block.options != opts || !block.in.equal(state) {
+				block = &messageBlock{options: opts, in: state.clone()}
+				rendered := m.renderMessage(message, opts, folds, &state)
+				block.lines, block.kinds, block.items = layoutBlocks(id, rendered)
+				block.parts = len(rendered)
+				block.out = state.clone()
+			} else {
+				state = block.out.clone()
 			}
-
-			if error != "" && !reverted {
-				error = styles.NewStyle().Width(width - 6).Render(error)
-				error = renderContentBlock(
-					m.app,
-					error,
-					width,
-					WithBorderColor(t.Error()),
-				)
-				blocks = append(blocks, error)
-				lineCount += lipgloss.Height(error) + 1
+			blocks[id] = block
+			for _, item := range block.items {
+				item.start += len(lines)
+				item.end += len(lines)
+				if item.title >= 0 {
+					item.title += len(lines)
+				}
+				items = append(items, item)
 			}
+			lines = append(lines, block.lines...)
+			kinds = append(kinds, block.kinds...)
+			partCount += block.parts
 		}
+		lineCount := len(lines) - 1
+
+		t := theme.CurrentTheme()
+		tail := []string{}
 
-		if revertedMessageCount > 0 || revertedToolCount > 0 {
+		if state.revertedMessageCount > 0 || state.revertedToolCount > 0 {
 			messagePlural := ""
 			toolPlural := ""
-			if revertedMessageCount != 1 {
+			if state.revertedMessageCount != 1 {
 				messagePlural = "s"
 			}
-			if revertedToolCount != 1 {
+			if state.revertedToolCount != 1 {
 				toolPlural = "s"
 			}
 			revertedStyle := styles.NewStyle().
@@ -716,9 +655,9 @@ func (m *messagesComponent) renderView() tea.Cmd {
 
 			content := revertedStyle.Render(fmt.Sprintf(
 				"%d message%s reverted, %d tool call%s reverted",
-				revertedMessageCount,
+				state.revertedMessageCount,
 				messagePlural,
-				revertedToolCount,
+				state.revertedToolCount,
 				toolPlural,
 			))
 			hintStyle := styles.NewStyle().Background(t.BackgroundPanel()).Foreground(t.Text())
@@ -758,15 +697,15 @@ func (m *messagesComponent) renderView() tea.Cmd {
 
 			content = styles.NewStyle().
 				Background(t.BackgroundPanel()).
-				Width(width - 6).
+				Width(opts.width - 6).
 				Render(content)
 			content = renderContentBlock(
 				m.app,
 				content,
-				width,
+				opts.width,
 				WithBorderColor(t.BackgroundPanel()),
 			)
-			blocks = append(blocks, content)
+			tail = append(tail, content)
 		}
 
 		if m.app.CurrentPermission.ID != "" &&
@@ -787,12 +726,11 @@ func (m *messagesComponent) renderView() tea.Cmd {
 								m.app,
 								toolPart,
 								m.app.CurrentPermission,
-								width,
+								opts.width,
 							)
 							if content != "" {
 								partCount++
-								lineCount += lipgloss.Height(content) + 1
-								blocks = append(blocks, content)
+								tail = append(tail, content)
 							}
 						}
 					}
@@ -800,67 +738,399 @@ func (m *messagesComponent) renderView() tea.Cmd {
 			}
 		}
 
-		final := []string{}
-		clipboard := []string{}
-		var selection *selection
-		if m.selection != nil {
-			selection = m.selection.coords(lipgloss.Height(header) + 1)
+		lines, kinds = appendBlocks(lines, kinds, tail...)
+
+		return renderCompleteMsg{
+			generation:       generation,
+			header:           header,
+			blocks:           blocks,
+			lines:            lines,
+			kinds:            kinds,
+			starts:           starts,
+			items:            items,
+			partCount:        partCount,
+			lineCount:        lineCount,
+			messagePositions: messagePositions,
 		}
-		for _, block := range blocks {
-			lines := strings.Split(block, "\n")
-			for index, line := range lines {
-				if selection == nil || index == 0 || index == len(lines)-1 {
-					final = append(final, line)
+	}
+}
+
+// renderMessage renders the blocks of a message, advancing the state carried
+// from one message to the next. A folded message is rendered as its first
+// line of text.
+func (m *messagesComponent) renderMessage(
+	message app.Message,
+	opts renderOptions,
+	folds map[string]bool,
+	state *renderState,
+) []renderedBlock {
+	blocks := m.renderParts(message, opts, folds, state)
+	id := mirror.MessageID(message.Info)
+	if len(blocks) > 0 && folded(folds, id, false) {
+		return []renderedBlock{{
+			content: renderFoldedMessage(m.app, message, opts.width),
+			fold:    id,
+			kind:    foldMessage,
+		}}
+	}
+	return blocks
+}
+
+// renderParts renders the blocks of the parts of a message.
+func (m *messagesComponent) renderParts(
+	message app.Message,
+	opts renderOptions,
+	folds map[string]bool,
+	state *renderState,
+) []renderedBlock {
+	t := theme.CurrentTheme()
+	width := opts.width
+	showToolDetails := opts.showToolDetails
+	showThinkingBlocks := opts.showThinkingBlocks
+	blocks := make([]renderedBlock, 0)
+
+	var content string
+	var cached bool
+	error := ""
+
+	switch casted := message.Info.(type) {
+	case sgptcoder.UserMessage:
+		if casted.ID == m.app.Session.Revert.MessageID {
+			state.reverted = true
+			state.revertedMessageCount = 1
+			state.revertedToolCount = 0
+			return blocks
+		}
+		if state.reverted {
+			state.revertedMessageCount++
+			return blocks
+		}
+
+		for partIndex, part := range message.Parts {
+			switch part := part.(type) {
+			case sgptcoder.TextPart:
+				if part.Synthetic {
+					continue
+				}
+				if part.Text == "" {
 					continue
 				}
-				y := len(final)
-				if y >= selection.startY && y <= selection.endY {
-					left := 3
-					if y == selection.startY {
-						left = selection.startX - 2
+				remainingParts := message.Parts[partIndex+1:]
+				fileParts := make([]sgptcoder.FilePart, 0)
+				agentParts := make([]sgptcoder.AgentPart, 0)
+				for _, part := range remainingParts {
+					switch part := part.(type) {
+					case sgptcoder.FilePart:
+						if part.Source.Text.Start >= 0 && part.Source.Text.End >= part.Source.Text.Start {
+							fileParts = append(fileParts, part)
+						}
+					case sgptcoder.AgentPart:
+						if part.Source.Start >= 0 && part.Source.End >= part.Source.Start {
+							agentParts = append(agentParts, part)
+						}
 					}
-					left = max(3, left)
-
-					width := ansi.StringWidth(line)
-					right := width - 1
-					if y == selection.endY {
-						right = min(selection.endX-2, right)
+				}
+				flexItems := []layout.FlexItem{}
+				if len(fileParts) > 0 {
+					fileStyle := styles.NewStyle().Background(t.BackgroundElement()).Foreground(t.TextMuted()).Padding(0, 1)
+					mediaTypeStyle := styles.NewStyle().Background(t.Secondary()).Foreground(t.BackgroundPanel()).Padding(0, 1)
+					for _, filePart := range fileParts {
+						mediaType := ""
+						switch filePart.Mime {
+						case "text/plain":
+							mediaType = "txt"
+						case "image/png", "image/jpeg", "image/gif", "image/webp":
+							mediaType = "img"
+							mediaTypeStyle = mediaTypeStyle.Background(t.Accent())
+						case "application/pdf":
+							mediaType = "pdf"
+							mediaTypeStyle = mediaTypeStyle.Background(t.Primary())
+						}
+						flexItems = append(flexItems, layout.FlexItem{
+							View: mediaTypeStyle.Render(mediaType) + fileStyle.Render(filePart.Filename),
+						})
 					}
+				}
+				bgColor := t.BackgroundPanel()
+				files := layout.Render(
+					layout.FlexOptions{
+						Background: &bgColor,
+						Width:      width - 6,
+						Direction:  layout.Column,
+					},
+					flexItems...,
+				)
 
-					prefix := ansi.Cut(line, 0, left)
-					middle := strings.TrimRight(ansi.Strip(ansi.Cut(line, left, right)), " ")
-					suffix := ansi.Cut(line, left+ansi.StringWidth(middle), width)
-					clipboard = append(clipboard, middle)
-					line = prefix + styles.NewStyle().
-						Background(t.Accent()).
-						Foreground(t.BackgroundPanel()).
-						Render(ansi.Strip(middle)) +
-						suffix
+				author := m.app.Config.Username
+				isQueued := casted.ID > opts.lastAssistantMessage
+				key := m.cache.GenerateKey(casted.ID, part.Text, width, files, author, isQueued)
+				content, cached = m.cache.Get(key)
+				if !cached {
+					content = renderText(
+						m.app,
+						message.Info,
+						part.Text,
+						author,
+						showToolDetails,
+						width,
+						files,
+						false,
+						isQueued,
+						false,
+						fileParts,
+						agentParts,
+					)
+					m.cache.Set(key, content)
+				}
+				if content != "" {
+					blocks = append(blocks, renderedBlock{content: content})
 				}
-				final = append(final, line)
 			}
-			y := len(final)
-			if selection != nil && y >= selection.startY && y < selection.endY {
-				clipboard = append(clipboard, "")
+		}
+
+	case sgptcoder.AssistantMessage:
+		if casted.Summary {
+			return blocks
+		}
+		if casted.ID == m.app.Session.Revert.MessageID {
+			state.reverted = true
+			state.revertedMessageCount = 1
+			state.revertedToolCount = 0
+		}
+		hasTextPart := false
+		hasContent := false
+		codeBlocks := 0
+		for partIndex, p := range message.Parts {
+			switch part := p.(type) {
+			case sgptcoder.TextPart:
+				if state.reverted {
+					continue
+				}
+				if strings.TrimSpace(part.Text) == "" {
+					continue
+				}
+				hasTextPart = true
+				text := part.Text
+				if casted.ID == opts.codeBlocksMessageID {
+					text = numberCodeBlocks(part.Text, codeBlocks)
+					codeBlocks += len(util.CodeBlocks(part.Text))
+				}
+				finished := part.Time.End > 0
+				remainingParts := message.Parts[partIndex+1:]
+				toolCallParts := make([]sgptcoder.ToolPart, 0)
+				listed := make([]sgptcoder.ToolPart, 0)
+
+				// sometimes tool calls happen without an assistant message
+				// these should be included in this assistant message as well
+				if len(state.orphanedToolCalls) > 0 {
+					toolCallParts = append(toolCallParts, state.orphanedToolCalls...)
+					listed = append(listed, state.orphanedToolCalls...)
+					state.orphanedToolCalls = make([]sgptcoder.ToolPart, 0)
+				}
+
+				remaining := true
+				for _, part := range remainingParts {
+					if !remaining {
+						break
+					}
+					switch part := part.(type) {
+					case sgptcoder.TextPart:
+						// we only want tool calls associated with the current text part.
+						// if we hit another text part, we're done.
+						remaining = false
+					case sgptcoder.ToolPart:
+						toolCallParts = append(toolCallParts, part)
+						// tool calls unfolded on their own are rendered in full
+						if !showToolDetails && folded(folds, part.ID, true) {
+							listed = append(listed, part)
+						}
+						if part.State.Status != sgptcoder.ToolPartStateStatusCompleted && part.State.Status != sgptcoder.ToolPartStateStatusError {
+							// i don't think there's a case where a tool call isn't in result state
+							// and the message time is 0, but just in case
+							finished = false
+						}
+					}
+				}
+
+				if finished {
+					key := m.cache.GenerateKey(casted.ID, text, width, showToolDetails, listed)
+					content, cached = m.cache.Get(key)
+					if !cached {
+						content = renderText(
+							m.app,
+							message.Info,
+							text,
+							casted.ModelID,
+							showToolDetails,
+							width,
+							"",
+							false,
+							false,
+							false,
+							[]sgptcoder.FilePart{},
+							[]sgptcoder.AgentPart{},
+							listed...,
+						)
+						m.cache.Set(key, content)
+					}
+				} else {
+					content = renderText(
+						m.app,
+						message.Info,
+						text,
+						casted.ModelID,
+						showToolDetails,
+						width,
+						"",
+						false,
+						false,
+						false,
+						[]sgptcoder.FilePart{},
+						[]sgptcoder.AgentPart{},
+						listed...,
+					)
+				}
+				if content != "" {
+					blocks = append(blocks, renderedBlock{content: content, tools: listed})
+					hasContent = true
+				}
+			case sgptcoder.ToolPart:
+				if state.reverted {
+					state.revertedToolCount++
+					continue
+				}
+
+				permission := sgptcoder.Permission{}
+				if m.app.CurrentPermission.CallID == part.CallID {
+					permission = m.app.CurrentPermission
+				}
+
+				fold := folded(folds, part.ID, !showToolDetails) && permission.ID == ""
+				if fold && !showToolDetails {
+					if !hasTextPart {
+						state.orphanedToolCalls = append(state.orphanedToolCalls, part)
+					}
+					continue
+				}
+				if fold {
+					blocks = append(blocks, renderedBlock{
+						content: renderContentBlock(m.app, renderToolTitle(part, width), width),
+						fold:    part.ID,
+						kind:    foldTool,
+					})
+					hasContent = true
+					continue
+				}
+
+				if part.State.Status == sgptcoder.ToolPartStateStatusCompleted || part.State.Status == sgptcoder.ToolPartStateStatusError {
+					key := m.cache.GenerateKey(casted.ID,
+						part.ID,
+						showToolDetails,
+						width,
+						permission.ID,
+					)
+					content, cached = m.cache.Get(key)
+					if !cached {
+						content = renderToolDetails(
+							m.app,
+							part,
+							permission,
+							width,
+						)
+						m.cache.Set(key, content)
+					}
+				} else {
+					// if the tool call isn't finished, don't cache
+					content = renderToolDetails(
+						m.app,
+						part,
+						permission,
+						width,
+					)
+				}
+				if content != "" {
+					blocks = append(blocks, renderedBlock{content: content, fold: part.ID, kind: foldTool})
+					hasContent = true
+				}
+			case sgptcoder.ReasoningPart:
+				if state.reverted {
+					continue
+				}
+				if !showThinkingBlocks {
+					continue
+				}
+				if part.Text != "" && folded(folds, part.ID, false) {
+					blocks = append(blocks, renderedBlock{
+						content: renderFoldedThinking(m.app, width),
+						fold:    part.ID,
+						kind:    foldThinking,
+					})
+					hasContent = true
+				} else if part.Text != "" {
+					text := part.Text
+					shimmer := part.Time.End == 0 && part.ID == opts.lastStreamingReasoningID
+					content = renderText(
+						m.app,
+						message.Info,
+						text,
+						casted.ModelID,
+						showToolDetails,
+						width,
+						"",
+						true,
+						false,
+						shimmer,
+						[]sgptcoder.FilePart{},
+						[]sgptcoder.AgentPart{},
+					)
+					blocks = append(blocks, renderedBlock{content: content, fold: part.ID, kind: foldThinking})
+					hasContent = true
+				}
 			}
-			final = append(final, "")
 		}
-		content := "\n" + strings.Join(final, "\n")
-		viewport.SetHeight(m.height - lipgloss.Height(header))
-		viewport.SetContent(content)
-		if tail {
-			viewport.GotoBottom()
+
+		switch err := casted.Error.AsUnion().(type) {
+		case nil:
+		case sgptcoder.AssistantMessageErrorMessageOutputLengthError:
+			error = "Message output length exceeded"
+		case sgptcoder.ProviderAuthError:
+			error = err.Data.Message
+		case sgptcoder.MessageAbortedError:
+			error = "Request was aborted"
+		case sgptcoder.UnknownError:
+			error = err.Data.Message
 		}
 
-		return renderCompleteMsg{
-			header:           header,
-			clipboard:        clipboard,
-			viewport:         viewport,
-			partCount:        partCount,
-			lineCount:        lineCount,
-			messagePositions: messagePositions,
+		if !hasContent && error == "" && !state.reverted {
+			content = renderText(
+				m.app,
+				message.Info,
+				"Generating...",
+				casted.ModelID,
+				showToolDetails,
+				width,
+				"",
+				false,
+				false,
+				false,
+				[]sgptcoder.FilePart{},
+				[]sgptcoder.AgentPart{},
+			)
+			blocks = append(blocks, renderedBlock{content: content})
 		}
 	}
+
+	if error != "" && !state.reverted {
+		error = styles.NewStyle().Width(width - 6).Render(error)
+		error = renderContentBlock(
+			m.app,
+			error,
+			width,
+			WithBorderColor(t.Error()),
+		)
+		blocks = append(blocks, renderedBlock{content: error})
+	}
+	return blocks
 }
 
 func (m *messagesComponent) renderHeader() string {
@@ -910,7 +1180,8 @@ func (m *messagesComponent) renderHeader() string {
 	isSubscriptionModel := m.app.Model != nil &&
 		m.app.Model.Cost.Input == 0 && m.app.Model.Cost.Output == 0
 
-	sessionInfoText := formatTokensAndCost(tokens, contextWindow, cost, isSubscriptionModel)
+	// The cost of older messages isn't known until they are loaded
+	sessionInfoText := formatTokensAndCost(tokens, contextWindow, cost, isSubscriptionModel, m.app.HasOlderMessages)
 	sessionInfo = styles.NewStyle().
 		Foreground(t.TextMuted()).
 		Background(bgColor).
@@ -1000,6 +1271,7 @@ func formatTokensAndCost(
 	contextWindow float64,
 	cost float64,
 	isSubscriptionModel bool,
+	partialCost bool,
 ) string {
 	// Format tokens in human-readable format (e.g., 110K, 1.2M)
 	var formattedTokens string
@@ -1034,6 +1306,9 @@ func formatTokensAndCost(
 	}
 
 	formattedCost := fmt.Sprintf("$%.2f", cost)
+	if partialCost {
+		formattedCost += "+"
+	}
 	return fmt.Sprintf(
 		" %s/%d%% (%s)",
 		formattedTokens,
@@ -1058,6 +1333,18 @@ func (m *messagesComponent) View() string {
 	}
 
 	viewport := m.viewport.View()
+	if m.search != nil {
+		viewport += "\n" + m.search.view(
+			m.width,
+			m.viewport.HighlightIndex(),
+			m.viewport.HighlightCount(),
+			m.showThinkingBlocks,
+			!m.app.HasOlderMessages,
+		)
+	}
+	if m.cursor != "" {
+		viewport += "\n" + m.cursorView()
+	}
 	return styles.NewStyle().
 		Background(bgColor).
 		Render(m.header + "\n" + viewport)
@@ -1065,22 +1352,22 @@ func (m *messagesComponent) View() string {
 
 func (m *messagesComponent) PageUp() (tea.Model, tea.Cmd) {
 	m.viewport.ViewUp()
-	return m, nil
+	return m, m.scrolled()
 }
 
 func (m *messagesComponent) PageDown() (tea.Model, tea.Cmd) {
 	m.viewport.ViewDown()
-	return m, nil
+	return m, m.scrolled()
 }
 
 func (m *messagesComponent) HalfPageUp() (tea.Model, tea.Cmd) {
 	m.viewport.HalfViewUp()
-	return m, nil
+	return m, m.scrolled()
 }
 
 func (m *messagesComponent) HalfPageDown() (tea.Model, tea.Cmd) {
 	m.viewport.HalfViewDown()
-	return m, nil
+	return m, m.scrolled()
 }
 
 func (m *messagesComponent) ToolDetailsVisible() bool {
@@ -1092,12 +1379,12 @@ func (m *messagesComponent) ThinkingBlocksVisible() bool {
 }
 
 func (m *messagesComponent) GotoTop() (tea.Model, tea.Cmd) {
-	m.viewport.GotoTop()
-	return m, nil
+	m.setWindow(0)
+	return m, m.scrolled()
 }
 
 func (m *messagesComponent) GotoBottom() (tea.Model, tea.Cmd) {
-	m.viewport.GotoBottom()
+	m.gotoBottom()
 	return m, nil
 }
 
@@ -1121,6 +1408,33 @@ func (m *messagesComponent) CopyLastMessage() (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// CodeBlocks returns the code blocks of the latest reply that has any, in the
+// order they are numbered in.
+func (m *messagesComponent) CodeBlocks() []util.CodeBlock {
+	_, blocks := latestCodeBlocks(m.app.Messages, m.app.Session.Revert.MessageID)
+	return blocks
+}
+
+// latestCodeBlocks returns the ID and the code blocks of the latest reply that
+// has any before the reverted messages. Only that reply is labeled, since it
+// is the one /yank copies from.
+func latestCodeBlocks(messages []app.Message, revertMessageID string) (string, []util.CodeBlock) {
+	if i := slices.IndexFunc(messages, func(message app.Message) bool {
+		return mirror.MessageID(message.Info) == revertMessageID
+	}); i >= 0 {
+		messages = messages[:i]
+	}
+	for _, message := range slices.Backward(messages) {
+		if _, ok := message.Info.(sgptcoder.AssistantMessage); !ok {
+			continue
+		}
+		if blocks := util.CodeBlocks(messageText(message)); len(blocks) > 0 {
+			return mirror.MessageID(message.Info), blocks
+		}
+	}
+	return "", nil
+}
+
 func (m *messagesComponent) UndoLastMessage() (tea.Model, tea.Cmd) {
 	after := float64(0)
 	var revertedMessage app.Message
@@ -1284,10 +1598,253 @@ func (m *messagesComponent) ScrollToMessage(messageID string) (tea.Model, tea.Cm
 	}
 
 	if position, exists := m.messagePositions[messageID]; exists {
-		m.viewport.SetYOffset(position)
+		m.setWindow(position)
 		m.tail = false // Stop auto-scrolling to bottom when manually navigating
 	}
-	return m, nil
+	return m, m.scrolled()
+}
+
+func (m *messagesComponent) StartSearch() (tea.Model, tea.Cmd) {
+	if m.cursor != "" {
+		m.closeCursor()
+	}
+	if m.search == nil {
+		m.search = newMessageSearch()
+		m.viewport.SetHeight(m.viewport.Height() - searchBarHeight)
+		// Search through the whole conversation
+		m.setWindow(m.offset())
+	}
+	return m, tea.Batch(m.renderView(), m.loadAll())
+}
+
+func (m *messagesComponent) Searching() bool {
+	return m.search != nil
+}
+
+func (m *messagesComponent) updateSearch(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.search = nil
+		m.viewport.SetHeight(m.viewport.Height() + searchBarHeight)
+		m.setWindow(m.offset())
+		return m, m.renderView()
+	case "enter", "down", "ctrl+n":
+		m.viewport.HighlightNext()
+		m.tail = false
+		return m, nil
+	case "shift+enter", "up", "ctrl+p":
+		if m.viewport.HighlightIndex() < 0 {
+			m.viewport.SelectHighlight(m.viewport.HighlightCount() - 1)
+		} else {
+			m.viewport.HighlightPrevious()
+		}
+		m.tail = false
+		return m, nil
+	case "ctrl+t":
+		m.search.thinking = !m.search.thinking
+		return m, m.renderView()
+	}
+
+	changed, cmd := m.search.update(msg)
+	if changed {
+		m.highlight(false)
+		m.tail = false
+	}
+	return m, cmd
+}
+
+// highlight highlights the matches of the search in the rendered messages and
+// scrolls to the first match below the top of the view. Keeping the selection
+// reselects the match at the same index without scrolling, for re-renders.
+func (m *messagesComponent) highlight(keep bool) {
+	index := m.viewport.HighlightIndex()
+	yOffset := m.viewport.YOffset
+	m.viewport.ClearHighlights()
+	m.viewport.SetHighlights(findMatches(m.viewport.GetContent(), m.search.input.Value()))
+	if keep && index >= 0 {
+		m.viewport.SelectHighlight(min(index, m.viewport.HighlightCount()-1))
+		m.viewport.SetYOffset(yOffset)
+	}
+}
+
+// windowPages is how many pages of lines above and below the view are given
+// to the viewport along with the visible ones.
+const windowPages = 3
+
+// offset returns the line of the conversation at the top of the view.
+func (m *messagesComponent) offset() int {
+	return m.windowStart + m.viewport.YOffset
+}
+
+func (m *messagesComponent) maxOffset() int {
+	return max(0, len(m.lines)-m.viewport.Height())
+}
+
+func (m *messagesComponent) atBottom() bool {
+	return m.windowEnd >= len(m.lines) && m.viewport.AtBottom()
+}
+
+func (m *messagesComponent) viewportHeight() int {
+	height := m.height - lipgloss.Height(m.header)
+	if m.search != nil {
+		height -= searchBarHeight
+	}
+	if m.cursor != "" {
+		height -= cursorBarHeight
+	}
+	return height
+}
+
+func (m *messagesComponent) gotoBottom() {
+	m.setWindow(m.maxOffset())
+	m.tail = true
+}
+
+// setWindow scrolls to the given line, giving the viewport the lines around
+// it, or all of them while searching so that every match can be highlighted.
+func (m *messagesComponent) setWindow(offset int) {
+	height := m.viewport.Height()
+	offset = max(0, min(offset, m.maxOffset()))
+	start, end := 0, len(m.lines)
+	if m.search == nil {
+		margin := height * windowPages
+		start = max(0, offset-margin)
+		end = min(len(m.lines), offset+height+margin)
+	}
+	window := make([]string, end-start)
+	copy(window, m.lines[start:end])
+	m.clipboard = m.selectLines(window, start)
+	m.markCursor(window, start)
+	m.windowStart, m.windowEnd = start, end
+	m.viewport.SetContentLines(window)
+	m.viewport.SetYOffset(offset - start)
+}
+
+// refresh gives the viewport its lines again, such as after the selection
+// changed.
+func (m *messagesComponent) refresh() {
+	m.setWindow(m.offset())
+	if m.search != nil {
+		m.highlight(true)
+	}
+}
+
+// scrolled moves the window along after scrolling, and loads older messages
+// once the top of the loaded ones comes into view.
+func (m *messagesComponent) scrolled() tea.Cmd {
+	offset := m.offset()
+	height := m.viewport.Height()
+	margin := height * windowPages
+	nearStart := m.windowStart > 0 && offset-m.windowStart < margin/2
+	nearEnd := m.windowEnd < len(m.lines) && m.windowEnd-offset-height < margin/2
+	if m.search == nil && (nearStart || nearEnd) {
+		m.setWindow(offset)
+	}
+	m.tail = m.atBottom()
+
+	if offset < height && m.app.HasOlderMessages && !m.loadingOlder && !m.olderFailed && !m.loading {
+		m.loadingOlder = true
+		return m.app.LoadOlderMessages()
+	}
+	return nil
+}
+
+// loadAll loads the messages not loaded yet, for searching the whole
+// conversation.
+func (m *messagesComponent) loadAll() tea.Cmd {
+	if !m.app.HasOlderMessages || m.loadingOlder || m.olderFailed {
+		return nil
+	}
+	m.loadingOlder = true
+	return m.app.LoadAllOlderMessages()
+}
+
+// anchor returns the message at the given line and how far into it the line
+// is.
+func (m *messagesComponent) anchor(line int) (string, int) {
+	i := sort.Search(len(m.starts), func(i int) bool { return m.starts[i].line > line })
+	if i == 0 {
+		return "", 0
+	}
+	return m.starts[i-1].id, line - m.starts[i-1].line
+}
+
+// selectLines highlights the selected lines of a window starting at the given
+// line, and returns the selected text.
+func (m *messagesComponent) selectLines(window []string, start int) []string {
+	if m.selection == nil {
+		return nil
+	}
+	t := theme.CurrentTheme()
+	selection := m.selection.coords(lipgloss.Height(m.header) + 1)
+	clipboard := []string{}
+	for i, line := range window {
+		// The selection counts lines from below the leading blank line
+		y := start + i - 1
+		switch m.kinds[start+i] {
+		case lineBorder:
+			continue
+		case lineGap:
+			if y >= selection.startY && y < selection.endY {
+				clipboard = append(clipboard, "")
+			}
+			continue
+		case lineLabel:
+			continue
+		}
+		if y < selection.startY || y > selection.endY {
+			continue
+		}
+		left := 3
+		if y == selection.startY {
+			left = selection.startX - 2
+		}
+		left = max(3, left)
+
+		width := ansi.StringWidth(line)
+		right := width - 1
+		if y == selection.endY {
+			right = min(selection.endX-2, right)
+		}
+
+		prefix := ansi.Cut(line, 0, left)
+		middle := strings.TrimRight(ansi.Strip(ansi.Cut(line, left, right)), " ")
+		suffix := ansi.Cut(line, left+ansi.StringWidth(middle), width)
+		clipboard = append(clipboard, middle)
+		window[i] = prefix + styles.NewStyle().
+			Background(t.Accent()).
+			Foreground(t.BackgroundPanel()).
+			Render(ansi.Strip(middle)) +
+			suffix
+	}
+	return clipboard
+}
+
+// markAnimating marks the messages still in progress as changed, so they are
+// rendered again with the next frame of their animation.
+func (m *messagesComponent) markAnimating() {
+	for _, message := range m.app.Messages {
+		animating := false
+		if assistant, ok := message.Info.(sgptcoder.AssistantMessage); ok && assistant.Time.Completed == 0 {
+			animating = true
+		}
+		for _, part := range message.Parts {
+			if tool, ok := part.(sgptcoder.ToolPart); ok &&
+				(tool.State.Status == sgptcoder.ToolPartStateStatusPending || tool.State.Status == sgptcoder.ToolPartStateStatusRunning) {
+				animating = true
+			}
+		}
+		if animating {
+			m.stale[mirror.MessageID(message.Info)] = true
+		}
+	}
+}
+
+// clearCache drops every rendered part and message.
+func (m *messagesComponent) clearCache() {
+	m.cache.Clear()
+	m.blocks = nil
+	m.generation++
 }
 
 func NewMessagesComponent(app *app.App) MessagesComponent {
@@ -1319,5 +1876,6 @@ func NewMessagesComponent(app *app.App) MessagesComponent {
 		cache:              NewPartCache(),
 		tail:               true,
 		messagePositions:   make(map[string]int),
+		stale:              make(map[string]bool),
 	}
 }
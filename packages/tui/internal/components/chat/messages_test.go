@@ -0,0 +1,236 @@
+package chat
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder/internal/app"
+	"github.com/skorpland/sgptcoder/internal/viewport"
+)
+
+// testMessages returns a messages component showing count lines, with a view
+// of the given height.
+func testMessages(count int, height int) *messagesComponent {
+	lines := make([]string, count)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i)
+	}
+	vp := viewport.New()
+	vp.SetWidth(20)
+	vp.SetHeight(height)
+	return &messagesComponent{
+		app: &app.App{
+			Session:  &sgptcoder.Session{ID: "ses_1"},
+			Messages: []app.Message{{Info: sgptcoder.UserMessage{ID: "msg_1"}}},
+		},
+		viewport: vp,
+		lines:    lines,
+		kinds:    make([]lineKind, count),
+	}
+}
+
+func TestLayoutBlocks(t *testing.T) {
+	tool := sgptcoder.ToolPart{ID: "prt_tool", MessageID: "msg_1"}
+	tests := []struct {
+		name   string
+		blocks []renderedBlock
+		lines  []string
+		kinds  []lineKind
+		items  []foldable
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:   "message title",
+			blocks: []renderedBlock{{content: "top\ntitle\nbottom", fold: "msg_1"}},
+			lines:  []string{"top", "title", "bottom", ""},
+			kinds:  []lineKind{lineBorder, lineContent, lineBorder, lineGap},
+			items:  []foldable{{id: "msg_1", message: "msg_1", kind: foldMessage, title: 1, end: 2}},
+		},
+		{
+			name: "thinking block after text",
+			blocks: []renderedBlock{
+				{content: "top\ntext\nbottom"},
+				{content: "top\nthinking\nmore\nbottom", fold: "prt_thinking", kind: foldThinking},
+			},
+			lines: []string{"top", "text", "bottom", "", "top", "thinking", "more", "bottom", ""},
+			kinds: []lineKind{
+				lineBorder, lineContent, lineBorder, lineGap,
+				lineBorder, lineContent, lineContent, lineBorder, lineGap,
+			},
+			items: []foldable{
+				{id: "msg_1", message: "msg_1", kind: foldMessage, title: -1, end: 7},
+				{id: "prt_thinking", message: "msg_1", kind: foldThinking, title: 5, start: 4, end: 7},
+			},
+		},
+		{
+			name:   "code block label",
+			blocks: []renderedBlock{{content: "top\ntext\n[1]" + codeBlockMarker + "go\ncode\nbottom"}},
+			lines:  []string{"top", "text", "[1] go", "code", "bottom", ""},
+			kinds:  []lineKind{lineBorder, lineContent, lineLabel, lineContent, lineBorder, lineGap},
+			items:  []foldable{{id: "msg_1", message: "msg_1", kind: foldMessage, title: -1, end: 4}},
+		},
+		{
+			name:   "tool calls listed under text",
+			blocks: []renderedBlock{{content: "top\ntext\n  ∟ read file\nbottom", tools: []sgptcoder.ToolPart{tool}}},
+			lines:  []string{"top", "text", "  ∟ read file", "bottom", ""},
+			kinds:  []lineKind{lineBorder, lineContent, lineContent, lineBorder, lineGap},
+			items: []foldable{
+				{id: "msg_1", message: "msg_1", kind: foldMessage, title: -1, end: 3},
+				{id: "prt_tool", message: "msg_1", kind: foldTool, title: 2, start: 2, end: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, kinds, items := layoutBlocks("msg_1", tt.blocks)
+			if !reflect.DeepEqual(lines, tt.lines) {
+				t.Errorf("lines = %q, want %q", lines, tt.lines)
+			}
+			if !reflect.DeepEqual(kinds, tt.kinds) {
+				t.Errorf("kinds = %v, want %v", kinds, tt.kinds)
+			}
+			if !reflect.DeepEqual(items, tt.items) {
+				t.Errorf("items = %+v, want %+v", items, tt.items)
+			}
+		})
+	}
+}
+
+func TestAnchor(t *testing.T) {
+	m := &messagesComponent{starts: []messageStart{{id: "msg_1", line: 0}, {id: "msg_2", line: 10}}}
+	tests := []struct {
+		line   int
+		id     string
+		offset int
+	}{
+		{line: 0, id: "msg_1"},
+		{line: 9, id: "msg_1", offset: 9},
+		{line: 10, id: "msg_2"},
+		{line: 25, id: "msg_2", offset: 15},
+		{line: -1},
+	}
+	for _, tt := range tests {
+		id, offset := m.anchor(tt.line)
+		if id != tt.id || offset != tt.offset {
+			t.Errorf("anchor(%d) = %q, %d, want %q, %d", tt.line, id, offset, tt.id, tt.offset)
+		}
+	}
+}
+
+func TestSetWindow(t *testing.T) {
+	tests := []struct {
+		name      string
+		searching bool
+		offset    int
+		start     int
+		end       int
+		top       int
+	}{
+		{name: "top", offset: 0, start: 0, end: 40, top: 0},
+		{name: "middle", offset: 50, start: 20, end: 90, top: 50},
+		{name: "past the bottom", offset: 500, start: 60, end: 100, top: 90},
+		{name: "above the top", offset: -5, start: 0, end: 40, top: 0},
+		{name: "searching", searching: true, offset: 50, start: 0, end: 100, top: 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMessages(100, 10)
+			if tt.searching {
+				m.search = &messageSearch{}
+			}
+			m.setWindow(tt.offset)
+			if m.windowStart != tt.start || m.windowEnd != tt.end {
+				t.Errorf("window = %d-%d, want %d-%d", m.windowStart, m.windowEnd, tt.start, tt.end)
+			}
+			if m.offset() != tt.top {
+				t.Errorf("offset = %d, want %d", m.offset(), tt.top)
+			}
+			if got := m.viewport.TotalLineCount(); got != tt.end-tt.start {
+				t.Errorf("viewport has %d lines, want %d", got, tt.end-tt.start)
+			}
+		})
+	}
+}
+
+func TestScrolled(t *testing.T) {
+	tests := []struct {
+		name        string
+		offset      int
+		older       bool
+		olderFailed bool
+		load        bool
+		start       int
+	}{
+		{name: "near the top loads older messages", offset: 5, older: true, load: true},
+		{name: "no older messages", offset: 5},
+		{name: "failed before", offset: 5, older: true, olderFailed: true},
+		{name: "away from the top", offset: 50, older: true, start: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMessages(100, 10)
+			m.app.HasOlderMessages = tt.older
+			m.olderFailed = tt.olderFailed
+			m.setWindow(tt.offset)
+			if cmd := m.scrolled(); (cmd != nil) != tt.load {
+				t.Errorf("loads older messages = %t, want %t", cmd != nil, tt.load)
+			}
+			if m.loadingOlder != tt.load {
+				t.Errorf("loadingOlder = %t, want %t", m.loadingOlder, tt.load)
+			}
+			if m.windowStart != tt.start {
+				t.Errorf("windowStart = %d, want %d", m.windowStart, tt.start)
+			}
+		})
+	}
+
+	// Scrolling near the edge of the window moves it along
+	m := testMessages(200, 10)
+	m.setWindow(50)
+	m.viewport.SetYOffset(m.viewport.YOffset + 25)
+	m.scrolled()
+	if m.windowStart != 45 || m.offset() != 75 {
+		t.Errorf("window starts at %d with offset %d, want 45 and 75", m.windowStart, m.offset())
+	}
+}
+
+func TestRenderStateEqual(t *testing.T) {
+	running := sgptcoder.ToolPart{ID: "prt_1", State: sgptcoder.ToolPartState{Status: sgptcoder.ToolPartStateStatusRunning}}
+	completed := sgptcoder.ToolPart{ID: "prt_1", State: sgptcoder.ToolPartState{Status: sgptcoder.ToolPartStateStatusCompleted}}
+	other := sgptcoder.ToolPart{ID: "prt_2", State: sgptcoder.ToolPartState{Status: sgptcoder.ToolPartStateStatusRunning}}
+	base := renderState{reverted: true, revertedMessageCount: 2, revertedToolCount: 1, orphanedToolCalls: []sgptcoder.ToolPart{running}}
+	tests := []struct {
+		name  string
+		other renderState
+		equal bool
+	}{
+		{"same", base.clone(), true},
+		{"not reverted", renderState{revertedMessageCount: 2, revertedToolCount: 1, orphanedToolCalls: []sgptcoder.ToolPart{running}}, false},
+		{"reverted messages", renderState{reverted: true, revertedMessageCount: 3, revertedToolCount: 1, orphanedToolCalls: []sgptcoder.ToolPart{running}}, false},
+		{"reverted tools", renderState{reverted: true, revertedMessageCount: 2, orphanedToolCalls: []sgptcoder.ToolPart{running}}, false},
+		{"tool status", renderState{reverted: true, revertedMessageCount: 2, revertedToolCount: 1, orphanedToolCalls: []sgptcoder.ToolPart{completed}}, false},
+		{"other tool", renderState{reverted: true, revertedMessageCount: 2, revertedToolCount: 1, orphanedToolCalls: []sgptcoder.ToolPart{other}}, false},
+		{"more tools", renderState{reverted: true, revertedMessageCount: 2, revertedToolCount: 1, orphanedToolCalls: []sgptcoder.ToolPart{running, other}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := base.equal(tt.other); got != tt.equal {
+				t.Errorf("equal = %t, want %t", got, tt.equal)
+			}
+			if got := tt.other.equal(base); got != tt.equal {
+				t.Errorf("equal is not symmetric")
+			}
+		})
+	}
+
+	// Clones don't share the tool calls, so the cached state can't change
+	clone := base.clone()
+	clone.orphanedToolCalls[0] = completed
+	if !base.equal(renderState{reverted: true, revertedMessageCount: 2, revertedToolCount: 1, orphanedToolCalls: []sgptcoder.ToolPart{running}}) {
+		t.Errorf("expected changing a clone to leave the original alone")
+	}
+}
@@ -0,0 +1,119 @@
+package chat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/skorpland/sgptcoder/internal/styles"
+	"github.com/skorpland/sgptcoder/internal/theme"
+)
+
+const searchBarHeight = 1
+
+// messageSearch is the find-in-conversation bar shown below the messages.
+// While it is open, tool details are rendered so their output can be found,
+// and thinking blocks are rendered if thinking is toggled on.
+type messageSearch struct {
+	input    textinput.Model
+	thinking bool
+}
+
+func newMessageSearch() *messageSearch {
+	t := theme.CurrentTheme()
+	bgColor := t.BackgroundElement()
+
+	ti := textinput.New()
+	ti.Placeholder = "Find in messages"
+	ti.Styles.Focused.Placeholder = styles.NewStyle().
+		Foreground(t.TextMuted()).
+		Background(bgColor).
+		Lipgloss()
+	ti.Styles.Focused.Text = styles.NewStyle().
+		Foreground(t.Text()).
+		Background(bgColor).
+		Lipgloss()
+	ti.Styles.Focused.Prompt = styles.NewStyle().
+		Foreground(t.Primary()).
+		Background(bgColor).
+		Bold(true).
+		Lipgloss()
+	ti.Styles.Cursor.Color = t.Primary()
+	ti.Styles.Cursor.Blink = false
+	ti.VirtualCursor = true
+
+	ti.Prompt = " Find: "
+	ti.CharLimit = -1
+	ti.Focus()
+
+	return &messageSearch{input: ti}
+}
+
+// update passes a key press to the input and reports whether the query
+// changed.
+func (s *messageSearch) update(msg tea.KeyPressMsg) (bool, tea.Cmd) {
+	previous := s.input.Value()
+	var cmd tea.Cmd
+	s.input, cmd = s.input.Update(msg)
+	return s.input.Value() != previous, cmd
+}
+
+// findMatches returns the byte ranges of the query in the content, with ANSI
+// sequences stripped, which is what the viewport highlights. The search
+// ignores case unless the query has an upper case letter.
+func findMatches(content string, query string) [][]int {
+	if query == "" {
+		return nil
+	}
+	pattern := regexp.QuoteMeta(query)
+	if !strings.ContainsFunc(query, unicode.IsUpper) {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.MustCompile(pattern).FindAllStringIndex(ansi.Strip(content), -1)
+}
+
+// view renders the search bar, with the position of the selected match and
+// the keys that navigate the matches. Thinking can only be toggled while the
+// thinking blocks are hidden from the messages. Until every message of the
+// session is loaded, the status says that only the loaded ones are searched.
+func (s *messageSearch) view(width int, index int, count int, thinkingShown bool, loaded bool) string {
+	t := theme.CurrentTheme()
+	bgColor := t.BackgroundElement()
+	base := styles.NewStyle().Foreground(t.Text()).Background(bgColor).Render
+	muted := styles.NewStyle().Foreground(t.TextMuted()).Background(bgColor).Render
+
+	status := ""
+	switch {
+	case s.input.Value() == "":
+	case count == 0:
+		status = styles.NewStyle().Foreground(t.Error()).Background(bgColor).Render("no matches")
+	default:
+		status = base(fmt.Sprintf("%d of %d", index+1, count))
+	}
+	if status != "" && !loaded {
+		status += muted(" in loaded messages")
+	}
+	hints := muted("  ") + base("enter") + muted(" next  ") +
+		base("shift+enter") + muted(" previous  ")
+	switch {
+	case s.thinking:
+		hints += base("ctrl+t") + muted(" skip thinking  ")
+	case !thinkingShown:
+		hints += base("ctrl+t") + muted(" search thinking  ")
+	}
+	hints += base("esc") + muted(" close ")
+	if lipgloss.Width(status)+lipgloss.Width(hints)+20 > width {
+		hints = muted(" ")
+	}
+
+	s.input.SetWidth(max(1, width-lipgloss.Width(status)-lipgloss.Width(hints)-lipgloss.Width(s.input.Prompt)-1))
+	input := s.input.View()
+	space := max(0, width-lipgloss.Width(input)-lipgloss.Width(status)-lipgloss.Width(hints))
+	spacer := styles.NewStyle().Background(bgColor).Width(space).Render("")
+	return input + spacer + status + hints
+}
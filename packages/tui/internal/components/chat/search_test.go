@@ -0,0 +1,30 @@
+package chat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		query   string
+		want    [][]int
+	}{
+		{"empty query", "hello", "", nil},
+		{"no match", "hello", "bye", nil},
+		{"lower case query ignores case", "Go go GO", "go", [][]int{{0, 2}, {3, 5}, {6, 8}}},
+		{"upper case query matches case", "Go go GO", "Go", [][]int{{0, 2}}},
+		{"regexp characters are literal", "a.b axb", "a.b", [][]int{{0, 3}}},
+		{"offsets skip ansi sequences", "\x1b[1mbold\x1b[0m text", "text", [][]int{{5, 9}}},
+		{"matches span styled runs", "\x1b[31mre\x1b[0m\x1b[32md\x1b[0m", "red", [][]int{{0, 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findMatches(tt.content, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findMatches(%q, %q) = %v, want %v", tt.content, tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package dialog
+
+import (
+	"context"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder/internal/app"
+	"github.com/skorpland/sgptcoder/internal/components/list"
+	"github.com/skorpland/sgptcoder/internal/components/modal"
+	"github.com/skorpland/sgptcoder/internal/layout"
+	"github.com/skorpland/sgptcoder/internal/styles"
+	"github.com/skorpland/sgptcoder/internal/theme"
+	"github.com/skorpland/sgptcoder/internal/util"
+)
+
+const numVisibleFiles = 12
+
+// FileSelectedMsg is sent when a file is picked in the file dialog.
+type FileSelectedMsg struct {
+	Path string
+}
+
+// FileDialog interface for the file browser and file search dialogs
+type FileDialog interface {
+	layout.Modal
+}
+
+type fileDialog struct {
+	app          *app.App
+	modal        *modal.Modal
+	searchDialog *SearchDialog
+	// search finds files anywhere in the project with Find.Files; otherwise
+	// the dialog browses the tree one directory at a time with File.List.
+	search  bool
+	dir     string
+	nodes   []sgptcoder.FileNode
+	changed []sgptcoder.File
+}
+
+// filesFoundMsg carries the results of Find.Files for a query.
+type filesFoundMsg struct {
+	query string
+	files []string
+}
+
+// fileItem is a list item for a file, a directory or the parent directory
+type fileItem struct {
+	path    string
+	name    string
+	dir     bool
+	added   int64
+	removed int64
+}
+
+func (f fileItem) Render(
+	selected bool,
+	width int,
+	baseStyle styles.Style,
+) string {
+	t := theme.CurrentTheme()
+
+	itemStyle := baseStyle.
+		Background(t.BackgroundPanel()).
+		Foreground(t.Text())
+	if f.dir {
+		itemStyle = itemStyle.Foreground(t.Accent())
+	}
+	if selected {
+		itemStyle = itemStyle.Foreground(t.Primary())
+	}
+
+	name := f.name
+	if f.dir {
+		name += "/"
+	}
+	text := itemStyle.Render(name)
+	if f.added > 0 {
+		text += baseStyle.Foreground(t.Success()).Background(t.BackgroundPanel()).Render(" +" + strconv.Itoa(int(f.added)))
+	}
+	if f.removed > 0 {
+		text += baseStyle.Foreground(t.Error()).Background(t.BackgroundPanel()).Render(" -" + strconv.Itoa(int(f.removed)))
+	}
+
+	return baseStyle.
+		Background(t.BackgroundPanel()).
+		PaddingLeft(1).
+		Render(text)
+}
+
+func (f fileItem) Selectable() bool {
+	return true
+}
+
+func (f *fileDialog) Init() tea.Cmd {
+	return f.searchDialog.Init()
+}
+
+func (f *fileDialog) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case SearchSelectionMsg:
+		item, ok := msg.Item.(fileItem)
+		if !ok {
+			return f, nil
+		}
+		if item.dir {
+			f.open(item.path)
+			return f, nil
+		}
+		return f, tea.Sequence(
+			util.CmdHandler(modal.CloseModalMsg{}),
+			util.CmdHandler(FileSelectedMsg{Path: item.path}),
+		)
+	case SearchCancelledMsg:
+		return f, util.CmdHandler(modal.CloseModalMsg{})
+	case SearchQueryChangedMsg:
+		if f.search && strings.TrimSpace(msg.Query) != "" {
+			return f, f.findFiles(msg.Query)
+		}
+		f.searchDialog.SetItems(f.buildDisplayList(msg.Query))
+		return f, nil
+	case filesFoundMsg:
+		// Results for a query that has since changed are dropped
+		if msg.query == f.searchDialog.GetQuery() {
+			f.searchDialog.SetItems(f.buildSearchResults(msg.files))
+		}
+		return f, nil
+	}
+
+	updatedDialog, cmd := f.searchDialog.Update(msg)
+	f.searchDialog = updatedDialog.(*SearchDialog)
+	return f, cmd
+}
+
+func (f *fileDialog) View() string {
+	return f.searchDialog.View()
+}
+
+// open lists the given directory, relative to the project root.
+func (f *fileDialog) open(dir string) {
+	nodes, err := f.app.Client.File.List(context.Background(), sgptcoder.FileListParams{
+		Path: sgptcoder.F(dir),
+	})
+	if err != nil {
+		slog.Error("Failed to list files", "path", dir, "error", err)
+		return
+	}
+	f.dir = dir
+	f.nodes = *nodes
+	sort.SliceStable(f.nodes, func(i, j int) bool {
+		if f.nodes[i].Type != f.nodes[j].Type {
+			return f.nodes[i].Type == sgptcoder.FileNodeTypeDirectory
+		}
+		return f.nodes[i].Name < f.nodes[j].Name
+	})
+
+	title := "Files"
+	if dir != "" && dir != "." {
+		title = "Files: " + dir
+	}
+	f.modal.SetTitle(title)
+	f.searchDialog.SetQuery("")
+	f.searchDialog.SetItems(f.buildDisplayList(""))
+}
+
+func (f *fileDialog) changes(filePath string) (int64, int64) {
+	for _, file := range f.changed {
+		if file.Path == filePath {
+			return file.Added, file.Removed
+		}
+	}
+	return 0, 0
+}
+
+func (f *fileDialog) buildDisplayList(query string) []list.Item {
+	if f.search {
+		return f.changedItems()
+	}
+
+	items := []list.Item{}
+	if f.dir != "" && f.dir != "." && query == "" {
+		parent := path.Dir(f.dir)
+		items = append(items, fileItem{path: parent, name: "..", dir: true})
+	}
+
+	names := make([]string, 0, len(f.nodes))
+	for _, node := range f.nodes {
+		if node.Ignored {
+			continue
+		}
+		names = append(names, node.Name)
+	}
+	if query != "" {
+		matches := fuzzy.RankFindFold(query, names)
+		sort.Sort(matches)
+		names = names[:0]
+		for _, match := range matches {
+			names = append(names, match.Target)
+		}
+	}
+	for _, name := range names {
+		for _, node := range f.nodes {
+			if node.Name != name {
+				continue
+			}
+			added, removed := f.changes(node.Path)
+			items = append(items, fileItem{
+				path:    node.Path,
+				name:    node.Name,
+				dir:     node.Type == sgptcoder.FileNodeTypeDirectory,
+				added:   added,
+				removed: removed,
+			})
+			break
+		}
+	}
+	return items
+}
+
+// changedItems lists the files changed in the working tree, shown by the
+// search dialog before anything is typed.
+func (f *fileDialog) changedItems() []list.Item {
+	items := []list.Item{}
+	for _, file := range f.changed {
+		items = append(items, fileItem{
+			path:    file.Path,
+			name:    file.Path,
+			added:   file.Added,
+			removed: file.Removed,
+		})
+	}
+	return items
+}
+
+func (f *fileDialog) buildSearchResults(files []string) []list.Item {
+	items := []list.Item{}
+	for _, file := range files {
+		added, removed := f.changes(file)
+		items = append(items, fileItem{
+			path:    file,
+			name:    file,
+			added:   added,
+			removed: removed,
+		})
+	}
+	return items
+}
+
+// findFiles searches the project with Find.Files off the UI goroutine.
+func (f *fileDialog) findFiles(query string) tea.Cmd {
+	return func() tea.Msg {
+		files, err := f.app.Client.Find.Files(context.Background(), sgptcoder.FindFilesParams{
+			Query: sgptcoder.F(strings.TrimSpace(query)),
+		})
+		if err != nil {
+			slog.Error("Failed to find files", "error", err)
+			return nil
+		}
+		return filesFoundMsg{query: query, files: *files}
+	}
+}
+
+func (f *fileDialog) Render(background string) string {
+	return f.modal.Render(f.View(), background)
+}
+
+func (f *fileDialog) Close() tea.Cmd {
+	return nil
+}
+
+func newFileDialog(app *app.App, search bool, title, placeholder string) *fileDialog {
+	dialog := &fileDialog{
+		app:    app,
+		search: search,
+	}
+
+	status, err := app.Client.File.Status(context.Background(), sgptcoder.FileStatusParams{})
+	if err != nil {
+		slog.Error("Failed to get file status", "error", err)
+	} else if status != nil {
+		dialog.changed = *status
+		sort.Slice(dialog.changed, func(i, j int) bool {
+			return dialog.changed[i].Added+dialog.changed[i].Removed >
+				dialog.changed[j].Added+dialog.changed[j].Removed
+		})
+	}
+
+	dialog.searchDialog = NewSearchDialog(placeholder, numVisibleFiles)
+	dialog.searchDialog.SetWidth(maxDialogWidth - 4)
+	dialog.modal = modal.New(
+		modal.WithTitle(title),
+		modal.WithMaxWidth(maxDialogWidth),
+	)
+	return dialog
+}
+
+// NewFileListDialog browses the project tree with File.List, starting at the
+// project root. Selecting a directory opens it; selecting a file closes the
+// dialog with a [FileSelectedMsg].
+func NewFileListDialog(app *app.App) FileDialog {
+	dialog := newFileDialog(app, false, "Files", "Filter files...")
+	dialog.open("")
+	return dialog
+}
+
+// NewFileSearchDialog finds files anywhere in the project. Before anything is
+// typed it lists the files changed in the working tree.
+func NewFileSearchDialog(app *app.App) FileDialog {
+	dialog := newFileDialog(app, true, "Search Files", "Search files...")
+	dialog.searchDialog.SetItems(dialog.buildDisplayList(""))
+	return dialog
+}
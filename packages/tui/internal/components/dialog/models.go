@@ -39,6 +39,7 @@ type modelDialog struct {
 	modal        *modal.Modal
 	searchDialog *SearchDialog
 	dialogWidth  int
+	rerun        *app.Prompt
 }
 
 type ModelWithProvider struct {
@@ -110,14 +111,18 @@ func (m *modelDialog) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case SearchSelectionMsg:
 		// Handle selection from search dialog
 		if item, ok := msg.Item.(modelItem); ok {
-			return m, tea.Sequence(
+			cmds := []tea.Cmd{
 				util.CmdHandler(modal.CloseModalMsg{}),
 				util.CmdHandler(
 					app.ModelSelectedMsg{
 						Provider: item.model.Provider,
 						Model:    item.model.Model,
 					}),
-			)
+			}
+			if m.rerun != nil {
+				cmds = append(cmds, util.CmdHandler(app.SendPrompt(*m.rerun)))
+			}
+			return m, tea.Sequence(cmds...)
 		}
 		return m, util.CmdHandler(modal.CloseModalMsg{})
 	case SearchCancelledMsg:
@@ -455,3 +460,15 @@ func NewModelDialog(app *app.App) ModelDialog {
 
 	return dialog
 }
+
+// NewRerunModelDialog returns a model dialog that sends a prompt again once a
+// model is selected.
+func NewRerunModelDialog(app *app.App, prompt app.Prompt) ModelDialog {
+	dialog := NewModelDialog(app).(*modelDialog)
+	dialog.rerun = &prompt
+	dialog.modal = modal.New(
+		modal.WithTitle("Re-run with Model"),
+		modal.WithMaxWidth(dialog.dialogWidth+4),
+	)
+	return dialog
+}
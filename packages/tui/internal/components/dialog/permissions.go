@@ -0,0 +1,509 @@
+package dialog
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/muesli/reflow/truncate"
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder/internal/app"
+	"github.com/skorpland/sgptcoder/internal/components/diff"
+	"github.com/skorpland/sgptcoder/internal/components/list"
+	"github.com/skorpland/sgptcoder/internal/components/modal"
+	"github.com/skorpland/sgptcoder/internal/layout"
+	"github.com/skorpland/sgptcoder/internal/permission"
+	"github.com/skorpland/sgptcoder/internal/styles"
+	"github.com/skorpland/sgptcoder/internal/theme"
+	"github.com/skorpland/sgptcoder/internal/util"
+)
+
+// PermissionLogDialog interface for the permission decisions dialog
+type PermissionLogDialog interface {
+	layout.Modal
+}
+
+// permissionLogItem is a list item for a logged decision or a saved rule
+type permissionLogItem struct {
+	decision *permission.Decision
+	rule     *permission.Rule
+}
+
+func (p permissionLogItem) Render(
+	selected bool,
+	width int,
+	baseStyle styles.Style,
+) string {
+	t := theme.CurrentTheme()
+
+	var prefix, text string
+	color := t.Success()
+	if p.decision != nil {
+		decision := p.decision
+		who := "you"
+		if decision.Automatic() {
+			who = "rule"
+		}
+		if decision.Response == sgptcoder.SessionPermissionRespondParamsResponseReject {
+			color = t.Error()
+		}
+		prefix = decision.Time.Format("15:04:05") + " " + string(decision.Response) + " (" + who + ") "
+		text = decision.Request.Permission.Title
+	} else if p.rule != nil {
+		if p.rule.Response == string(sgptcoder.SessionPermissionRespondParamsResponseReject) {
+			color = t.Error()
+		}
+		text = p.rule.String()
+	}
+
+	prefixStyle := baseStyle.Foreground(color)
+	textStyle := baseStyle.Foreground(t.Text())
+	if selected {
+		prefixStyle = prefixStyle.Background(t.Primary()).Foreground(t.BackgroundElement())
+		textStyle = textStyle.Background(t.Primary()).Foreground(t.BackgroundElement())
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	text = truncate.StringWithTail(text, uint(max(0, width-len(prefix)-2)), "...")
+
+	line := prefixStyle.PaddingLeft(1).Render(prefix) + textStyle.Render(text)
+	if selected {
+		return baseStyle.Background(t.Primary()).Width(width).Render(line)
+	}
+	return line
+}
+
+func (p permissionLogItem) Selectable() bool {
+	return true
+}
+
+type permissionLogDialog struct {
+	app       *app.App
+	modal     *modal.Modal
+	list      list.List[permissionLogItem]
+	showRules bool
+	decisions []permission.Decision
+}
+
+func (p *permissionLogDialog) Init() tea.Cmd {
+	return nil
+}
+
+func (p *permissionLogDialog) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.list.SetMaxWidth(layout.Current.Container.Width - 12)
+	case tea.KeyPressMsg:
+		switch msg.String() {
+		case "tab":
+			p.showRules = !p.showRules
+			p.updateListItems()
+			p.list.SetSelectedIndex(0)
+			return p, nil
+		case "enter", "a":
+			// Answer requests like the selected one the same way from now on
+			if p.showRules {
+				return p, nil
+			}
+			if item, idx := p.list.GetSelectedItem(); idx >= 0 && item.decision != nil {
+				rule := permission.RuleFor(item.decision.Request, item.decision.Response)
+				return p, p.app.AddPermissionRule(rule)
+			}
+		case "x", "delete", "backspace":
+			if !p.showRules {
+				return p, nil
+			}
+			if _, idx := p.list.GetSelectedItem(); idx >= 0 {
+				cmd := p.app.RemovePermissionRule(idx)
+				p.updateListItems()
+				p.list.SetSelectedIndex(min(idx, len(p.app.State.PermissionRules)-1))
+				return p, cmd
+			}
+		}
+	}
+
+	listModel, cmd := p.list.Update(msg)
+	p.list = listModel.(list.List[permissionLogItem])
+	return p, cmd
+}
+
+func (p *permissionLogDialog) updateListItems() {
+	var items []permissionLogItem
+	if p.showRules {
+		for i := range p.app.State.PermissionRules {
+			items = append(items, permissionLogItem{rule: &p.app.State.PermissionRules[i]})
+		}
+		p.modal.SetTitle("Permission Rules")
+		p.list.SetEmptyMessage("No permission rules")
+	} else {
+		for i := range p.decisions {
+			items = append(items, permissionLogItem{decision: &p.decisions[i]})
+		}
+		p.modal.SetTitle("Permission Decisions")
+		p.list.SetEmptyMessage("No permission decisions yet")
+	}
+	p.list.SetItems(items)
+}
+
+func (p *permissionLogDialog) Render(background string) string {
+	t := theme.CurrentTheme()
+	keyStyle := styles.NewStyle().
+		Foreground(t.Text()).
+		Background(t.BackgroundPanel()).
+		Bold(true).
+		Render
+	mutedStyle := styles.NewStyle().Foreground(t.TextMuted()).Background(t.BackgroundPanel()).Render
+
+	var leftHelp string
+	if p.showRules {
+		leftHelp = keyStyle("x/del") + mutedStyle(" delete rule")
+	} else {
+		leftHelp = keyStyle("enter") + mutedStyle(" always answer like this")
+	}
+	rightHelp := keyStyle("tab") + mutedStyle(" decisions/rules")
+
+	bgColor := t.BackgroundPanel()
+	helpText := layout.Render(layout.FlexOptions{
+		Direction:  layout.Row,
+		Justify:    layout.JustifySpaceBetween,
+		Width:      layout.Current.Container.Width - 14,
+		Background: &bgColor,
+	}, layout.FlexItem{View: leftHelp}, layout.FlexItem{View: rightHelp})
+	helpText = styles.NewStyle().PaddingLeft(1).PaddingTop(1).Render(helpText)
+
+	content := strings.Join([]string{p.list.View(), helpText}, "\n")
+	return p.modal.Render(content, background)
+}
+
+func (p *permissionLogDialog) Close() tea.Cmd {
+	return nil
+}
+
+// NewPermissionLogDialog lists how recent permission requests were answered,
+// by the user or by a permission rule, and the saved rules.
+func NewPermissionLogDialog(app *app.App) PermissionLogDialog {
+	listComponent := list.NewListComponent(
+		list.WithMaxVisibleHeight[permissionLogItem](12),
+		list.WithFallbackMessage[permissionLogItem]("No permission decisions yet"),
+		list.WithAlphaNumericKeys[permissionLogItem](false),
+		list.WithRenderFunc(
+			func(item permissionLogItem, selected bool, width int, baseStyle styles.Style) string {
+				return item.Render(selected, width, baseStyle)
+			},
+		),
+		list.WithSelectableFunc(func(item permissionLogItem) bool {
+			return true
+		}),
+	)
+	listComponent.SetMaxWidth(layout.Current.Container.Width - 12)
+
+	dialog := &permissionLogDialog{
+		app:  app,
+		list: listComponent,
+		modal: modal.New(
+			modal.WithTitle("Permission Decisions"),
+			modal.WithMaxWidth(layout.Current.Container.Width-8),
+		),
+	}
+	if app.PermissionLog != nil {
+		dialog.decisions = app.PermissionLog.Decisions()
+	}
+	dialog.updateListItems()
+	return dialog
+}
+
+// PermissionReviewDialog interface for the pending permissions dialog
+type PermissionReviewDialog interface {
+	layout.Modal
+}
+
+// permissionReviewItem is a list item for a pending permission
+type permissionReviewItem struct {
+	permission sgptcoder.Permission
+	session    string
+	marked     bool
+}
+
+func (p permissionReviewItem) Render(
+	selected bool,
+	width int,
+	baseStyle styles.Style,
+) string {
+	t := theme.CurrentTheme()
+
+	mark := "[ ] "
+	if p.marked {
+		mark = "[x] "
+	}
+	prefix := mark + p.permission.Type + " "
+	suffix := ""
+	if p.session != "" {
+		suffix = " · " + p.session
+	}
+	text := strings.Join(strings.Fields(p.permission.Title), " ")
+	text = truncate.StringWithTail(text, uint(max(0, width-len(prefix)-len(suffix)-2)), "...")
+
+	prefixStyle := baseStyle.Foreground(t.Warning())
+	textStyle := baseStyle.Foreground(t.Text())
+	suffixStyle := baseStyle.Foreground(t.TextMuted())
+	if selected {
+		prefixStyle = prefixStyle.Background(t.Primary()).Foreground(t.BackgroundElement())
+		textStyle = textStyle.Background(t.Primary()).Foreground(t.BackgroundElement())
+		suffixStyle = suffixStyle.Background(t.Primary()).Foreground(t.BackgroundElement())
+	}
+
+	line := prefixStyle.PaddingLeft(1).Render(prefix) + textStyle.Render(text) + suffixStyle.Render(suffix)
+	if selected {
+		return baseStyle.Background(t.Primary()).Width(width).Render(line)
+	}
+	return line
+}
+
+func (p permissionReviewItem) Selectable() bool {
+	return true
+}
+
+type permissionReviewDialog struct {
+	app      *app.App
+	modal    *modal.Modal
+	list     list.List[permissionReviewItem]
+	sessions map[string]string
+	marked   map[string]bool
+}
+
+func (p *permissionReviewDialog) Init() tea.Cmd {
+	return nil
+}
+
+func (p *permissionReviewDialog) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.list.SetMaxWidth(layout.Current.Container.Width - 12)
+	case sgptcoder.EventListResponseEventPermissionUpdated,
+		sgptcoder.EventListResponseEventPermissionReplied:
+		// Another client or a permission rule may have answered some already
+		_, idx := p.list.GetSelectedItem()
+		p.updateListItems()
+		p.list.SetSelectedIndex(max(0, idx))
+		return p, nil
+	case tea.KeyPressMsg:
+		switch msg.String() {
+		case "space":
+			if item, idx := p.list.GetSelectedItem(); idx >= 0 {
+				if p.marked[item.permission.ID] {
+					delete(p.marked, item.permission.ID)
+				} else {
+					p.marked[item.permission.ID] = true
+				}
+				p.updateListItems()
+				p.list.SetSelectedIndex(idx)
+			}
+			return p, nil
+		case "ctrl+a":
+			// Mark everything, or clear the marks if everything is marked
+			items := p.pending()
+			all := !slices.ContainsFunc(items, func(permission sgptcoder.Permission) bool {
+				return !p.marked[permission.ID]
+			})
+			p.marked = map[string]bool{}
+			if !all {
+				for _, permission := range items {
+					p.marked[permission.ID] = true
+				}
+			}
+			_, idx := p.list.GetSelectedItem()
+			p.updateListItems()
+			p.list.SetSelectedIndex(max(0, idx))
+			return p, nil
+		case "enter":
+			return p, p.respond(sgptcoder.SessionPermissionRespondParamsResponseOnce)
+		case "a":
+			return p, p.respond(sgptcoder.SessionPermissionRespondParamsResponseAlways)
+		case "r":
+			return p, p.respond(sgptcoder.SessionPermissionRespondParamsResponseReject)
+		}
+	}
+
+	listModel, cmd := p.list.Update(msg)
+	p.list = listModel.(list.List[permissionReviewItem])
+	return p, cmd
+}
+
+// respond answers the marked permissions, or the selected one if none are
+// marked, and closes the dialog once nothing is left.
+func (p *permissionReviewDialog) respond(response sgptcoder.SessionPermissionRespondParamsResponse) tea.Cmd {
+	var targets []sgptcoder.Permission
+	for _, permission := range p.pending() {
+		if p.marked[permission.ID] {
+			targets = append(targets, permission)
+		}
+	}
+	item, idx := p.list.GetSelectedItem()
+	if len(targets) == 0 {
+		if idx < 0 {
+			return nil
+		}
+		targets = append(targets, item.permission)
+	}
+
+	var cmds []tea.Cmd
+	for _, permission := range targets {
+		cmds = append(cmds, p.app.RespondToPermission(permission, response))
+		delete(p.marked, permission.ID)
+	}
+	p.updateListItems()
+	if len(p.pending()) == 0 {
+		cmds = append(cmds, util.CmdHandler(modal.CloseModalMsg{}))
+	} else {
+		p.list.SetSelectedIndex(min(max(0, idx), len(p.pending())-1))
+	}
+	return tea.Batch(cmds...)
+}
+
+// pending returns the queued permissions of the sessions the dialog covers.
+func (p *permissionReviewDialog) pending() []sgptcoder.Permission {
+	return p.app.PendingPermissions(p.sessions)
+}
+
+func (p *permissionReviewDialog) updateListItems() {
+	var items []permissionReviewItem
+	for _, permission := range p.pending() {
+		item := permissionReviewItem{
+			permission: permission,
+			marked:     p.marked[permission.ID],
+		}
+		if permission.SessionID != p.app.Session.ID {
+			item.session = p.sessions[permission.SessionID]
+			if item.session == "" {
+				item.session = permission.SessionID
+			}
+		}
+		items = append(items, item)
+	}
+	p.list.SetItems(items)
+}
+
+// preview shows what the selected permission would do: the full command, the
+// proposed edit or the new file.
+func (p *permissionReviewDialog) preview(width int) string {
+	item, idx := p.list.GetSelectedItem()
+	if idx < 0 {
+		return ""
+	}
+	t := theme.CurrentTheme()
+	height := min(12, max(3, layout.Current.Container.Height/3))
+	base := styles.NewStyle().Background(t.BackgroundPanel()).Width(width)
+	metadata := item.permission.Metadata
+	filePath, _ := metadata["filePath"].(string)
+
+	var body string
+	switch item.permission.Type {
+	case "bash":
+		if command, ok := metadata["command"].(string); ok {
+			body = base.Foreground(t.Text()).Render("$ " + command)
+		}
+	case "edit":
+		if patch, ok := metadata["diff"].(string); ok && filePath != "" {
+			var err error
+			if width < 120 {
+				body, err = diff.FormatUnifiedDiff(filePath, patch, diff.WithWidth(width))
+			} else {
+				body, err = diff.FormatDiff(filePath, patch, diff.WithWidth(width))
+			}
+			if err != nil {
+				body = ""
+			}
+		}
+	case "write":
+		if content, ok := metadata["content"].(string); ok && filePath != "" {
+			body = util.RenderFile(filePath, content, width, util.WithTruncate(height))
+		}
+	}
+	if body == "" {
+		body = base.Foreground(t.Text()).Render(item.permission.Title)
+	}
+	body = strings.TrimRight(body, "\n")
+	if lines := strings.Count(body, "\n") + 1; lines > height {
+		body = util.TruncateHeight(body, height) + "\n" +
+			base.Foreground(t.TextMuted()).Render(fmt.Sprintf("… %d more lines", lines-height))
+	}
+
+	header := ""
+	if filePath != "" {
+		header = base.Foreground(t.TextMuted()).Render(util.Relative(filePath)) + "\n"
+	}
+	return header + body
+}
+
+func (p *permissionReviewDialog) Render(background string) string {
+	t := theme.CurrentTheme()
+	keyStyle := styles.NewStyle().
+		Foreground(t.Text()).
+		Background(t.BackgroundPanel()).
+		Bold(true).
+		Render
+	mutedStyle := styles.NewStyle().Foreground(t.TextMuted()).Background(t.BackgroundPanel()).Render
+
+	leftHelp := keyStyle("space") + mutedStyle(" mark  ") +
+		keyStyle("ctrl+a") + mutedStyle(" mark all")
+	rightHelp := keyStyle("enter") + mutedStyle(" accept  ") +
+		keyStyle("a") + mutedStyle(" always  ") +
+		keyStyle("r") + mutedStyle(" reject")
+
+	bgColor := t.BackgroundPanel()
+	width := layout.Current.Container.Width - 14
+	helpText := layout.Render(layout.FlexOptions{
+		Direction:  layout.Row,
+		Justify:    layout.JustifySpaceBetween,
+		Width:      width,
+		Background: &bgColor,
+	}, layout.FlexItem{View: leftHelp}, layout.FlexItem{View: rightHelp})
+	helpText = styles.NewStyle().PaddingLeft(1).PaddingTop(1).Render(helpText)
+
+	parts := []string{p.list.View()}
+	if preview := p.preview(width); preview != "" {
+		parts = append(parts, styles.NewStyle().PaddingLeft(1).PaddingTop(1).Render(preview))
+	}
+	parts = append(parts, helpText)
+	return p.modal.Render(strings.Join(parts, "\n"), background)
+}
+
+func (p *permissionReviewDialog) Close() tea.Cmd {
+	return nil
+}
+
+// NewPermissionReviewDialog lists the permissions waiting for an answer in
+// the current session and the sessions it started, so requests of subagents
+// that are not on screen can be answered too, several at a time.
+func NewPermissionReviewDialog(app *app.App) PermissionReviewDialog {
+	sessions := app.PermissionSessions(context.Background())
+
+	listComponent := list.NewListComponent(
+		list.WithMaxVisibleHeight[permissionReviewItem](8),
+		list.WithFallbackMessage[permissionReviewItem]("No permissions waiting"),
+		list.WithAlphaNumericKeys[permissionReviewItem](false),
+		list.WithRenderFunc(
+			func(item permissionReviewItem, selected bool, width int, baseStyle styles.Style) string {
+				return item.Render(selected, width, baseStyle)
+			},
+		),
+		list.WithSelectableFunc(func(item permissionReviewItem) bool {
+			return true
+		}),
+	)
+	listComponent.SetMaxWidth(layout.Current.Container.Width - 12)
+
+	dialog := &permissionReviewDialog{
+		app:      app,
+		list:     listComponent,
+		sessions: sessions,
+		marked:   map[string]bool{},
+		modal: modal.New(
+			modal.WithTitle("Pending Permissions"),
+			modal.WithMaxWidth(layout.Current.Container.Width-8),
+		),
+	}
+	dialog.updateListItems()
+	return dialog
+}
@@ -0,0 +1,318 @@
+package fileviewer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder/internal/app"
+	"github.com/skorpland/sgptcoder/internal/components/dialog"
+	"github.com/skorpland/sgptcoder/internal/components/diff"
+	"github.com/skorpland/sgptcoder/internal/components/toast"
+	"github.com/skorpland/sgptcoder/internal/styles"
+	"github.com/skorpland/sgptcoder/internal/theme"
+	"github.com/skorpland/sgptcoder/internal/util"
+	"github.com/skorpland/sgptcoder/internal/viewport"
+)
+
+// Mode is what the file viewer shows for the open file.
+type Mode int
+
+const (
+	ModeContent Mode = iota
+	ModeUnifiedDiff
+	ModeSplitDiff
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeUnifiedDiff:
+		return "unified diff"
+	case ModeSplitDiff:
+		return "split diff"
+	}
+	return "content"
+}
+
+type fileLoadedMsg struct {
+	path    string
+	content string
+	diff    string
+}
+
+type fileLoadFailedMsg struct {
+	path string
+}
+
+type renderCompleteMsg struct {
+	path    string
+	mode    Mode
+	width   int
+	content string
+}
+
+type FileViewerComponent interface {
+	tea.Model
+	tea.ViewModel
+	HasFile() bool
+	Filename() string
+	Mode() Mode
+	OpenFile(path string) (tea.Model, tea.Cmd)
+	Clear()
+	ToggleMode() (tea.Model, tea.Cmd)
+	PageUp() (tea.Model, tea.Cmd)
+	PageDown() (tea.Model, tea.Cmd)
+	HalfPageUp() (tea.Model, tea.Cmd)
+	HalfPageDown() (tea.Model, tea.Cmd)
+	GotoTop() (tea.Model, tea.Cmd)
+	GotoBottom() (tea.Model, tea.Cmd)
+}
+
+type fileViewerComponent struct {
+	app           *app.App
+	width, height int
+	viewport      viewport.Model
+	filename      string
+	content       string
+	diff          string
+	mode          Mode
+	loading       bool
+}
+
+func (f *fileViewerComponent) Init() tea.Cmd {
+	return f.viewport.Init()
+}
+
+func (f *fileViewerComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		f.width = msg.Width - 4
+		f.height = msg.Height - 7
+		f.viewport.SetWidth(f.width)
+		f.viewport.SetHeight(f.height - 2)
+		if f.HasFile() {
+			return f, f.render()
+		}
+		return f, nil
+	case fileLoadedMsg:
+		if msg.path != f.filename {
+			return f, nil
+		}
+		f.content = msg.content
+		f.diff = msg.diff
+		// Files the agent changed are most interesting as a diff.
+		f.mode = ModeContent
+		if f.diff != "" {
+			f.mode = ModeUnifiedDiff
+		}
+		return f, f.render()
+	case fileLoadFailedMsg:
+		if msg.path != f.filename {
+			return f, nil
+		}
+		f.Clear()
+		return f, toast.NewErrorToast(fmt.Sprintf("Failed to open %s", msg.path))
+	case renderCompleteMsg:
+		if msg.path != f.filename || msg.mode != f.mode || msg.width != f.width {
+			return f, nil
+		}
+		f.loading = false
+		f.viewport.SetContent(msg.content)
+		return f, nil
+	case dialog.ThemeSelectedMsg:
+		if f.HasFile() {
+			return f, f.render()
+		}
+	case tea.MouseWheelMsg:
+		var cmd tea.Cmd
+		f.viewport, cmd = f.viewport.Update(msg)
+		return f, cmd
+	}
+	return f, nil
+}
+
+func (f *fileViewerComponent) load(path string) tea.Cmd {
+	return func() tea.Msg {
+		response, err := f.app.Client.File.Read(context.Background(), sgptcoder.FileReadParams{
+			Path: sgptcoder.F(path),
+		})
+		if err != nil {
+			slog.Error("Failed to read file", "path", path, "error", err)
+			return fileLoadFailedMsg{path: path}
+		}
+		diffText := patchToUnifiedDiff(response.Patch)
+		if diffText == "" {
+			diffText = response.Diff
+		}
+		return fileLoadedMsg{
+			path:    path,
+			content: response.Content,
+			diff:    diffText,
+		}
+	}
+}
+
+func (f *fileViewerComponent) render() tea.Cmd {
+	path, mode, width := f.filename, f.mode, f.width
+	content, diffText := f.content, f.diff
+	return func() tea.Msg {
+		var rendered string
+		var err error
+		switch mode {
+		case ModeUnifiedDiff:
+			rendered, err = diff.FormatUnifiedDiff(path, diffText, diff.WithWidth(width))
+		case ModeSplitDiff:
+			rendered, err = diff.FormatDiff(path, diffText, diff.WithWidth(width))
+		default:
+			rendered = util.RenderFile(path, content, width)
+		}
+		if err != nil {
+			slog.Error("Failed to render diff", "path", path, "error", err)
+			rendered = util.RenderFile(path, content, width)
+		}
+		return renderCompleteMsg{path: path, mode: mode, width: width, content: rendered}
+	}
+}
+
+// patchToUnifiedDiff turns the structured patch returned by the server back
+// into unified diff text for the diff renderer.
+func patchToUnifiedDiff(patch sgptcoder.FileReadResponsePatch) string {
+	if len(patch.Hunks) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", patch.OldFileName, patch.NewFileName)
+	for _, hunk := range patch.Hunks {
+		fmt.Fprintf(
+			&sb,
+			"@@ -%d,%d +%d,%d @@\n",
+			int(hunk.OldStart),
+			int(hunk.OldLines),
+			int(hunk.NewStart),
+			int(hunk.NewLines),
+		)
+		for _, line := range hunk.Lines {
+			sb.WriteString(line)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+func (f *fileViewerComponent) View() string {
+	t := theme.CurrentTheme()
+	if !f.HasFile() {
+		return ""
+	}
+
+	base := styles.NewStyle().Foreground(t.Text()).Background(t.Background()).Bold(true).Render
+	muted := styles.NewStyle().Foreground(t.TextMuted()).Background(t.Background()).Render
+	header := base(util.Relative(f.filename)) + muted(" "+f.mode.String())
+	header = styles.NewStyle().
+		Background(t.Background()).
+		Width(f.width).
+		Render(header)
+
+	body := f.viewport.View()
+	if f.loading {
+		body = lipgloss.Place(
+			f.width,
+			f.height-2,
+			lipgloss.Center,
+			lipgloss.Center,
+			muted("Loading..."),
+			styles.WhitespaceStyle(t.Background()),
+		)
+	}
+	return styles.NewStyle().
+		Background(t.Background()).
+		PaddingLeft(2).
+		PaddingRight(2).
+		Render("\n" + header + "\n" + body)
+}
+
+func (f *fileViewerComponent) HasFile() bool {
+	return f.filename != ""
+}
+
+func (f *fileViewerComponent) Filename() string {
+	return f.filename
+}
+
+func (f *fileViewerComponent) Mode() Mode {
+	return f.mode
+}
+
+// OpenFile loads the file with File.Read and shows it, as a diff if the file
+// has uncommitted changes.
+func (f *fileViewerComponent) OpenFile(path string) (tea.Model, tea.Cmd) {
+	f.filename = path
+	f.loading = true
+	f.viewport.GotoTop()
+	return f, f.load(path)
+}
+
+func (f *fileViewerComponent) Clear() {
+	f.filename = ""
+	f.content = ""
+	f.diff = ""
+	f.mode = ModeContent
+	f.loading = false
+	f.viewport.SetContent("")
+	f.viewport.GotoTop()
+}
+
+// ToggleMode cycles between the file content, the unified diff and the split
+// diff. Files without changes only have their content.
+func (f *fileViewerComponent) ToggleMode() (tea.Model, tea.Cmd) {
+	if !f.HasFile() {
+		return f, nil
+	}
+	if f.diff == "" {
+		return f, toast.NewInfoToast("No changes to " + util.Relative(f.filename))
+	}
+	f.mode = (f.mode + 1) % 3
+	f.loading = true
+	return f, f.render()
+}
+
+func (f *fileViewerComponent) PageUp() (tea.Model, tea.Cmd) {
+	f.viewport.ViewUp()
+	return f, nil
+}
+
+func (f *fileViewerComponent) PageDown() (tea.Model, tea.Cmd) {
+	f.viewport.ViewDown()
+	return f, nil
+}
+
+func (f *fileViewerComponent) HalfPageUp() (tea.Model, tea.Cmd) {
+	f.viewport.HalfViewUp()
+	return f, nil
+}
+
+func (f *fileViewerComponent) HalfPageDown() (tea.Model, tea.Cmd) {
+	f.viewport.HalfViewDown()
+	return f, nil
+}
+
+func (f *fileViewerComponent) GotoTop() (tea.Model, tea.Cmd) {
+	f.viewport.GotoTop()
+	return f, nil
+}
+
+func (f *fileViewerComponent) GotoBottom() (tea.Model, tea.Cmd) {
+	f.viewport.GotoBottom()
+	return f, nil
+}
+
+func NewFileViewerComponent(app *app.App) FileViewerComponent {
+	return &fileViewerComponent{
+		app:      app,
+		viewport: viewport.New(),
+	}
+}
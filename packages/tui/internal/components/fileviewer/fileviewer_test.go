@@ -0,0 +1,32 @@
+package fileviewer
+
+import (
+	"testing"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+)
+
+func TestPatchToUnifiedDiff(t *testing.T) {
+	patch := sgptcoder.FileReadResponsePatch{
+		OldFileName: "main.go",
+		NewFileName: "main.go",
+		Hunks: []sgptcoder.FileReadResponsePatchHunk{
+			{
+				OldStart: 1,
+				OldLines: 2,
+				NewStart: 1,
+				NewLines: 2,
+				Lines:    []string{" package main", "-var a = 1", "+var a = 2"},
+			},
+		},
+	}
+
+	expected := "--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,2 @@\n package main\n-var a = 1\n+var a = 2\n"
+	if got := patchToUnifiedDiff(patch); got != expected {
+		t.Errorf("patchToUnifiedDiff() = %q, want %q", got, expected)
+	}
+
+	if got := patchToUnifiedDiff(sgptcoder.FileReadResponsePatch{}); got != "" {
+		t.Errorf("patchToUnifiedDiff() without hunks = %q, want empty", got)
+	}
+}
@@ -13,11 +13,23 @@ import (
 	"github.com/skorpland/sgptcoder/internal/theme"
 )
 
+// Level is the kind of a toast notification, independent of its color.
+type Level int
+
+const (
+	LevelDefault Level = iota
+	LevelInfo
+	LevelSuccess
+	LevelWarning
+	LevelError
+)
+
 // ShowToastMsg is a message to display a toast notification
 type ShowToastMsg struct {
 	Message  string
 	Title    *string
 	Color    compat.AdaptiveColor
+	Level    Level
 	Duration time.Duration
 }
 
@@ -189,6 +201,7 @@ type toastOptions struct {
 	title    *string
 	duration *time.Duration
 	color    *compat.AdaptiveColor
+	level    Level
 }
 
 type ToastOption func(*toastOptions)
@@ -210,6 +223,12 @@ func WithColor(color compat.AdaptiveColor) ToastOption {
 	}
 }
 
+func withLevel(level Level) ToastOption {
+	return func(t *toastOptions) {
+		t.level = level
+	}
+}
+
 func NewToast(message string, options ...ToastOption) tea.Cmd {
 	t := theme.CurrentTheme()
 	duration := 5 * time.Second
@@ -229,12 +248,13 @@ func NewToast(message string, options ...ToastOption) tea.Cmd {
 			Title:    opts.title,
 			Duration: *opts.duration,
 			Color:    *opts.color,
+			Level:    opts.level,
 		}
 	}
 }
 
 func NewInfoToast(message string, options ...ToastOption) tea.Cmd {
-	options = append(options, WithColor(theme.CurrentTheme().Info()))
+	options = append(options, WithColor(theme.CurrentTheme().Info()), withLevel(LevelInfo))
 	return NewToast(
 		message,
 		options...,
@@ -242,7 +262,7 @@ func NewInfoToast(message string, options ...ToastOption) tea.Cmd {
 }
 
 func NewSuccessToast(message string, options ...ToastOption) tea.Cmd {
-	options = append(options, WithColor(theme.CurrentTheme().Success()))
+	options = append(options, WithColor(theme.CurrentTheme().Success()), withLevel(LevelSuccess))
 	return NewToast(
 		message,
 		options...,
@@ -250,7 +270,7 @@ func NewSuccessToast(message string, options ...ToastOption) tea.Cmd {
 }
 
 func NewWarningToast(message string, options ...ToastOption) tea.Cmd {
-	options = append(options, WithColor(theme.CurrentTheme().Warning()))
+	options = append(options, WithColor(theme.CurrentTheme().Warning()), withLevel(LevelWarning))
 	return NewToast(
 		message,
 		options...,
@@ -258,7 +278,7 @@ func NewWarningToast(message string, options ...ToastOption) tea.Cmd {
 }
 
 func NewErrorToast(message string, options ...ToastOption) tea.Cmd {
-	options = append(options, WithColor(theme.CurrentTheme().Error()))
+	options = append(options, WithColor(theme.CurrentTheme().Error()), withLevel(LevelError))
 	return NewToast(
 		message,
 		options...,
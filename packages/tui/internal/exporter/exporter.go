@@ -0,0 +1,114 @@
+// Package exporter writes session transcripts as Markdown, JSON or HTML.
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder/internal/app"
+)
+
+// Format is the file format of an exported transcript.
+type Format string
+
+const (
+	// FormatMarkdown is a readable transcript including tool inputs, outputs
+	// and diffs, with the token usage and cost of every step.
+	FormatMarkdown Format = "markdown"
+	// FormatJSON is a lossless transcript holding the session and every
+	// message with all of its parts, as returned by the server.
+	FormatJSON Format = "json"
+	// FormatHTML is the Markdown transcript rendered as a self-contained HTML
+	// page.
+	FormatHTML Format = "html"
+)
+
+// ParseFormat parses a format name or file extension.
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(value, ".")) {
+	case "markdown", "md":
+		return FormatMarkdown, nil
+	case "json":
+		return FormatJSON, nil
+	case "html", "htm":
+		return FormatHTML, nil
+	}
+	return "", fmt.Errorf("invalid export format %q, expected markdown, json or html", value)
+}
+
+// FormatForPath picks the format from the extension of path, falling back to
+// Markdown.
+func FormatForPath(path string) Format {
+	format, err := ParseFormat(filepath.Ext(path))
+	if err != nil {
+		return FormatMarkdown
+	}
+	return format
+}
+
+// Extension returns the file extension for the format, including the dot.
+func (f Format) Extension() string {
+	switch f {
+	case FormatJSON:
+		return ".json"
+	case FormatHTML:
+		return ".html"
+	}
+	return ".md"
+}
+
+// Options controls what goes into an exported transcript. The JSON format is
+// always complete and ignores them.
+type Options struct {
+	// Reasoning includes the reasoning parts of assistant messages.
+	Reasoning bool
+}
+
+// Export writes the session transcript to w in the given format.
+func Export(
+	w io.Writer,
+	format Format,
+	session sgptcoder.Session,
+	messages []app.Message,
+	opts Options,
+) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, session, messages)
+	case FormatHTML:
+		return writeHTML(w, session, messages, opts)
+	case FormatMarkdown, "":
+		_, err := io.WriteString(w, Markdown(session, messages, opts))
+		return err
+	}
+	return fmt.Errorf("invalid export format %q", format)
+}
+
+// WriteFile exports the session transcript to the file at path, creating any
+// missing parent directories.
+func WriteFile(
+	path string,
+	format Format,
+	session sgptcoder.Session,
+	messages []app.Message,
+	opts Options,
+) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Export(file, format, session, messages, opts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
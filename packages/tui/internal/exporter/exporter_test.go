@@ -0,0 +1,175 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder/internal/app"
+)
+
+func decodeMessage(t *testing.T, info string, parts ...string) app.Message {
+	t.Helper()
+	var message sgptcoder.Message
+	if err := json.Unmarshal([]byte(info), &message); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	result := app.Message{Info: message.AsUnion()}
+	for _, raw := range parts {
+		var part sgptcoder.Part
+		if err := json.Unmarshal([]byte(raw), &part); err != nil {
+			t.Fatalf("failed to decode part: %v", err)
+		}
+		result.Parts = append(result.Parts, part.AsUnion())
+	}
+	return result
+}
+
+func testTranscript(t *testing.T) (sgptcoder.Session, []app.Message) {
+	t.Helper()
+	var session sgptcoder.Session
+	err := json.Unmarshal([]byte(`{"id":"ses_1","directory":"/src","projectID":"p","time":{"created":0,"updated":0},"title":"Fix <b>bug</b>","version":"1"}`), &session)
+	if err != nil {
+		t.Fatalf("failed to decode session: %v", err)
+	}
+	return session, []app.Message{
+		decodeMessage(t,
+			`{"id":"msg_1","role":"user","sessionID":"ses_1","time":{"created":0}}`,
+			`{"id":"prt_1","messageID":"msg_1","sessionID":"ses_1","type":"text","text":"fix it <script>"}`,
+		),
+		decodeMessage(t,
+			`{"id":"msg_2","role":"assistant","sessionID":"ses_1","cost":0.5,"mode":"build","modelID":"m","providerID":"p","path":{"cwd":"/src","root":"/src"},"system":[],"time":{"created":0},"tokens":{"input":1200,"output":30,"reasoning":0,"cache":{"read":0,"write":0}},"extra":"kept"}`,
+			`{"id":"prt_2","messageID":"msg_2","sessionID":"ses_1","type":"reasoning","text":"thinking hard","time":{"start":0}}`,
+			`{"id":"prt_3","messageID":"msg_2","sessionID":"ses_1","type":"tool","callID":"c","tool":"edit","state":{"status":"completed","input":{"filePath":"main.go"},"output":"has \u0060\u0060\u0060 fence","metadata":{"diff":"-a\n+b"},"title":"main.go","time":{"start":0,"end":1}}}`,
+			`{"id":"prt_4","messageID":"msg_2","sessionID":"ses_1","type":"step-finish","cost":0.5,"tokens":{"input":1200,"output":30,"reasoning":0,"cache":{"read":0,"write":0}}}`,
+		),
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	session, messages := testTranscript(t)
+
+	output := Markdown(session, messages, Options{})
+	for _, expected := range []string{
+		"# Fix <b>bug</b>",
+		"fix it <script>",
+		"### Tool: edit `main.go`",
+		"```json\n{\n  \"filePath\": \"main.go\"\n}\n```",
+		"```diff\n-a\n+b\n```",
+		"````\nhas ``` fence\n````",
+		"*Step: 1.2K input, 30 output tokens, $0.5000*",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected markdown to contain %q, got:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "thinking hard") {
+		t.Errorf("expected reasoning to be left out, got:\n%s", output)
+	}
+
+	output = Markdown(session, messages, Options{Reasoning: true})
+	if !strings.Contains(output, "> thinking hard") {
+		t.Errorf("expected reasoning to be included, got:\n%s", output)
+	}
+}
+
+func TestToolCalls(t *testing.T) {
+	_, messages := testTranscript(t)
+	if output := ToolCalls(messages[0]); output != "" {
+		t.Errorf("expected no tool calls for a user message, got:\n%s", output)
+	}
+	output := ToolCalls(messages[1])
+	if !strings.HasPrefix(output, "### Tool: edit `main.go`") || strings.Contains(output, "Step:") {
+		t.Errorf("expected only the tool call, got:\n%s", output)
+	}
+}
+
+func TestJSONKeepsUnknownFields(t *testing.T) {
+	session, messages := testTranscript(t)
+
+	var buf bytes.Buffer
+	if err := Export(&buf, FormatJSON, session, messages, Options{}); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	var transcript Transcript
+	if err := json.Unmarshal(buf.Bytes(), &transcript); err != nil {
+		t.Fatalf("failed to decode transcript: %v", err)
+	}
+	if transcript.Version != TranscriptVersion {
+		t.Errorf("Version = %d, want %d", transcript.Version, TranscriptVersion)
+	}
+	if len(transcript.Messages) != 2 || len(transcript.Messages[1].Parts) != 3 {
+		t.Fatalf("unexpected transcript layout: %+v", transcript)
+	}
+	var info bytes.Buffer
+	if err := json.Compact(&info, transcript.Messages[1].Info); err != nil {
+		t.Fatalf("failed to compact message: %v", err)
+	}
+	if !strings.Contains(info.String(), `"extra":"kept"`) {
+		t.Errorf("expected unknown field to be kept, got %s", info.String())
+	}
+}
+
+func TestHTMLEscapesContent(t *testing.T) {
+	session, messages := testTranscript(t)
+
+	var buf bytes.Buffer
+	if err := Export(&buf, FormatHTML, session, messages, Options{}); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	output := buf.String()
+	if strings.Contains(output, "<script>") || strings.Contains(output, "<b>bug") {
+		t.Errorf("expected markup from the transcript to be escaped, got:\n%s", output)
+	}
+	if !strings.Contains(output, "<title>Fix &lt;b&gt;bug&lt;/b&gt;</title>") {
+		t.Errorf("expected escaped title, got:\n%s", output)
+	}
+}
+
+func TestFormatForPath(t *testing.T) {
+	tests := map[string]Format{
+		"out.json":       FormatJSON,
+		"out.HTML":       FormatHTML,
+		"notes.md":       FormatMarkdown,
+		"transcript":     FormatMarkdown,
+		"dir/review.htm": FormatHTML,
+	}
+	for path, expected := range tests {
+		if got := FormatForPath(path); got != expected {
+			t.Errorf("FormatForPath(%q) = %q, want %q", path, got, expected)
+		}
+	}
+}
+
+func TestReadTranscript(t *testing.T) {
+	session, messages := testTranscript(t)
+
+	var buf bytes.Buffer
+	if err := Export(&buf, FormatJSON, session, messages, Options{}); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	readSession, readMessages, err := ReadTranscript(&buf)
+	if err != nil {
+		t.Fatalf("ReadTranscript() error = %v", err)
+	}
+	if readSession.ID != session.ID || readSession.Title != session.Title {
+		t.Errorf("session = %+v, want %+v", readSession, session)
+	}
+	if len(readMessages) != len(messages) {
+		t.Fatalf("got %d messages, want %d", len(readMessages), len(messages))
+	}
+	tool, ok := readMessages[1].Parts[1].(sgptcoder.ToolPart)
+	if !ok {
+		t.Fatalf("expected a tool part, got %T", readMessages[1].Parts[1])
+	}
+	if tool.State.Output != "has ``` fence" {
+		t.Errorf("tool output = %q", tool.State.Output)
+	}
+
+	_, _, err = ReadTranscript(strings.NewReader(`{"version":99,"session":{},"messages":[]}`))
+	if err == nil {
+		t.Error("expected an error for an unsupported version")
+	}
+}
@@ -0,0 +1,68 @@
+package exporter
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder/internal/app"
+	"github.com/yuin/goldmark"
+)
+
+// htmlTemplate wraps the rendered transcript in a page with inline styles, so
+// the file can be attached and opened anywhere without other assets.
+var htmlTemplate = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 56rem; margin: 2rem auto; padding: 0 1rem; font: 15px/1.6 -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; background: #ffffff; }
+h1 { font-size: 1.6rem; }
+h2 { font-size: 1.2rem; margin-top: 2rem; }
+h3 { font-size: 1rem; margin-bottom: 0.25rem; }
+hr { border: 0; border-top: 1px solid #d1d9e0; margin: 2rem 0; }
+code { font: 13px/1.45 ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; background: #f6f8fa; padding: 0.1rem 0.3rem; border-radius: 4px; }
+pre { background: #f6f8fa; padding: 0.75rem 1rem; border-radius: 6px; overflow-x: auto; }
+pre code { padding: 0; background: none; }
+blockquote { margin: 0 0 1rem; padding: 0 1rem; color: #59636e; border-left: 0.25rem solid #d1d9e0; }
+@media (prefers-color-scheme: dark) {
+  body { color: #f0f6fc; background: #0d1117; }
+  hr { border-top-color: #3d444d; }
+  code, pre { background: #151b23; }
+  blockquote { color: #9198a1; border-left-color: #3d444d; }
+}
+</style>
+</head>
+<body>
+{{.Body}}
+</body>
+</html>
+`))
+
+func writeHTML(
+	w io.Writer,
+	session sgptcoder.Session,
+	messages []app.Message,
+	opts Options,
+) error {
+	// goldmark escapes raw HTML by default, so nothing in the transcript can
+	// inject markup into the page.
+	var body bytes.Buffer
+	if err := goldmark.Convert([]byte(Markdown(session, messages, opts)), &body); err != nil {
+		return err
+	}
+	title := session.Title
+	if title == "" {
+		title = "Conversation History"
+	}
+	return htmlTemplate.Execute(w, struct {
+		Title string
+		Body  template.HTML
+	}{
+		Title: title,
+		Body:  template.HTML(body.String()),
+	})
+}
@@ -0,0 +1,149 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder/internal/app"
+)
+
+// TranscriptVersion is the version of the [Transcript] layout written by the
+// JSON format.
+const TranscriptVersion = 1
+
+// Transcript is the layout of the JSON format. Messages have the same shape as
+// the server's message list, so every field the server sent is kept, including
+// ones this client does not know about.
+type Transcript struct {
+	Version  int                 `json:"version"`
+	Session  json.RawMessage     `json:"session"`
+	Messages []TranscriptMessage `json:"messages"`
+}
+
+// TranscriptMessage is a message and its parts in a [Transcript].
+type TranscriptMessage struct {
+	Info  json.RawMessage   `json:"info"`
+	Parts []json.RawMessage `json:"parts"`
+}
+
+// NewTranscript builds the JSON transcript of a session.
+func NewTranscript(session sgptcoder.Session, messages []app.Message) (Transcript, error) {
+	encodedSession, err := encode(session, session.JSON.RawJSON())
+	if err != nil {
+		return Transcript{}, err
+	}
+	transcript := Transcript{
+		Version:  TranscriptVersion,
+		Session:  encodedSession,
+		Messages: make([]TranscriptMessage, 0, len(messages)),
+	}
+	for _, message := range messages {
+		info, err := encodeMessage(message.Info)
+		if err != nil {
+			return Transcript{}, err
+		}
+		parts := make([]json.RawMessage, 0, len(message.Parts))
+		for _, part := range message.Parts {
+			encoded, err := encodePart(part)
+			if err != nil {
+				return Transcript{}, err
+			}
+			parts = append(parts, encoded)
+		}
+		transcript.Messages = append(transcript.Messages, TranscriptMessage{Info: info, Parts: parts})
+	}
+	return transcript, nil
+}
+
+// ReadTranscript decodes a transcript written by the JSON format.
+func ReadTranscript(r io.Reader) (sgptcoder.Session, []app.Message, error) {
+	var transcript Transcript
+	if err := json.NewDecoder(r).Decode(&transcript); err != nil {
+		return sgptcoder.Session{}, nil, fmt.Errorf("invalid transcript: %w", err)
+	}
+	if transcript.Version < 1 || transcript.Version > TranscriptVersion {
+		return sgptcoder.Session{}, nil, fmt.Errorf("unsupported transcript version %d", transcript.Version)
+	}
+
+	var session sgptcoder.Session
+	if err := json.Unmarshal(transcript.Session, &session); err != nil {
+		return sgptcoder.Session{}, nil, fmt.Errorf("invalid transcript session: %w", err)
+	}
+	messages := make([]app.Message, 0, len(transcript.Messages))
+	for _, encoded := range transcript.Messages {
+		var info sgptcoder.Message
+		if err := json.Unmarshal(encoded.Info, &info); err != nil {
+			return sgptcoder.Session{}, nil, fmt.Errorf("invalid transcript message: %w", err)
+		}
+		message := app.Message{Info: info.AsUnion()}
+		if message.Info == nil {
+			continue
+		}
+		for _, encodedPart := range encoded.Parts {
+			var part sgptcoder.Part
+			if err := json.Unmarshal(encodedPart, &part); err != nil {
+				return sgptcoder.Session{}, nil, fmt.Errorf("invalid transcript part: %w", err)
+			}
+			if part.AsUnion() != nil {
+				message.Parts = append(message.Parts, part.AsUnion())
+			}
+		}
+		messages = append(messages, message)
+	}
+	return session, messages, nil
+}
+
+func writeJSON(w io.Writer, session sgptcoder.Session, messages []app.Message) error {
+	transcript, err := NewTranscript(session, messages)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(transcript)
+}
+
+// encode prefers the raw JSON the value was decoded from, which is lossless,
+// and falls back to marshalling values built locally.
+func encode(value any, raw string) (json.RawMessage, error) {
+	if raw != "" && json.Valid([]byte(raw)) {
+		return json.RawMessage(raw), nil
+	}
+	return json.Marshal(value)
+}
+
+func encodeMessage(message sgptcoder.MessageUnion) (json.RawMessage, error) {
+	switch message := message.(type) {
+	case sgptcoder.UserMessage:
+		return encode(message, message.JSON.RawJSON())
+	case sgptcoder.AssistantMessage:
+		return encode(message, message.JSON.RawJSON())
+	}
+	return json.Marshal(message)
+}
+
+func encodePart(part sgptcoder.PartUnion) (json.RawMessage, error) {
+	switch part := part.(type) {
+	case sgptcoder.TextPart:
+		return encode(part, part.JSON.RawJSON())
+	case sgptcoder.ReasoningPart:
+		return encode(part, part.JSON.RawJSON())
+	case sgptcoder.FilePart:
+		return encode(part, part.JSON.RawJSON())
+	case sgptcoder.ToolPart:
+		return encode(part, part.JSON.RawJSON())
+	case sgptcoder.StepStartPart:
+		return encode(part, part.JSON.RawJSON())
+	case sgptcoder.StepFinishPart:
+		return encode(part, part.JSON.RawJSON())
+	case sgptcoder.SnapshotPart:
+		return encode(part, part.JSON.RawJSON())
+	case sgptcoder.PartPatchPart:
+		return encode(part, part.JSON.RawJSON())
+	case sgptcoder.AgentPart:
+		return encode(part, part.JSON.RawJSON())
+	}
+	return json.Marshal(part)
+}
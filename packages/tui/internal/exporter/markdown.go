@@ -0,0 +1,223 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder/internal/app"
+)
+
+const timeFormat = "2006-01-02 15:04:05"
+
+// Markdown renders the session transcript as Markdown. Every tool call is
+// written with its input, output or error, and the diff for edits. Each step
+// of an assistant reply ends with its token usage and cost.
+func Markdown(session sgptcoder.Session, messages []app.Message, opts Options) string {
+	var sb strings.Builder
+
+	title := session.Title
+	if title == "" {
+		title = "Conversation History"
+	}
+	fmt.Fprintf(&sb, "# %s\n\n", title)
+	if session.ID != "" {
+		fmt.Fprintf(&sb, "- Session: `%s`\n", session.ID)
+	}
+	if session.Time.Created > 0 {
+		fmt.Fprintf(&sb, "- Created: %s\n", formatTime(session.Time.Created))
+	}
+	if session.Directory != "" {
+		fmt.Fprintf(&sb, "- Directory: `%s`\n", session.Directory)
+	}
+	if session.Share.URL != "" {
+		fmt.Fprintf(&sb, "- Shared: %s\n", session.Share.URL)
+	}
+
+	var cost, input, output float64
+	for _, message := range messages {
+		switch info := message.Info.(type) {
+		case sgptcoder.UserMessage:
+			sb.WriteString("\n---\n\n")
+			fmt.Fprintf(&sb, "## User (*%s*)\n\n", formatTime(info.Time.Created))
+		case sgptcoder.AssistantMessage:
+			cost += info.Cost
+			input += info.Tokens.Input
+			output += info.Tokens.Output
+			sb.WriteString("\n---\n\n")
+			fmt.Fprintf(
+				&sb,
+				"## Assistant (*%s/%s, %s*)\n\n",
+				info.ProviderID,
+				info.ModelID,
+				formatTime(info.Time.Created),
+			)
+		default:
+			continue
+		}
+
+		for _, part := range message.Parts {
+			writeMarkdownPart(&sb, part, opts)
+		}
+
+		if info, ok := message.Info.(sgptcoder.AssistantMessage); ok && info.Error.Name != "" {
+			fmt.Fprintf(&sb, "> **Error:** %s\n\n", assistantError(info.Error))
+		}
+	}
+
+	if cost > 0 || input > 0 || output > 0 {
+		sb.WriteString("\n---\n\n")
+		fmt.Fprintf(
+			&sb,
+			"**Total:** %s input, %s output tokens, $%.4f\n",
+			formatTokens(input),
+			formatTokens(output),
+			cost,
+		)
+	}
+
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+func writeMarkdownPart(sb *strings.Builder, part sgptcoder.PartUnion, opts Options) {
+	switch part := part.(type) {
+	case sgptcoder.TextPart:
+		if part.Synthetic || strings.TrimSpace(part.Text) == "" {
+			return
+		}
+		sb.WriteString(strings.TrimSpace(part.Text))
+		sb.WriteString("\n\n")
+	case sgptcoder.ReasoningPart:
+		if !opts.Reasoning || strings.TrimSpace(part.Text) == "" {
+			return
+		}
+		sb.WriteString("> **Reasoning**\n>\n")
+		for line := range strings.SplitSeq(strings.TrimSpace(part.Text), "\n") {
+			sb.WriteString(strings.TrimRight("> "+line, " "))
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	case sgptcoder.FilePart:
+		name := part.Filename
+		if name == "" {
+			name = part.URL
+		}
+		fmt.Fprintf(sb, "**File:** `%s` (%s)\n\n", name, part.Mime)
+	case sgptcoder.AgentPart:
+		fmt.Fprintf(sb, "**Agent:** @%s\n\n", part.Name)
+	case sgptcoder.ToolPart:
+		writeMarkdownTool(sb, part)
+	case sgptcoder.StepFinishPart:
+		fmt.Fprintf(
+			sb,
+			"*Step: %s input, %s output",
+			formatTokens(part.Tokens.Input),
+			formatTokens(part.Tokens.Output),
+		)
+		if part.Tokens.Reasoning > 0 {
+			fmt.Fprintf(sb, ", %s reasoning", formatTokens(part.Tokens.Reasoning))
+		}
+		if part.Tokens.Cache.Read > 0 || part.Tokens.Cache.Write > 0 {
+			fmt.Fprintf(
+				sb,
+				", %s cache read, %s cache write",
+				formatTokens(part.Tokens.Cache.Read),
+				formatTokens(part.Tokens.Cache.Write),
+			)
+		}
+		fmt.Fprintf(sb, " tokens, $%.4f*\n\n", part.Cost)
+	}
+}
+
+// ToolCalls renders the tool calls of a message as Markdown, as they appear
+// in a transcript. It is empty if the message has no tool calls.
+func ToolCalls(message app.Message) string {
+	var sb strings.Builder
+	for _, part := range message.Parts {
+		if part, ok := part.(sgptcoder.ToolPart); ok {
+			writeMarkdownTool(&sb, part)
+		}
+	}
+	return sb.String()
+}
+
+func writeMarkdownTool(sb *strings.Builder, part sgptcoder.ToolPart) {
+	fmt.Fprintf(sb, "### Tool: %s", part.Tool)
+	if part.State.Title != "" {
+		fmt.Fprintf(sb, " `%s`", part.State.Title)
+	}
+	sb.WriteString("\n\n")
+
+	if input, ok := part.State.Input.(map[string]any); ok && len(input) > 0 {
+		if encoded, err := json.MarshalIndent(input, "", "  "); err == nil {
+			sb.WriteString("**Input**\n\n")
+			writeFence(sb, "json", string(encoded))
+		}
+	}
+
+	metadata, _ := part.State.Metadata.(map[string]any)
+	if diff, ok := metadata["diff"].(string); ok && strings.TrimSpace(diff) != "" {
+		sb.WriteString("**Diff**\n\n")
+		writeFence(sb, "diff", diff)
+	}
+
+	switch part.State.Status {
+	case sgptcoder.ToolPartStateStatusCompleted:
+		if strings.TrimSpace(part.State.Output) != "" {
+			sb.WriteString("**Output**\n\n")
+			writeFence(sb, "", part.State.Output)
+		}
+	case sgptcoder.ToolPartStateStatusError:
+		fmt.Fprintf(sb, "> **Error:** %s\n\n", strings.TrimSpace(part.State.Error))
+	default:
+		fmt.Fprintf(sb, "*%s*\n\n", part.State.Status)
+	}
+}
+
+// writeFence writes content as a fenced code block, using a fence longer than
+// any run of backticks in the content so it cannot be closed early.
+func writeFence(sb *strings.Builder, language, content string) {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	sb.WriteString(fence + language + "\n")
+	sb.WriteString(strings.TrimRight(content, "\n"))
+	sb.WriteString("\n" + fence + "\n\n")
+}
+
+func assistantError(err sgptcoder.AssistantMessageError) string {
+	switch err := err.AsUnion().(type) {
+	case sgptcoder.ProviderAuthError:
+		return err.Data.Message
+	case sgptcoder.UnknownError:
+		return err.Data.Message
+	case sgptcoder.MessageAbortedError:
+		if err.Data.Message != "" {
+			return err.Data.Message
+		}
+	}
+	return string(err.Name)
+}
+
+func formatTime(millis float64) string {
+	return time.UnixMilli(int64(millis)).Format(timeFormat)
+}
+
+func formatTokens(tokens float64) string {
+	switch {
+	case tokens >= 1_000_000:
+		return fmt.Sprintf("%.1fM", tokens/1_000_000)
+	case tokens >= 1_000:
+		return fmt.Sprintf("%.1fK", tokens/1_000)
+	}
+	return fmt.Sprintf("%d", int(tokens))
+}
@@ -0,0 +1,371 @@
+// Package headless drives a session without the terminal UI. It reuses the
+// same [app.App] as the TUI to send a prompt or command, answers permission
+// requests according to a [PermissionPolicy], writes the assistant output to
+// stdout and reports the outcome as a process exit code.
+package headless
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder/internal/app"
+	"github.com/skorpland/sgptcoder/internal/components/toast"
+	"github.com/skorpland/sgptcoder/internal/util"
+)
+
+// Exit codes returned by [Run].
+const (
+	// ExitSuccess means the session went idle after answering the prompt.
+	ExitSuccess = 0
+	// ExitError means the prompt could not be sent, the event stream failed or
+	// the session reported an error, including an aborted reply.
+	ExitError = 1
+	// ExitUsage means the options were invalid.
+	ExitUsage = 2
+	// ExitPermissionRejected means the reply completed but the permission
+	// policy rejected at least one permission request along the way.
+	ExitPermissionRejected = 3
+	// ExitInterrupted means the context was cancelled before the session went
+	// idle. The running reply is aborted.
+	ExitInterrupted = 130
+)
+
+// Options configures a headless run.
+type Options struct {
+	// Prompt is the text to send. It defaults to the app's InitialPrompt.
+	Prompt string
+	// Command runs a custom command with Arguments instead of sending Prompt.
+	Command   string
+	Arguments string
+	// Format is the output format. It defaults to [FormatText].
+	Format Format
+	// Permissions answers permission requests of the session and of the
+	// sessions it spawns. The zero value rejects every request.
+	Permissions PermissionPolicy
+	// Stdout and Stderr default to os.Stdout and os.Stderr.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Run sends the prompt or command described by opts and blocks until the
+// session goes idle, errors or ctx is cancelled. The app's InitialModel,
+// InitialAgent and InitialSession are honored as in the TUI. Run does not
+// install a signal handler; cancel ctx to interrupt it.
+func Run(ctx context.Context, a *app.App, opts Options) int {
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = os.Stderr
+	}
+	if opts.Format == "" {
+		opts.Format = FormatText
+	}
+	if opts.Prompt == "" && opts.Command == "" && a.InitialPrompt != nil {
+		opts.Prompt = *a.InitialPrompt
+	}
+	if opts.Prompt == "" && opts.Command == "" {
+		fmt.Fprintln(opts.Stderr, "error: a prompt or command is required")
+		return ExitUsage
+	}
+	// The prompt is sent by the model once a session is ready, not by
+	// InitializeProvider.
+	a.InitialPrompt = nil
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribe before sending anything so that no part of the reply is missed.
+	stream := a.Client.Event.ListStreaming(ctx, sgptcoder.EventListParams{})
+	defer stream.Close()
+	if !stream.Next() {
+		err := stream.Err()
+		if err == nil {
+			err = errors.New("event stream closed")
+		}
+		fmt.Fprintf(opts.Stderr, "error: failed to subscribe to events: %v\n", err)
+		return ExitError
+	}
+
+	m := newModel(ctx, a, opts)
+	program := tea.NewProgram(
+		m,
+		tea.WithContext(ctx),
+		tea.WithInput(nil),
+		tea.WithOutput(io.Discard),
+		tea.WithoutSignalHandler(),
+	)
+
+	go func() {
+		for stream.Next() {
+			program.Send(stream.Current().AsUnion())
+		}
+		if err := stream.Err(); err != nil && ctx.Err() == nil {
+			program.Send(errorMsg{err: err})
+		}
+	}()
+
+	if _, err := program.Run(); err != nil {
+		if ctx.Err() != nil || errors.Is(err, tea.ErrProgramKilled) {
+			if m.sent && a.Session.ID != "" {
+				a.Cancel(context.Background(), a.Session.ID)
+			}
+			if !m.finished {
+				m.out.error("Interrupted", "the run was interrupted")
+				m.out.done(a.Session.ID, m.reply(), ExitInterrupted)
+			}
+			return ExitInterrupted
+		}
+		fmt.Fprintf(opts.Stderr, "error: %v\n", err)
+		return ExitError
+	}
+	return m.exitCode
+}
+
+type errorMsg struct {
+	err error
+}
+
+type model struct {
+	ctx  context.Context
+	app  *app.App
+	opts Options
+	out  output
+
+	// sessions holds the session the prompt is sent to and every session
+	// spawned from it, whose permission requests are answered as well.
+	sessions map[string]bool
+
+	sent          bool
+	submitted     bool
+	userMessageID string
+	texts         map[string]string
+	tools         map[string]sgptcoder.ToolPartStateStatus
+	rejected      bool
+	finished      bool
+	exitCode      int
+}
+
+func newModel(ctx context.Context, a *app.App, opts Options) *model {
+	return &model{
+		ctx:      ctx,
+		app:      a,
+		opts:     opts,
+		out:      newOutput(opts.Format, opts.Stdout, opts.Stderr),
+		sessions: map[string]bool{},
+		texts:    map[string]string{},
+		tools:    map[string]sgptcoder.ToolPartStateStatus{},
+	}
+}
+
+func (m *model) Init() tea.Cmd {
+	initialize := m.app.InitializeProvider()
+	if initialize == nil {
+		return util.CmdHandler(errorMsg{err: errors.New("no model is available, check the configured providers")})
+	}
+	var send tea.Cmd
+	if m.opts.Command != "" {
+		send = util.CmdHandler(app.SendCommand{Command: m.opts.Command, Args: m.opts.Arguments})
+	} else {
+		send = util.CmdHandler(app.SendPrompt{Text: m.opts.Prompt})
+	}
+	return tea.Sequence(initialize, send)
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case app.ModelSelectedMsg:
+		m.app.Provider = &msg.Provider
+		m.app.Model = &msg.Model
+	case app.SessionSelectedMsg:
+		messages, err := m.app.ListMessages(m.ctx, msg.ID)
+		if errors.Is(err, sgptcoder.ErrSessionNotFound) {
+			return m, m.fail("Error", "session "+msg.ID+" not found")
+		}
+		if err != nil {
+			return m, m.fail("Error", "failed to open session: "+err.Error())
+		}
+		m.app.Session = msg
+		m.app.Messages = messages
+		m.sessions[msg.ID] = true
+	case app.SessionCreatedMsg:
+		m.app.Session = msg.Session
+		m.sessions[msg.Session.ID] = true
+	case app.SendPrompt:
+		m.app, cmd = m.app.SendPrompt(m.ctx, msg)
+		m.sent = true
+		m.sessions[m.app.Session.ID] = true
+		if n := len(m.app.Messages); n > 0 {
+			if info, ok := m.app.Messages[n-1].Info.(sgptcoder.UserMessage); ok {
+				m.userMessageID = info.ID
+			}
+		}
+		return m, cmd
+	case app.SendCommand:
+		m.app, cmd = m.app.SendCommand(m.ctx, msg.Command, msg.Args)
+		m.sent = true
+		m.sessions[m.app.Session.ID] = true
+		return m, cmd
+	case toast.ShowToastMsg:
+		if msg.Level == toast.LevelError {
+			name := "Error"
+			if msg.Title != nil {
+				name = *msg.Title
+			}
+			return m, m.fail(name, msg.Message)
+		}
+		slog.Debug("headless notification", "message", msg.Message)
+	case errorMsg:
+		return m, m.fail("Error", msg.err.Error())
+	case sgptcoder.EventListResponseEventSessionUpdated:
+		if m.sessions[msg.Properties.Info.ParentID] {
+			m.sessions[msg.Properties.Info.ID] = true
+		}
+		if msg.Properties.Info.ID == m.app.Session.ID {
+			m.app.Session = &msg.Properties.Info
+		}
+	case sgptcoder.EventListResponseEventMessageUpdated:
+		m.app.UpdateMessage(msg.Properties.Info)
+		if m.sent && msg.Properties.Info.SessionID == m.app.Session.ID &&
+			msg.Properties.Info.Role == sgptcoder.MessageRoleUser {
+			if m.userMessageID == "" {
+				m.userMessageID = msg.Properties.Info.ID
+			}
+			if msg.Properties.Info.ID == m.userMessageID {
+				m.submitted = true
+			}
+		}
+	case sgptcoder.EventListResponseEventMessagePartUpdated:
+		m.app.UpdatePart(msg.Properties.Part)
+		if m.isReply(msg.Properties.Part.SessionID, msg.Properties.Part.MessageID) {
+			m.writePart(msg.Properties.Part.AsUnion())
+		}
+	case sgptcoder.EventListResponseEventMessagePartRemoved:
+		m.app.RemovePart(msg.Properties.SessionID, msg.Properties.MessageID, msg.Properties.PartID)
+	case sgptcoder.EventListResponseEventMessageRemoved:
+		m.app.RemoveMessage(msg.Properties.SessionID, msg.Properties.MessageID)
+	case sgptcoder.EventListResponseEventPermissionUpdated:
+		if !m.sessions[msg.Properties.SessionID] {
+			return m, nil
+		}
+		return m, m.respond(msg.Properties)
+	case sgptcoder.EventListResponseEventPermissionReplied:
+		m.app.RemovePermission(msg.Properties.PermissionID)
+	case sgptcoder.EventListResponseEventSessionError:
+		if msg.Properties.SessionID != m.app.Session.ID || !m.sent {
+			return m, nil
+		}
+		name := string(msg.Properties.Error.Name)
+		var message string
+		switch err := msg.Properties.Error.AsUnion().(type) {
+		case sgptcoder.ProviderAuthError:
+			message = err.Data.Message
+		case sgptcoder.UnknownError:
+			message = err.Data.Message
+		case sgptcoder.MessageAbortedError:
+			message = err.Data.Message
+		}
+		return m, m.fail(name, message)
+	case sgptcoder.EventListResponseEventSessionIdle:
+		if msg.Properties.SessionID != m.app.Session.ID || !m.submitted {
+			return m, nil
+		}
+		if m.rejected {
+			return m, m.exit(ExitPermissionRejected)
+		}
+		return m, m.exit(ExitSuccess)
+	}
+	return m, nil
+}
+
+func (m *model) respond(permission sgptcoder.Permission) tea.Cmd {
+	m.app.AddPermission(permission)
+	response := m.opts.Permissions.Response(permission)
+	if response == sgptcoder.SessionPermissionRespondParamsResponseReject {
+		m.rejected = true
+	}
+	m.out.permission(permission, response)
+	return func() tea.Msg {
+		_, err := m.app.Client.Session.Permissions.Respond(
+			m.ctx,
+			permission.SessionID,
+			permission.ID,
+			sgptcoder.SessionPermissionRespondParams{Response: sgptcoder.F(response)},
+		)
+		if err != nil {
+			slog.Error("Failed to respond to permission request", "error", err)
+			return toast.NewErrorToast("Failed to respond to permission request: " + err.Error())()
+		}
+		return nil
+	}
+}
+
+// isReply reports whether the message is an assistant message answering the
+// prompt.
+func (m *model) isReply(sessionID, messageID string) bool {
+	if sessionID != m.app.Session.ID || m.userMessageID == "" || messageID <= m.userMessageID {
+		return false
+	}
+	for _, message := range m.app.Messages {
+		if info, ok := message.Info.(sgptcoder.AssistantMessage); ok && info.ID == messageID {
+			return !info.Summary
+		}
+	}
+	return false
+}
+
+func (m *model) reply() []app.Message {
+	var reply []app.Message
+	for _, message := range m.app.Messages {
+		if info, ok := message.Info.(sgptcoder.AssistantMessage); ok && m.isReply(info.SessionID, info.ID) {
+			reply = append(reply, message)
+		}
+	}
+	return reply
+}
+
+func (m *model) writePart(part sgptcoder.PartUnion) {
+	switch part := part.(type) {
+	case sgptcoder.TextPart:
+		if part.Synthetic {
+			return
+		}
+		previous := m.texts[part.ID]
+		if part.Text == previous {
+			return
+		}
+		m.texts[part.ID] = part.Text
+		// Text parts only ever grow while streaming.
+		m.out.text(part, strings.TrimPrefix(part.Text, previous))
+	case sgptcoder.ToolPart:
+		if m.tools[part.ID] == part.State.Status {
+			return
+		}
+		m.tools[part.ID] = part.State.Status
+		m.out.tool(part)
+	}
+}
+
+func (m *model) fail(name, message string) tea.Cmd {
+	m.out.error(name, message)
+	return m.exit(ExitError)
+}
+
+func (m *model) exit(code int) tea.Cmd {
+	if m.finished {
+		return nil
+	}
+	m.finished = true
+	m.exitCode = code
+	m.out.done(m.app.Session.ID, m.reply(), code)
+	return tea.Quit
+}
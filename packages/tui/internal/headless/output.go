@@ -0,0 +1,197 @@
+package headless
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder/internal/app"
+)
+
+// Format selects how the assistant output is written to stdout.
+type Format string
+
+const (
+	// FormatText streams the assistant text as it arrives. Tool progress is
+	// written to stderr.
+	FormatText Format = "text"
+	// FormatMarkdown writes the whole reply, including tool calls, as markdown
+	// once the session goes idle.
+	FormatMarkdown Format = "markdown"
+	// FormatJSON writes one JSON object per line for every text delta, tool
+	// update, permission decision and error, followed by a final "done" line.
+	FormatJSON Format = "json"
+)
+
+// ParseFormat parses the value of an output format flag.
+func ParseFormat(value string) (Format, error) {
+	switch Format(strings.ToLower(value)) {
+	case "", FormatText, "plain":
+		return FormatText, nil
+	case FormatMarkdown, "md":
+		return FormatMarkdown, nil
+	case FormatJSON, "jsonl":
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("invalid output format %q, expected text, markdown or json", value)
+}
+
+type output interface {
+	text(part sgptcoder.TextPart, delta string)
+	tool(part sgptcoder.ToolPart)
+	permission(permission sgptcoder.Permission, response sgptcoder.SessionPermissionRespondParamsResponse)
+	error(name, message string)
+	done(sessionID string, reply []app.Message, exitCode int)
+}
+
+func newOutput(format Format, stdout, stderr io.Writer) output {
+	switch format {
+	case FormatMarkdown:
+		return &markdownOutput{stderr: stderr, stdout: stdout}
+	case FormatJSON:
+		return &jsonOutput{encoder: json.NewEncoder(stdout)}
+	}
+	return &textOutput{stdout: stdout, stderr: stderr}
+}
+
+type textOutput struct {
+	stdout, stderr io.Writer
+	last           string
+}
+
+func (o *textOutput) text(part sgptcoder.TextPart, delta string) {
+	fmt.Fprint(o.stdout, delta)
+	o.last = delta
+}
+
+func (o *textOutput) tool(part sgptcoder.ToolPart) {
+	switch part.State.Status {
+	case sgptcoder.ToolPartStateStatusCompleted:
+		fmt.Fprintf(o.stderr, "[%s] %s\n", part.Tool, part.State.Title)
+	case sgptcoder.ToolPartStateStatusError:
+		fmt.Fprintf(o.stderr, "[%s] error: %s\n", part.Tool, part.State.Error)
+	}
+}
+
+func (o *textOutput) permission(permission sgptcoder.Permission, response sgptcoder.SessionPermissionRespondParamsResponse) {
+	fmt.Fprintf(o.stderr, "[permission] %s: %s\n", permission.Title, response)
+}
+
+func (o *textOutput) error(name, message string) {
+	fmt.Fprintf(o.stderr, "error: %s: %s\n", name, message)
+}
+
+func (o *textOutput) done(sessionID string, reply []app.Message, exitCode int) {
+	if o.last != "" && !strings.HasSuffix(o.last, "\n") {
+		fmt.Fprintln(o.stdout)
+	}
+}
+
+type markdownOutput struct {
+	stdout, stderr io.Writer
+}
+
+func (o *markdownOutput) text(part sgptcoder.TextPart, delta string) {}
+
+func (o *markdownOutput) tool(part sgptcoder.ToolPart) {}
+
+func (o *markdownOutput) permission(permission sgptcoder.Permission, response sgptcoder.SessionPermissionRespondParamsResponse) {
+	fmt.Fprintf(o.stderr, "[permission] %s: %s\n", permission.Title, response)
+}
+
+func (o *markdownOutput) error(name, message string) {
+	fmt.Fprintf(o.stderr, "error: %s: %s\n", name, message)
+}
+
+func (o *markdownOutput) done(sessionID string, reply []app.Message, exitCode int) {
+	var sb strings.Builder
+	for _, message := range reply {
+		for _, part := range message.Parts {
+			switch part := part.(type) {
+			case sgptcoder.TextPart:
+				if part.Synthetic || strings.TrimSpace(part.Text) == "" {
+					continue
+				}
+				sb.WriteString(strings.TrimSpace(part.Text))
+				sb.WriteString("\n\n")
+			case sgptcoder.ToolPart:
+				fmt.Fprintf(&sb, "**%s**", part.Tool)
+				if part.State.Title != "" {
+					fmt.Fprintf(&sb, " `%s`", part.State.Title)
+				}
+				sb.WriteString("\n\n")
+				if part.State.Status == sgptcoder.ToolPartStateStatusError {
+					fmt.Fprintf(&sb, "> Error: %s\n\n", part.State.Error)
+				}
+			}
+		}
+	}
+	fmt.Fprint(o.stdout, strings.TrimRight(sb.String(), "\n")+"\n")
+}
+
+// jsonRecord is one line of [FormatJSON] output.
+type jsonRecord struct {
+	Type           string `json:"type"`
+	SessionID      string `json:"sessionID,omitempty"`
+	MessageID      string `json:"messageID,omitempty"`
+	PartID         string `json:"partID,omitempty"`
+	Delta          string `json:"delta,omitempty"`
+	Tool           string `json:"tool,omitempty"`
+	Status         string `json:"status,omitempty"`
+	Title          string `json:"title,omitempty"`
+	PermissionID   string `json:"permissionID,omitempty"`
+	PermissionType string `json:"permissionType,omitempty"`
+	Response       string `json:"response,omitempty"`
+	Name           string `json:"name,omitempty"`
+	Error          string `json:"error,omitempty"`
+	ExitCode       *int   `json:"exitCode,omitempty"`
+}
+
+type jsonOutput struct {
+	encoder *json.Encoder
+}
+
+func (o *jsonOutput) text(part sgptcoder.TextPart, delta string) {
+	o.encoder.Encode(jsonRecord{
+		Type:      "text",
+		SessionID: part.SessionID,
+		MessageID: part.MessageID,
+		PartID:    part.ID,
+		Delta:     delta,
+	})
+}
+
+func (o *jsonOutput) tool(part sgptcoder.ToolPart) {
+	o.encoder.Encode(jsonRecord{
+		Type:      "tool",
+		SessionID: part.SessionID,
+		MessageID: part.MessageID,
+		PartID:    part.ID,
+		Tool:      part.Tool,
+		Status:    string(part.State.Status),
+		Title:     part.State.Title,
+		Error:     part.State.Error,
+	})
+}
+
+func (o *jsonOutput) permission(permission sgptcoder.Permission, response sgptcoder.SessionPermissionRespondParamsResponse) {
+	o.encoder.Encode(jsonRecord{
+		Type:           "permission",
+		SessionID:      permission.SessionID,
+		MessageID:      permission.MessageID,
+		PermissionID:   permission.ID,
+		PermissionType: permission.Type,
+		Title:          permission.Title,
+		Response:       string(response),
+	})
+}
+
+func (o *jsonOutput) error(name, message string) {
+	o.encoder.Encode(jsonRecord{Type: "error", Name: name, Error: message})
+}
+
+func (o *jsonOutput) done(sessionID string, reply []app.Message, exitCode int) {
+	o.encoder.Encode(jsonRecord{Type: "done", SessionID: sessionID, ExitCode: &exitCode})
+}
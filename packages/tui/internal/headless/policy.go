@@ -0,0 +1,62 @@
+package headless
+
+import (
+	"strings"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+	"github.com/skorpland/sgptcoder/internal/permission"
+)
+
+// PermissionPolicy decides how permission requests are answered when nobody is
+// around to answer them.
+type PermissionPolicy struct {
+	// Default is the response for permission types without an entry in Types.
+	// An empty Default rejects.
+	Default sgptcoder.SessionPermissionRespondParamsResponse
+	// Types maps a permission type, such as "bash", "edit" or "webfetch", to
+	// the response for it.
+	Types map[string]sgptcoder.SessionPermissionRespondParamsResponse
+}
+
+// Response returns the response the policy gives to the permission.
+func (p PermissionPolicy) Response(permission sgptcoder.Permission) sgptcoder.SessionPermissionRespondParamsResponse {
+	if response, ok := p.Types[permission.Type]; ok {
+		return response
+	}
+	if p.Default == "" {
+		return sgptcoder.SessionPermissionRespondParamsResponseReject
+	}
+	return p.Default
+}
+
+// ParsePermissionPolicy parses a policy from a comma separated list of
+// responses. A bare response sets the default and type=response sets the
+// response for one permission type, for example "reject,edit=once,bash=always".
+// The responses are "once", "always" and "reject"; "allow" is accepted as an
+// alias for "once" and "deny" for "reject".
+func ParsePermissionPolicy(value string) (PermissionPolicy, error) {
+	policy := PermissionPolicy{
+		Types: map[string]sgptcoder.SessionPermissionRespondParamsResponse{},
+	}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		permissionType, value, found := strings.Cut(entry, "=")
+		if !found {
+			permissionType, value = "", entry
+		}
+		response, err := permission.ParseResponse(value)
+		if err != nil {
+			return PermissionPolicy{}, err
+		}
+		permissionType = strings.TrimSpace(permissionType)
+		if permissionType == "" || permissionType == "*" {
+			policy.Default = response
+		} else {
+			policy.Types[permissionType] = response
+		}
+	}
+	return policy, nil
+}
@@ -0,0 +1,41 @@
+package headless
+
+import (
+	"testing"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+)
+
+func TestParsePermissionPolicy(t *testing.T) {
+	policy, err := ParsePermissionPolicy("allow, bash=reject,edit=always")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		permissionType string
+		expected       sgptcoder.SessionPermissionRespondParamsResponse
+	}{
+		{"bash", sgptcoder.SessionPermissionRespondParamsResponseReject},
+		{"edit", sgptcoder.SessionPermissionRespondParamsResponseAlways},
+		{"webfetch", sgptcoder.SessionPermissionRespondParamsResponseOnce},
+	}
+	for _, tt := range tests {
+		t.Run(tt.permissionType, func(t *testing.T) {
+			got := policy.Response(sgptcoder.Permission{Type: tt.permissionType})
+			if got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPermissionPolicyRejectsByDefault(t *testing.T) {
+	var policy PermissionPolicy
+	if got := policy.Response(sgptcoder.Permission{Type: "bash"}); got != sgptcoder.SessionPermissionRespondParamsResponseReject {
+		t.Errorf("expected reject, got %s", got)
+	}
+	if _, err := ParsePermissionPolicy("bash=maybe"); err == nil {
+		t.Error("expected an error for an unknown response")
+	}
+}
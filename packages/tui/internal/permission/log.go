@@ -0,0 +1,59 @@
+package permission
+
+import (
+	"sync"
+	"time"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+)
+
+const maxDecisions = 200
+
+// Decision records how a permission request was answered.
+type Decision struct {
+	Time     time.Time
+	Request  Request
+	Response sgptcoder.SessionPermissionRespondParamsResponse
+	// Rule is the rule that answered the request, nil if the user did.
+	Rule *Rule
+}
+
+// Automatic reports whether a rule answered the request.
+func (d Decision) Automatic() bool {
+	return d.Rule != nil
+}
+
+// Log keeps the most recent decisions.
+type Log struct {
+	mu        sync.Mutex
+	decisions []Decision
+}
+
+// NewLog creates an empty decision log.
+func NewLog() *Log {
+	return &Log{}
+}
+
+// Add records a decision, dropping the oldest once the log is full.
+func (l *Log) Add(decision Decision) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if decision.Time.IsZero() {
+		decision.Time = time.Now()
+	}
+	l.decisions = append(l.decisions, decision)
+	if len(l.decisions) > maxDecisions {
+		l.decisions = l.decisions[len(l.decisions)-maxDecisions:]
+	}
+}
+
+// Decisions returns the logged decisions, newest first.
+func (l *Log) Decisions() []Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	decisions := make([]Decision, len(l.decisions))
+	for i, decision := range l.decisions {
+		decisions[len(l.decisions)-1-i] = decision
+	}
+	return decisions
+}
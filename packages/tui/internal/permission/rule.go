@@ -0,0 +1,222 @@
+// Package permission decides permission requests on the client from a list
+// of rules and keeps a log of every decision.
+package permission
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+)
+
+// Rule answers matching permission requests without asking. Empty fields
+// match anything.
+type Rule struct {
+	// Tool matches the permission type, such as "bash", "edit", "write" or
+	// "webfetch".
+	Tool string `toml:"tool,omitempty"`
+	// Command matches the full command of a bash request. "*" matches any run
+	// of characters and "?" a single one, as in the server's bash permissions,
+	// so "go test *" matches every go test invocation.
+	Command string `toml:"command,omitempty"`
+	// Path matches the file of an edit or write request, relative to the
+	// project root unless the glob is absolute. "*" does not cross directories
+	// and "**" does.
+	Path string `toml:"path,omitempty"`
+	// Session matches the ID of the session asking.
+	Session string `toml:"session,omitempty"`
+	// Agent matches the agent that is running the tool.
+	Agent string `toml:"agent,omitempty"`
+	// Response is "once", "always" or "reject".
+	Response string `toml:"response"`
+}
+
+// Request is a permission request with the context rules match against.
+type Request struct {
+	Permission sgptcoder.Permission
+	// Agent is the agent of the message that asked, if known.
+	Agent string
+	// Root is the project root that relative path globs are resolved against.
+	Root string
+}
+
+// Command returns the bash command of the request, if any.
+func (r Request) Command() string {
+	command, _ := r.Permission.Metadata["command"].(string)
+	return command
+}
+
+// Path returns the file the request wants to change, if any.
+func (r Request) Path() string {
+	path, _ := r.Permission.Metadata["filePath"].(string)
+	return path
+}
+
+// Matches reports whether the rule applies to the request.
+func (rule Rule) Matches(request Request) bool {
+	if !matchWildcard(rule.Tool, request.Permission.Type) ||
+		!matchWildcard(rule.Session, request.Permission.SessionID) ||
+		!matchWildcard(rule.Agent, request.Agent) {
+		return false
+	}
+	if rule.Command != "" {
+		command := request.Command()
+		if command == "" || !matchWildcard(rule.Command, strings.TrimSpace(command)) {
+			return false
+		}
+	}
+	if rule.Path != "" {
+		path := request.Path()
+		if path == "" {
+			return false
+		}
+		if !filepath.IsAbs(rule.Path) && request.Root != "" {
+			if relative, err := filepath.Rel(request.Root, path); err == nil {
+				path = relative
+			}
+		}
+		if !matchGlob(rule.Path, filepath.ToSlash(path)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that the rule has a valid response and patterns.
+func (rule Rule) Validate() error {
+	if _, err := ParseResponse(rule.Response); err != nil {
+		return err
+	}
+	if rule.Tool == "" && rule.Command == "" && rule.Path == "" && rule.Session == "" && rule.Agent == "" {
+		return fmt.Errorf("permission rule matches every request")
+	}
+	return nil
+}
+
+// String describes the rule for display.
+func (rule Rule) String() string {
+	var conditions []string
+	if rule.Tool != "" {
+		conditions = append(conditions, rule.Tool)
+	}
+	if rule.Command != "" {
+		conditions = append(conditions, "`"+rule.Command+"`")
+	}
+	if rule.Path != "" {
+		conditions = append(conditions, rule.Path)
+	}
+	if rule.Agent != "" {
+		conditions = append(conditions, "agent "+rule.Agent)
+	}
+	if rule.Session != "" {
+		conditions = append(conditions, "session "+rule.Session)
+	}
+	return strings.Join(conditions, " ") + " → " + rule.Response
+}
+
+// Rules is an ordered list of rules. The first matching rule wins.
+type Rules []Rule
+
+// Match returns the first valid rule that applies to the request.
+func (rules Rules) Match(request Request) (Rule, bool) {
+	for _, rule := range rules {
+		if rule.Validate() != nil {
+			continue
+		}
+		if rule.Matches(request) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// RuleFor returns a rule that answers requests exactly like the given one
+// with the given response: the same tool with the same command or file.
+func RuleFor(request Request, response sgptcoder.SessionPermissionRespondParamsResponse) Rule {
+	rule := Rule{
+		Tool:     request.Permission.Type,
+		Command:  escapeWildcard(strings.TrimSpace(request.Command())),
+		Response: string(response),
+	}
+	if path := request.Path(); path != "" {
+		if request.Root != "" {
+			if relative, err := filepath.Rel(request.Root, path); err == nil && !strings.HasPrefix(relative, "..") {
+				path = relative
+			}
+		}
+		rule.Path = escapeWildcard(filepath.ToSlash(path))
+	}
+	return rule
+}
+
+// ParseResponse parses "once", "always" or "reject". "allow" is accepted as
+// an alias for "once" and "deny" for "reject".
+func ParseResponse(value string) (sgptcoder.SessionPermissionRespondParamsResponse, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "once", "allow":
+		return sgptcoder.SessionPermissionRespondParamsResponseOnce, nil
+	case "always":
+		return sgptcoder.SessionPermissionRespondParamsResponseAlways, nil
+	case "reject", "deny":
+		return sgptcoder.SessionPermissionRespondParamsResponseReject, nil
+	}
+	return "", fmt.Errorf("invalid permission response %q, expected once, always or reject", value)
+}
+
+func matchWildcard(pattern, value string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	var sb strings.Builder
+	sb.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	matched, _ := regexp.MatchString(sb.String(), value)
+	return matched
+}
+
+func matchGlob(pattern, path string) bool {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				// "**/" also matches no directories at all
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					sb.WriteString("(?:.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		}
+	}
+	sb.WriteString("$")
+	matched, _ := regexp.MatchString(sb.String(), path)
+	return matched
+}
+
+// escapeWildcard keeps literal "*" and "?" in a command or path from acting as
+// wildcards by replacing them with "?", which still matches them.
+func escapeWildcard(value string) string {
+	return strings.NewReplacer("*", "?").Replace(value)
+}
@@ -0,0 +1,128 @@
+package permission
+
+import (
+	"testing"
+
+	"github.com/skorpland/sgptcoder-sdk-go"
+)
+
+func bashRequest(command string) Request {
+	return Request{
+		Permission: sgptcoder.Permission{
+			Type:      "bash",
+			SessionID: "ses_1",
+			Metadata:  map[string]interface{}{"command": command},
+		},
+		Agent: "build",
+		Root:  "/repo",
+	}
+}
+
+func editRequest(path string) Request {
+	return Request{
+		Permission: sgptcoder.Permission{
+			Type:      "edit",
+			SessionID: "ses_1",
+			Metadata:  map[string]interface{}{"filePath": path},
+		},
+		Agent: "build",
+		Root:  "/repo",
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     Rule
+		request  Request
+		expected bool
+	}{
+		{"tool", Rule{Tool: "bash"}, bashRequest("ls"), true},
+		{"other tool", Rule{Tool: "edit"}, bashRequest("ls"), false},
+		{"command wildcard", Rule{Command: "go test *"}, bashRequest("go test ./..."), true},
+		{"command prefix only", Rule{Command: "go test *"}, bashRequest("go build ./..."), false},
+		{"command exact", Rule{Command: "ls"}, bashRequest("ls -la"), false},
+		{"command without command", Rule{Command: "*"}, editRequest("/repo/main.go"), false},
+		{"path glob", Rule{Path: "*.go"}, editRequest("/repo/main.go"), true},
+		{"path glob stays in directory", Rule{Path: "*.go"}, editRequest("/repo/internal/app.go"), false},
+		{"path double star", Rule{Path: "internal/**/*.go"}, editRequest("/repo/internal/app.go"), true},
+		{"path double star nested", Rule{Path: "internal/**"}, editRequest("/repo/internal/a/b/c.ts"), true},
+		{"path absolute", Rule{Path: "/tmp/*"}, editRequest("/tmp/scratch"), true},
+		{"agent", Rule{Tool: "bash", Agent: "plan"}, bashRequest("ls"), false},
+		{"session", Rule{Session: "ses_*"}, bashRequest("ls"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Matches(tt.request); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRulesMatchFirstValidRule(t *testing.T) {
+	rules := Rules{
+		{Tool: "bash", Response: "maybe"},
+		{Tool: "bash", Command: "rm *", Response: "reject"},
+		{Tool: "bash", Response: "once"},
+	}
+	rule, ok := rules.Match(bashRequest("rm -rf /"))
+	if !ok || rule.Response != "reject" {
+		t.Errorf("expected the reject rule, got %v", rule)
+	}
+	rule, ok = rules.Match(bashRequest("ls"))
+	if !ok || rule.Response != "once" {
+		t.Errorf("expected the once rule, got %v", rule)
+	}
+	if _, ok := rules.Match(editRequest("/repo/main.go")); ok {
+		t.Error("expected no rule to match an edit")
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	if err := (Rule{Tool: "bash", Response: "always"}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := (Rule{Response: "always"}).Validate(); err == nil {
+		t.Error("expected an error for a rule without conditions")
+	}
+	if err := (Rule{Tool: "bash", Response: "sometimes"}).Validate(); err == nil {
+		t.Error("expected an error for an unknown response")
+	}
+}
+
+func TestRuleFor(t *testing.T) {
+	rule := RuleFor(bashRequest("ls *.go"), sgptcoder.SessionPermissionRespondParamsResponseAlways)
+	if rule.Tool != "bash" || rule.Command != "ls ?.go" || rule.Response != "always" {
+		t.Errorf("unexpected rule %+v", rule)
+	}
+	if !rule.Matches(bashRequest("ls *.go")) {
+		t.Error("expected the rule to match its request")
+	}
+
+	rule = RuleFor(editRequest("/repo/internal/app.go"), sgptcoder.SessionPermissionRespondParamsResponseReject)
+	if rule.Path != "internal/app.go" {
+		t.Errorf("expected a path relative to the root, got %q", rule.Path)
+	}
+	if !rule.Matches(editRequest("/repo/internal/app.go")) || rule.Matches(editRequest("/repo/internal/tui.go")) {
+		t.Error("expected the rule to match only its file")
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	tests := map[string]sgptcoder.SessionPermissionRespondParamsResponse{
+		"once":   sgptcoder.SessionPermissionRespondParamsResponseOnce,
+		"Allow":  sgptcoder.SessionPermissionRespondParamsResponseOnce,
+		"always": sgptcoder.SessionPermissionRespondParamsResponseAlways,
+		"deny":   sgptcoder.SessionPermissionRespondParamsResponseReject,
+		"reject": sgptcoder.SessionPermissionRespondParamsResponseReject,
+	}
+	for value, expected := range tests {
+		if got, err := ParseResponse(value); err != nil || got != expected {
+			t.Errorf("%s: expected %s, got %s (%v)", value, expected, got, err)
+		}
+	}
+	if _, err := ParseResponse("maybe"); err == nil {
+		t.Error("expected an error for an unknown response")
+	}
+}
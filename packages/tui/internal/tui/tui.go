@@ -3,13 +3,18 @@ package tui
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/charmbracelet/bubbles/v2/key"
 	tea "github.com/charmbracelet/bubbletea/v2"
@@ -23,9 +28,11 @@ import (
 	"github.com/skorpland/sgptcoder/internal/components/chat"
 	cmdcomp "github.com/skorpland/sgptcoder/internal/components/commands"
 	"github.com/skorpland/sgptcoder/internal/components/dialog"
+	"github.com/skorpland/sgptcoder/internal/components/fileviewer"
 	"github.com/skorpland/sgptcoder/internal/components/modal"
 	"github.com/skorpland/sgptcoder/internal/components/status"
 	"github.com/skorpland/sgptcoder/internal/components/toast"
+	"github.com/skorpland/sgptcoder/internal/exporter"
 	"github.com/skorpland/sgptcoder/internal/layout"
 	"github.com/skorpland/sgptcoder/internal/styles"
 	"github.com/skorpland/sgptcoder/internal/theme"
@@ -66,6 +73,7 @@ type Model struct {
 	status               status.StatusComponent
 	editor               chat.EditorComponent
 	messages             chat.MessagesComponent
+	fileViewer           fileviewer.FileViewerComponent
 	completions          dialog.CompletionDialog
 	commandProvider      completions.CompletionProvider
 	fileProvider         completions.CompletionProvider
@@ -89,6 +97,7 @@ func (a Model) Init() tea.Cmd {
 	cmds = append(cmds, a.app.InitializeProvider())
 	cmds = append(cmds, a.editor.Init())
 	cmds = append(cmds, a.messages.Init())
+	cmds = append(cmds, a.fileViewer.Init())
 	cmds = append(cmds, a.status.Init())
 	cmds = append(cmds, a.completions.Init())
 	cmds = append(cmds, a.toastManager.Init())
@@ -104,17 +113,12 @@ func (a Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyPressMsg:
 		keyString := msg.String()
 
-		if a.app.CurrentPermission.ID != "" {
+		// The message search and cursor take these keys while they are open
+		if a.app.CurrentPermission.ID != "" && a.modal == nil &&
+			!a.messages.Searching() && !a.messages.HasCursor() {
 			if keyString == "enter" || keyString == "esc" || keyString == "a" {
-				sessionID := a.app.CurrentPermission.SessionID
-				permissionID := a.app.CurrentPermission.ID
+				permission := a.app.CurrentPermission
 				a.editor.Focus()
-				a.app.Permissions = a.app.Permissions[1:]
-				if len(a.app.Permissions) > 0 {
-					a.app.CurrentPermission = a.app.Permissions[0]
-				} else {
-					a.app.CurrentPermission = sgptcoder.Permission{}
-				}
 				response := sgptcoder.SessionPermissionRespondParamsResponseOnce
 				switch keyString {
 				case "enter":
@@ -125,20 +129,7 @@ func (a Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					response = sgptcoder.SessionPermissionRespondParamsResponseReject
 				}
 
-				return a, func() tea.Msg {
-					resp, err := a.app.Client.Session.Permissions.Respond(
-						context.Background(),
-						sessionID,
-						permissionID,
-						sgptcoder.SessionPermissionRespondParams{Response: sgptcoder.F(response)},
-					)
-					if err != nil {
-						slog.Error("Failed to respond to permission request", "error", err)
-						return toast.NewErrorToast("Failed to respond to permission request")()
-					}
-					slog.Debug("Responded to permission request", "response", resp)
-					return nil
-				}
+				return a, a.app.RespondToPermission(permission, response)
 			}
 		}
 
@@ -185,6 +176,13 @@ func (a Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, cmd
 		}
 
+		// Pass key presses to the message search or cursor while it is open
+		if a.messages.Searching() || a.messages.HasCursor() {
+			updated, cmd := a.messages.Update(msg)
+			a.messages = updated.(chat.MessagesComponent)
+			return a, cmd
+		}
+
 		// 2. Check for commands that require leader
 		if a.app.IsLeaderSequence {
 			matches := a.app.Commands.Matches(msg, a.app.IsLeaderSequence)
@@ -192,6 +190,12 @@ func (a Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if len(matches) > 0 {
 				return a, util.CmdHandler(commands.ExecuteCommandsMsg(matches))
 			}
+			// the leader and a digit yank that code block of the latest reply
+			if len(keyString) == 1 && keyString >= "1" && keyString <= "9" {
+				yank := a.app.Commands[commands.MessagesYankCommand]
+				yank.Args = keyString
+				return a, util.CmdHandler(commands.ExecuteCommandMsg(yank))
+			}
 		}
 
 		// 3. Handle completions trigger
@@ -258,6 +262,12 @@ func (a Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, tea.Batch(cmds...)
 		}
 
+		// Close the open file before esc falls through to interrupt
+		fileCloseCommand := a.app.Commands[commands.FileCloseCommand]
+		if a.fileViewer.HasFile() && fileCloseCommand.Matches(msg, a.app.IsLeaderSequence) {
+			return a, util.CmdHandler(commands.ExecuteCommandMsg(fileCloseCommand))
+		}
+
 		// 4. Maximize editor responsiveness for printable characters
 		if msg.Text != "" {
 			updated, cmd := a.editor.Update(msg)
@@ -345,6 +355,13 @@ func (a Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, tea.Batch(cmds...)
 		}
 
+		if a.fileViewer.HasFile() {
+			updated, cmd := a.fileViewer.Update(msg)
+			a.fileViewer = updated.(fileviewer.FileViewerComponent)
+			cmds = append(cmds, cmd)
+			return a, tea.Batch(cmds...)
+		}
+
 		updated, cmd := a.messages.Update(msg)
 		a.messages = updated.(chat.MessagesComponent)
 		cmds = append(cmds, cmd)
@@ -451,8 +468,10 @@ func (a Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.editor = updated.(chat.EditorComponent)
 		cmds = append(cmds, cmd)
 	case app.SessionClearedMsg:
+		a.app.StopReplay()
 		a.app.Session = &sgptcoder.Session{}
 		a.app.Messages = []app.Message{}
+		a.app.HasOlderMessages = false
 	case dialog.CompletionDialogCloseMsg:
 		a.showCompletionDialog = false
 	case sgptcoder.EventListResponseEventInstallationUpdated:
@@ -471,6 +490,14 @@ func (a Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if a.app.Session != nil && msg.Properties.Info.ID == a.app.Session.ID {
 			a.app.Session = &sgptcoder.Session{}
 			a.app.Messages = []app.Message{}
+			a.app.HasOlderMessages = false
+		}
+		if _, ok := a.app.State.Folds[msg.Properties.Info.ID]; ok {
+			a.app.State.ClearFolds(msg.Properties.Info.ID)
+			return a, tea.Batch(
+				a.app.SaveState(),
+				toast.NewSuccessToast("Session deleted successfully"),
+			)
 		}
 		return a, toast.NewSuccessToast("Session deleted successfully")
 	case sgptcoder.EventListResponseEventSessionUpdated:
@@ -479,174 +506,28 @@ func (a Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	case sgptcoder.EventListResponseEventMessagePartUpdated:
 		slog.Debug("message part updated", "message", msg.Properties.Part.MessageID, "part", msg.Properties.Part.ID)
-		if msg.Properties.Part.SessionID == a.app.Session.ID {
-			messageIndex := slices.IndexFunc(a.app.Messages, func(m app.Message) bool {
-				switch casted := m.Info.(type) {
-				case sgptcoder.UserMessage:
-					return casted.ID == msg.Properties.Part.MessageID
-				case sgptcoder.AssistantMessage:
-					return casted.ID == msg.Properties.Part.MessageID
-				}
-				return false
-			})
-			if messageIndex > -1 {
-				message := a.app.Messages[messageIndex]
-				partIndex := slices.IndexFunc(message.Parts, func(p sgptcoder.PartUnion) bool {
-					switch casted := p.(type) {
-					case sgptcoder.TextPart:
-						return casted.ID == msg.Properties.Part.ID
-					case sgptcoder.ReasoningPart:
-						return casted.ID == msg.Properties.Part.ID
-					case sgptcoder.FilePart:
-						return casted.ID == msg.Properties.Part.ID
-					case sgptcoder.ToolPart:
-						return casted.ID == msg.Properties.Part.ID
-					case sgptcoder.StepStartPart:
-						return casted.ID == msg.Properties.Part.ID
-					case sgptcoder.StepFinishPart:
-						return casted.ID == msg.Properties.Part.ID
-					}
-					return false
-				})
-				if partIndex > -1 {
-					message.Parts[partIndex] = msg.Properties.Part.AsUnion()
-				}
-				if partIndex == -1 {
-					message.Parts = append(message.Parts, msg.Properties.Part.AsUnion())
-				}
-				a.app.Messages[messageIndex] = message
-			}
-		}
+		a.app.UpdatePart(msg.Properties.Part)
 	case sgptcoder.EventListResponseEventMessagePartRemoved:
 		slog.Debug("message part removed", "session", msg.Properties.SessionID, "message", msg.Properties.MessageID, "part", msg.Properties.PartID)
-		if msg.Properties.SessionID == a.app.Session.ID {
-			messageIndex := slices.IndexFunc(a.app.Messages, func(m app.Message) bool {
-				switch casted := m.Info.(type) {
-				case sgptcoder.UserMessage:
-					return casted.ID == msg.Properties.MessageID
-				case sgptcoder.AssistantMessage:
-					return casted.ID == msg.Properties.MessageID
-				}
-				return false
-			})
-			if messageIndex > -1 {
-				message := a.app.Messages[messageIndex]
-				partIndex := slices.IndexFunc(message.Parts, func(p sgptcoder.PartUnion) bool {
-					switch casted := p.(type) {
-					case sgptcoder.TextPart:
-						return casted.ID == msg.Properties.PartID
-					case sgptcoder.ReasoningPart:
-						return casted.ID == msg.Properties.PartID
-					case sgptcoder.FilePart:
-						return casted.ID == msg.Properties.PartID
-					case sgptcoder.ToolPart:
-						return casted.ID == msg.Properties.PartID
-					case sgptcoder.StepStartPart:
-						return casted.ID == msg.Properties.PartID
-					case sgptcoder.StepFinishPart:
-						return casted.ID == msg.Properties.PartID
-					}
-					return false
-				})
-				if partIndex > -1 {
-					// Remove the part at partIndex
-					message.Parts = append(message.Parts[:partIndex], message.Parts[partIndex+1:]...)
-					a.app.Messages[messageIndex] = message
-				}
-			}
-		}
+		a.app.RemovePart(msg.Properties.SessionID, msg.Properties.MessageID, msg.Properties.PartID)
 	case sgptcoder.EventListResponseEventMessageRemoved:
 		slog.Debug("message removed", "session", msg.Properties.SessionID, "message", msg.Properties.MessageID)
-		if msg.Properties.SessionID == a.app.Session.ID {
-			messageIndex := slices.IndexFunc(a.app.Messages, func(m app.Message) bool {
-				switch casted := m.Info.(type) {
-				case sgptcoder.UserMessage:
-					return casted.ID == msg.Properties.MessageID
-				case sgptcoder.AssistantMessage:
-					return casted.ID == msg.Properties.MessageID
-				}
-				return false
-			})
-			if messageIndex > -1 {
-				a.app.Messages = append(a.app.Messages[:messageIndex], a.app.Messages[messageIndex+1:]...)
-			}
-		}
+		a.app.RemoveMessage(msg.Properties.SessionID, msg.Properties.MessageID)
 	case sgptcoder.EventListResponseEventMessageUpdated:
-		if msg.Properties.Info.SessionID == a.app.Session.ID {
-			matchIndex := slices.IndexFunc(a.app.Messages, func(m app.Message) bool {
-				switch casted := m.Info.(type) {
-				case sgptcoder.UserMessage:
-					return casted.ID == msg.Properties.Info.ID
-				case sgptcoder.AssistantMessage:
-					return casted.ID == msg.Properties.Info.ID
-				}
-				return false
-			})
-
-			if matchIndex > -1 {
-				match := a.app.Messages[matchIndex]
-				a.app.Messages[matchIndex] = app.Message{
-					Info:  msg.Properties.Info.AsUnion(),
-					Parts: match.Parts,
-				}
-			}
-
-			if matchIndex == -1 {
-				// Extract the new message ID
-				var newMessageID string
-				switch casted := msg.Properties.Info.AsUnion().(type) {
-				case sgptcoder.UserMessage:
-					newMessageID = casted.ID
-				case sgptcoder.AssistantMessage:
-					newMessageID = casted.ID
-				}
-
-				// Find the correct insertion index by scanning backwards
-				// Most messages are added to the end, so start from the end
-				insertIndex := len(a.app.Messages)
-				for i := len(a.app.Messages) - 1; i >= 0; i-- {
-					var existingID string
-					switch casted := a.app.Messages[i].Info.(type) {
-					case sgptcoder.UserMessage:
-						existingID = casted.ID
-					case sgptcoder.AssistantMessage:
-						existingID = casted.ID
-					}
-					if existingID < newMessageID {
-						insertIndex = i + 1
-						break
-					}
-				}
-
-				// Create the new message
-				newMessage := app.Message{
-					Info:  msg.Properties.Info.AsUnion(),
-					Parts: []sgptcoder.PartUnion{},
-				}
-
-				// Insert at the correct position
-				a.app.Messages = append(a.app.Messages[:insertIndex], append([]app.Message{newMessage}, a.app.Messages[insertIndex:]...)...)
-			}
-		}
+		a.app.UpdateMessage(msg.Properties.Info)
 	case sgptcoder.EventListResponseEventPermissionUpdated:
 		slog.Debug("permission updated", "session", msg.Properties.SessionID, "permission", msg.Properties.ID)
-		a.app.Permissions = append(a.app.Permissions, msg.Properties)
-		a.app.CurrentPermission = a.app.Permissions[0]
-		a.editor.Blur()
-	case sgptcoder.EventListResponseEventPermissionReplied:
-		index := slices.IndexFunc(a.app.Permissions, func(p sgptcoder.Permission) bool {
-			return p.ID == msg.Properties.PermissionID
-		})
-		if index > -1 {
-			a.app.Permissions = append(a.app.Permissions[:index], a.app.Permissions[index+1:]...)
+		if cmd, ok := a.app.AutoRespond(msg.Properties); ok {
+			return a, cmd
 		}
-		if a.app.CurrentPermission.ID == msg.Properties.PermissionID {
-			if len(a.app.Permissions) > 0 {
-				a.app.CurrentPermission = a.app.Permissions[0]
-			} else {
-				a.app.CurrentPermission = sgptcoder.Permission{}
-			}
+		a.app.AddPermission(msg.Properties)
+		a.editor.Blur()
+		if msg.Properties.SessionID != a.app.Session.ID {
+			// Requests of subagents are not shown inline
+			cmds = append(cmds, toast.NewInfoToast("Permission required: "+msg.Properties.Title+" (/review)"))
 		}
+	case sgptcoder.EventListResponseEventPermissionReplied:
+		a.app.RemovePermission(msg.Properties.PermissionID)
 	case sgptcoder.EventListResponseEventSessionError:
 		switch err := msg.Properties.Error.AsUnion().(type) {
 		case nil:
@@ -657,6 +538,11 @@ func (a Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			slog.Error("Server error", "name", err.Name, "message", err.Data.Message)
 			return a, toast.NewErrorToast(err.Data.Message, toast.WithTitle(string(err.Name)))
 		}
+	case sgptcoder.EventListResponseEventSessionIdle:
+		if msg.Properties.SessionID == a.app.Session.ID && a.app.IsReplaying() {
+			a.app, cmd = a.app.ReplayNext(context.Background())
+			cmds = append(cmds, cmd)
+		}
 	case sgptcoder.EventListResponseEventSessionCompacted:
 		if msg.Properties.SessionID == a.app.Session.ID {
 			return a, toast.NewSuccessToast("Session compacted successfully")
@@ -679,17 +565,34 @@ func (a Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.messages = updated.(chat.MessagesComponent)
 		cmds = append(cmds, cmd)
 
-		messages, err := a.app.ListMessages(context.Background(), msg.ID)
+		messages, more, err := a.app.ListMessagesPage(context.Background(), msg.ID, "", app.MessagePageSize)
 		if err != nil {
 			slog.Error("Failed to list messages", "error", err.Error())
 			return a, toast.NewErrorToast("Failed to open session")
 		}
+		if msg.ID != a.app.Session.ID {
+			a.app.StopReplay()
+		}
 		a.app.Session = msg
 		a.app.Messages = messages
+		a.app.HasOlderMessages = more
 		cmds = append(cmds, util.CmdHandler(app.SessionLoadedMsg{}))
 		return a, tea.Batch(cmds...)
 	case app.SessionCreatedMsg:
 		a.app.Session = msg.Session
+	case app.OlderMessagesLoadedMsg:
+		if msg.Err != nil {
+			cmds = append(cmds, toast.NewErrorToast("Failed to load older messages"))
+		} else if msg.SessionID == a.app.Session.ID {
+			a.app.Messages = append(msg.Messages, a.app.Messages...)
+			a.app.HasOlderMessages = msg.More
+		}
+	case dialog.FileSelectedMsg:
+		updated, cmd := a.fileViewer.OpenFile(msg.Path)
+		a.fileViewer = updated.(fileviewer.FileViewerComponent)
+		cmds = append(cmds, cmd)
+	case chat.RerunPromptMsg:
+		a.modal = dialog.NewRerunModelDialog(a.app, msg.Prompt)
 	case dialog.ScrollToMessageMsg:
 		updated, cmd := a.messages.ScrollToMessage(msg.MessageID)
 		a.messages = updated.(chat.MessagesComponent)
@@ -887,6 +790,10 @@ func (a Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	a.messages = updatedMessages.(chat.MessagesComponent)
 	cmds = append(cmds, cmd)
 
+	updatedFileViewer, cmd := a.fileViewer.Update(msg)
+	a.fileViewer = updatedFileViewer.(fileviewer.FileViewerComponent)
+	cmds = append(cmds, cmd)
+
 	if a.modal != nil {
 		updatedModal, cmd := a.modal.Update(msg)
 		a.modal = updatedModal.(layout.Modal)
@@ -1090,6 +997,9 @@ func (a Model) chat() (string, int, int) {
 	editorView := a.editor.View()
 	lines := a.editor.Lines()
 	messagesView := a.messages.View()
+	if a.fileViewer.HasFile() {
+		messagesView = a.fileViewer.View()
+	}
 
 	editorWidth := lipgloss.Width(editorView)
 	editorHeight := max(lines, 5)
@@ -1371,51 +1281,55 @@ func (a Model) executeCommand(command commands.Command) (tea.Model, tea.Cmd) {
 			return a, toast.NewErrorToast("No active session to export.")
 		}
 
-		// Use current conversation history
+		// Use current conversation history, with the messages not loaded yet
 		messages := a.app.Messages
+		if a.app.HasOlderMessages {
+			all, err := a.app.ListMessages(context.Background(), a.app.Session.ID)
+			if err != nil {
+				slog.Error("Failed to list messages", "error", err)
+				return a, toast.NewErrorToast("Failed to export conversation.")
+			}
+			messages = all
+		}
 		if len(messages) == 0 {
 			return a, toast.NewInfoToast("No messages to export.")
 		}
 
+		opts := exporter.Options{Reasoning: a.messages.ThinkingBlocksVisible()}
+		if command.Args != "" {
+			path, format, err := exportTarget(a.app.Session, command.Args)
+			if err != nil {
+				return a, toast.NewErrorToast(err.Error())
+			}
+			err = exporter.WriteFile(path, format, *a.app.Session, messages, opts)
+			if err != nil {
+				slog.Error("Failed to export conversation", "path", path, "error", err)
+				return a, toast.NewErrorToast("Failed to export conversation.")
+			}
+			return a, toast.NewSuccessToast("Exported to " + util.Relative(path))
+		}
+
 		// Format to Markdown
-		markdownContent := formatConversationToMarkdown(messages)
+		markdownContent := exporter.Markdown(*a.app.Session, messages, opts)
 
-		// Check if EDITOR is set
-		editor := os.Getenv("EDITOR")
-		if editor == "" {
-			return a, toast.NewErrorToast("No EDITOR set, can't open editor")
+		cmds = append(cmds, app.OpenInEditor(markdownContent, "conversation-*.md"))
+	case commands.SessionImportCommand:
+		path, mode, err := importSource(command.Args)
+		if err != nil {
+			return a, toast.NewErrorToast(err.Error())
 		}
-
-		// Create and write to temp file
-		tmpfile, err := os.CreateTemp("", "conversation-*.md")
+		file, err := os.Open(path)
 		if err != nil {
-			slog.Error("Failed to create temp file", "error", err)
-			return a, toast.NewErrorToast("Failed to create temporary file.")
+			slog.Error("Failed to open transcript", "path", path, "error", err)
+			return a, toast.NewErrorToast("Failed to open " + util.Relative(path))
 		}
-
-		_, err = tmpfile.WriteString(markdownContent)
+		session, messages, err := exporter.ReadTranscript(file)
+		file.Close()
 		if err != nil {
-			slog.Error("Failed to write to temp file", "error", err)
-			tmpfile.Close()
-			os.Remove(tmpfile.Name())
-			return a, toast.NewErrorToast("Failed to write conversation to file.")
+			slog.Error("Failed to read transcript", "path", path, "error", err)
+			return a, toast.NewErrorToast(err.Error())
 		}
-		tmpfile.Close()
-
-		// Open in editor
-		parts := strings.Fields(editor)
-		c := exec.Command(parts[0], append(parts[1:], tmpfile.Name())...) //nolint:gosec
-		c.Stdin = os.Stdin
-		c.Stdout = os.Stdout
-		c.Stderr = os.Stderr
-		cmd = tea.ExecProcess(c, func(err error) tea.Msg {
-			if err != nil {
-				slog.Error("Failed to open editor for conversation", "error", err)
-			}
-			// Clean up the file after editor closes
-			os.Remove(tmpfile.Name())
-			return nil
-		})
+		a.app, cmd = a.app.ImportSession(context.Background(), session, messages, mode)
 		cmds = append(cmds, cmd)
 	case commands.ToolDetailsCommand:
 		message := "Tool details are now visible"
@@ -1450,8 +1364,32 @@ func (a Model) executeCommand(command commands.Command) (tea.Model, tea.Cmd) {
 	case commands.ThemeListCommand:
 		themeDialog := dialog.NewThemeDialog()
 		a.modal = themeDialog
+	case commands.FileListCommand:
+		fileDialog := dialog.NewFileListDialog(a.app)
+		a.modal = fileDialog
+	case commands.FileSearchCommand:
+		fileDialog := dialog.NewFileSearchDialog(a.app)
+		a.modal = fileDialog
+	case commands.FileDiffToggleCommand:
+		if !a.fileViewer.HasFile() {
+			return a, toast.NewInfoToast("No file open")
+		}
+		updated, cmd := a.fileViewer.ToggleMode()
+		a.fileViewer = updated.(fileviewer.FileViewerComponent)
+		cmds = append(cmds, cmd)
+	case commands.FileCloseCommand:
+		a.fileViewer.Clear()
 	case commands.ProjectInitCommand:
 		cmds = append(cmds, a.app.InitializeProject(context.Background()))
+	case commands.PermissionLogCommand:
+		permissionDialog := dialog.NewPermissionLogDialog(a.app)
+		a.modal = permissionDialog
+	case commands.PermissionReviewCommand:
+		if len(a.app.Permissions) == 0 {
+			return a, toast.NewInfoToast("No permissions waiting")
+		}
+		reviewDialog := dialog.NewPermissionReviewDialog(a.app)
+		a.modal = reviewDialog
 	case commands.InputClearCommand:
 		if a.editor.Value() == "" {
 			return a, nil
@@ -1472,33 +1410,132 @@ func (a Model) executeCommand(command commands.Command) (tea.Model, tea.Cmd) {
 		a.editor = updated.(chat.EditorComponent)
 		cmds = append(cmds, cmd)
 	case commands.MessagesFirstCommand:
+		if a.fileViewer.HasFile() {
+			updated, cmd := a.fileViewer.GotoTop()
+			a.fileViewer = updated.(fileviewer.FileViewerComponent)
+			cmds = append(cmds, cmd)
+			break
+		}
 		updated, cmd := a.messages.GotoTop()
 		a.messages = updated.(chat.MessagesComponent)
 		cmds = append(cmds, cmd)
 	case commands.MessagesLastCommand:
+		if a.fileViewer.HasFile() {
+			updated, cmd := a.fileViewer.GotoBottom()
+			a.fileViewer = updated.(fileviewer.FileViewerComponent)
+			cmds = append(cmds, cmd)
+			break
+		}
 		updated, cmd := a.messages.GotoBottom()
 		a.messages = updated.(chat.MessagesComponent)
 		cmds = append(cmds, cmd)
 	case commands.MessagesPageUpCommand:
+		if a.fileViewer.HasFile() {
+			updated, cmd := a.fileViewer.PageUp()
+			a.fileViewer = updated.(fileviewer.FileViewerComponent)
+			cmds = append(cmds, cmd)
+			break
+		}
 		updated, cmd := a.messages.PageUp()
 		a.messages = updated.(chat.MessagesComponent)
 		cmds = append(cmds, cmd)
 	case commands.MessagesPageDownCommand:
+		if a.fileViewer.HasFile() {
+			updated, cmd := a.fileViewer.PageDown()
+			a.fileViewer = updated.(fileviewer.FileViewerComponent)
+			cmds = append(cmds, cmd)
+			break
+		}
 		updated, cmd := a.messages.PageDown()
 		a.messages = updated.(chat.MessagesComponent)
 		cmds = append(cmds, cmd)
 	case commands.MessagesHalfPageUpCommand:
+		if a.fileViewer.HasFile() {
+			updated, cmd := a.fileViewer.HalfPageUp()
+			a.fileViewer = updated.(fileviewer.FileViewerComponent)
+			cmds = append(cmds, cmd)
+			break
+		}
 		updated, cmd := a.messages.HalfPageUp()
 		a.messages = updated.(chat.MessagesComponent)
 		cmds = append(cmds, cmd)
 	case commands.MessagesHalfPageDownCommand:
+		if a.fileViewer.HasFile() {
+			updated, cmd := a.fileViewer.HalfPageDown()
+			a.fileViewer = updated.(fileviewer.FileViewerComponent)
+			cmds = append(cmds, cmd)
+			break
+		}
 		updated, cmd := a.messages.HalfPageDown()
 		a.messages = updated.(chat.MessagesComponent)
 		cmds = append(cmds, cmd)
+	case commands.MessagesSearchCommand:
+		if a.fileViewer.HasFile() {
+			return a, toast.NewInfoToast("Close the file to search messages")
+		}
+		updated, cmd := a.messages.StartSearch()
+		a.messages = updated.(chat.MessagesComponent)
+		cmds = append(cmds, cmd)
+	case commands.MessagesPreviousCommand:
+		if a.fileViewer.HasFile() {
+			return a, toast.NewInfoToast("Close the file to move between messages")
+		}
+		updated, cmd := a.messages.Previous()
+		a.messages = updated.(chat.MessagesComponent)
+		cmds = append(cmds, cmd)
+	case commands.MessagesNextCommand:
+		if a.fileViewer.HasFile() {
+			return a, toast.NewInfoToast("Close the file to move between messages")
+		}
+		updated, cmd := a.messages.Next()
+		a.messages = updated.(chat.MessagesComponent)
+		cmds = append(cmds, cmd)
 	case commands.MessagesCopyCommand:
 		updated, cmd := a.messages.CopyLastMessage()
 		a.messages = updated.(chat.MessagesComponent)
 		cmds = append(cmds, cmd)
+	case commands.MessagesYankCommand:
+		n, target, force, err := yankTarget(command.Args)
+		if err != nil {
+			return a, toast.NewErrorToast(err.Error())
+		}
+		blocks := a.messages.CodeBlocks()
+		if len(blocks) == 0 {
+			return a, toast.NewInfoToast("No code blocks in the latest reply")
+		}
+		if n == 0 {
+			n = len(blocks)
+		}
+		if n > len(blocks) {
+			return a, toast.NewErrorToast(fmt.Sprintf("No code block %d in the latest reply", n))
+		}
+		block := blocks[n-1]
+		switch target {
+		case "":
+			cmds = append(cmds, app.SetClipboard(block.Content))
+			cmds = append(cmds, toast.NewSuccessToast(fmt.Sprintf("Code block %d copied to clipboard", n)))
+		case "edit":
+			pattern := "code-*"
+			if block.Language != "" && !strings.ContainsFunc(block.Language, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			}) {
+				pattern += "." + block.Language
+			}
+			cmds = append(cmds, app.OpenInEditor(block.Content+"\n", pattern))
+		default:
+			path, err := resolvePath(target)
+			if err != nil {
+				return a, toast.NewErrorToast(err.Error())
+			}
+			if err := writeFile(path, block.Content+"\n", force); err != nil {
+				if errors.Is(err, fs.ErrExist) {
+					return a, toast.NewErrorToast(fmt.Sprintf("%s already exists, use /yank %d -f %s to overwrite it", util.Relative(path), n, target))
+				}
+				slog.Error("Failed to write code block", "path", path, "error", err)
+				return a, toast.NewErrorToast("Failed to write " + util.Relative(path))
+			}
+			cmds = append(cmds, toast.NewSuccessToast(fmt.Sprintf("Wrote code block %d to %s", n, util.Relative(path))))
+		}
 	case commands.MessagesUndoCommand:
 		updated, cmd := a.messages.UndoLastMessage()
 		a.messages = updated.(chat.MessagesComponent)
@@ -1534,6 +1571,7 @@ func NewModel(app *app.App) tea.Model {
 		app:                  app,
 		editor:               editor,
 		messages:             messages,
+		fileViewer:           fileviewer.NewFileViewerComponent(app),
 		completions:          completions,
 		commandProvider:      commandProvider,
 		fileProvider:         fileProvider,
@@ -1549,43 +1587,101 @@ func NewModel(app *app.App) tea.Model {
 	return model
 }
 
-func formatConversationToMarkdown(messages []app.Message) string {
-	var builder strings.Builder
-
-	builder.WriteString("# Conversation History\n\n")
+// importSource parses the arguments of /import: the path of a JSON transcript,
+// optionally preceded by "replay" to re-submit its prompts.
+func importSource(args string) (string, app.ImportMode, error) {
+	fields := strings.Fields(args)
+	mode := app.ImportRehydrate
+	if len(fields) > 0 && fields[0] == "replay" {
+		mode = app.ImportReplay
+		fields = fields[1:]
+	}
+	if len(fields) != 1 {
+		return "", mode, fmt.Errorf("usage: /import [replay] <transcript.json>")
+	}
+	path, err := resolvePath(fields[0])
+	return path, mode, err
+}
 
-	for _, msg := range messages {
-		builder.WriteString("---\n\n")
+// yankTarget parses the arguments of /yank: the number of a code block, 0 for
+// the last one if it is left out, then optionally "edit" to open the block in
+// the editor or a path to write it to, after -f to write over an existing
+// file.
+func yankTarget(args string) (int, string, bool, error) {
+	fields := strings.Fields(args)
+	n := 0
+	if len(fields) > 0 {
+		if parsed, err := strconv.Atoi(fields[0]); err == nil {
+			if parsed < 1 {
+				return 0, "", false, fmt.Errorf("code blocks are numbered from 1")
+			}
+			n = parsed
+			fields = fields[1:]
+		}
+	}
+	force := len(fields) > 0 && fields[0] == "-f"
+	if force {
+		fields = fields[1:]
+	}
+	if len(fields) > 1 || (force && (len(fields) == 0 || fields[0] == "edit")) {
+		return 0, "", false, fmt.Errorf("usage: /yank [n] [edit | [-f] path]")
+	}
+	if len(fields) == 0 {
+		return n, "", false, nil
+	}
+	return n, fields[0], force, nil
+}
 
-		var role string
-		var timestamp time.Time
+// writeFile writes content to a new file, refusing to write over an existing
+// one unless force is set.
+func writeFile(path string, content string, force bool) error {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
 
-		switch info := msg.Info.(type) {
-		case sgptcoder.UserMessage:
-			role = "User"
-			timestamp = time.UnixMilli(int64(info.Time.Created))
-		case sgptcoder.AssistantMessage:
-			role = "Assistant"
-			timestamp = time.UnixMilli(int64(info.Time.Created))
-		default:
+// exportTarget parses the arguments of /export: a path, a format or both,
+// e.g. "review.html", "json" or "json out/transcript". Without a path the
+// transcript is written to the working directory, named after the session.
+func exportTarget(session *sgptcoder.Session, args string) (string, exporter.Format, error) {
+	var path string
+	var format exporter.Format
+	for _, arg := range strings.Fields(args) {
+		if parsed, err := exporter.ParseFormat(arg); err == nil && format == "" && !strings.Contains(arg, ".") {
+			format = parsed
 			continue
 		}
-
-		builder.WriteString(
-			fmt.Sprintf("**%s** (*%s*)\n\n", role, timestamp.Format("2006-01-02 15:04:05")),
-		)
-
-		for _, part := range msg.Parts {
-			switch p := part.(type) {
-			case sgptcoder.TextPart:
-				builder.WriteString(p.Text + "\n\n")
-			case sgptcoder.FilePart:
-				builder.WriteString(fmt.Sprintf("[File: %s]\n\n", p.Filename))
-			case sgptcoder.ToolPart:
-				builder.WriteString(fmt.Sprintf("[Tool: %s]\n\n", p.Tool))
-			}
+		if path != "" {
+			return "", "", fmt.Errorf("usage: /export [markdown|json|html] [path]")
 		}
+		path = arg
+	}
+	if format == "" {
+		format = exporter.FormatForPath(path)
 	}
+	if path == "" {
+		path = session.ID + format.Extension()
+	}
+	absolute, err := resolvePath(path)
+	return absolute, format, err
+}
 
-	return builder.String()
+// resolvePath makes a path typed in a command absolute, expanding a leading ~.
+func resolvePath(path string) (string, error) {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	return filepath.Abs(path)
 }
@@ -0,0 +1,99 @@
+package util
+
+import "strings"
+
+// CodeBlock is a fenced code block of a Markdown document.
+type CodeBlock struct {
+	// Language is the first word of the info string, if any.
+	Language string
+	Content  string
+}
+
+// CodeBlocks returns the fenced code blocks of a Markdown document, in order.
+// A block that is never closed runs to the end of the document.
+func CodeBlocks(markdown string) []CodeBlock {
+	var blocks []CodeBlock
+	for _, block := range fencedBlocks(strings.Split(markdown, "\n")) {
+		blocks = append(blocks, block.CodeBlock)
+	}
+	return blocks
+}
+
+// NumberCodeBlocks puts a line reading label(n, block) at the top of the n-th
+// fenced code block of a Markdown document, counting from 1 as in CodeBlocks.
+func NumberCodeBlocks(markdown string, label func(n int, block CodeBlock) string) string {
+	lines := strings.Split(markdown, "\n")
+	blocks := fencedBlocks(lines)
+	if len(blocks) == 0 {
+		return markdown
+	}
+	numbered := make([]string, 0, len(lines)+len(blocks))
+	next := 0
+	for i, line := range lines {
+		numbered = append(numbered, line)
+		if next < len(blocks) && blocks[next].open == i {
+			block := blocks[next]
+			next++
+			numbered = append(numbered, block.indent+label(next, block.CodeBlock))
+		}
+	}
+	return strings.Join(numbered, "\n")
+}
+
+// fencedBlock is a fenced code block with the line it opens on and the
+// indentation of its fence.
+type fencedBlock struct {
+	CodeBlock
+	open   int
+	indent string
+}
+
+func fencedBlocks(lines []string) []fencedBlock {
+	var blocks []fencedBlock
+	var block *fencedBlock
+	var fence string
+	var content []string
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if block == nil {
+			fence = openingFence(trimmed)
+			if fence != "" {
+				block = &fencedBlock{open: i, indent: line[:len(line)-len(trimmed)]}
+				if fields := strings.Fields(trimmed[len(fence):]); len(fields) > 0 {
+					block.Language = fields[0]
+				}
+				content = nil
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, fence) && strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1])) == "" {
+			block.Content = strings.Join(content, "\n")
+			blocks = append(blocks, *block)
+			block = nil
+			continue
+		}
+		content = append(content, line)
+	}
+	if block != nil {
+		block.Content = strings.Join(content, "\n")
+		blocks = append(blocks, *block)
+	}
+	return blocks
+}
+
+// openingFence returns the run of three or more backticks or tildes a line
+// starts with, or "" if it doesn't open a code block.
+func openingFence(line string) string {
+	if line == "" || (line[0] != '`' && line[0] != '~') {
+		return ""
+	}
+	n := len(line) - len(strings.TrimLeft(line, line[:1]))
+	if n < 3 {
+		return ""
+	}
+	// A backtick fence's info string can't contain backticks
+	if line[0] == '`' && strings.Contains(line[n:], "`") {
+		return ""
+	}
+	return line[:n]
+}
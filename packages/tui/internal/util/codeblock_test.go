@@ -0,0 +1,36 @@
+package util_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/skorpland/sgptcoder/internal/util"
+)
+
+func TestCodeBlocks(t *testing.T) {
+	markdown := "Run this:\n\n" +
+		"```sh\nmake build\n```\n\n" +
+		"Then `inline` code is skipped.\n\n" +
+		"~~~~ go title=main.go\nfunc main() {\n\t// ```\n}\n~~~~\n\n" +
+		"```\nunclosed\n"
+	want := []util.CodeBlock{
+		{Language: "sh", Content: "make build"},
+		{Language: "go", Content: "func main() {\n\t// ```\n}"},
+		{Content: "unclosed\n"},
+	}
+	if got := util.CodeBlocks(markdown); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestNumberCodeBlocks(t *testing.T) {
+	markdown := "Text\n\n```go\nfunc main() {}\n```\n\n- item\n\n  ~~~\n  make\n  ~~~"
+	numbered := util.NumberCodeBlocks(markdown, func(n int, block util.CodeBlock) string {
+		return fmt.Sprintf("#%d %s", n, block.Language)
+	})
+	want := "Text\n\n```go\n#1 go\nfunc main() {}\n```\n\n- item\n\n  ~~~\n  #2 \n  make\n  ~~~"
+	if numbered != want {
+		t.Fatalf("got %q, want %q", numbered, want)
+	}
+}
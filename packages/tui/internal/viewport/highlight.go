@@ -10,7 +10,7 @@ import (
 //
 // Assumptions:
 // - matches are measured in bytes, e.g. what [regex.FindAllStringIndex] would return
-// - matches were made against the given content
+// - matches were made against the given content with ANSI sequences stripped
 // - matches are in order
 // - matches do not overlap
 // - content is line terminated with \n only
@@ -30,8 +30,9 @@ func parseMatches(
 	previousLinesOffset := 0
 	bytePos := 0
 
+	content = ansi.Strip(content)
 	highlights := make([]highlightInfo, 0, len(matches))
-	gr := uniseg.NewGraphemes(ansi.Strip(content))
+	gr := uniseg.NewGraphemes(content)
 
 	for _, match := range matches {
 		byteStart, byteEnd := match[0], match[1]
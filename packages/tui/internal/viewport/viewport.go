@@ -670,6 +670,30 @@ func (m *Model) showHighlight() {
 	m.memo.Invalidate()
 }
 
+// HighlightCount returns the number of highlights.
+func (m Model) HighlightCount() int {
+	return len(m.highlights)
+}
+
+// HighlightIndex returns the index of the selected highlight, or -1 if none
+// is selected.
+func (m Model) HighlightIndex() int {
+	if len(m.highlights) == 0 {
+		return -1
+	}
+	return m.hiIdx
+}
+
+// SelectHighlight selects the highlight at the given index and scrolls to it.
+func (m *Model) SelectHighlight(i int) {
+	if i < 0 || i >= len(m.highlights) {
+		return
+	}
+	m.hiIdx = i
+	m.showHighlight()
+	m.memo.Invalidate()
+}
+
 // HighlightNext highlights the next match.
 func (m *Model) HighlightNext() {
 	if m.highlights == nil {